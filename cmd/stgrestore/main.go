@@ -0,0 +1,143 @@
+// Command stgrestore rebuilds the data file of an object type from a bin log
+// as it stood at a past time or transaction. The bin log is either a single
+// file given with -log or the segment directory of a segmented bin log given
+// with -segments.
+//
+//	stgrestore -log binlog.jsonl -type order -out orders.jsonl \
+//		-at 2022-07-07T13:51:57Z
+//	stgrestore -segments binlog -type order -out orders.jsonl \
+//		-transaction 42
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/yo3jones/stg/pkg/jsonl"
+	"github.com/yo3jones/stg/pkg/objbinlog"
+)
+
+// transactionId holds a transaction id in its marshalled form so that ids of
+// any type can be matched against the -transaction flag.
+type transactionId string
+
+func (id *transactionId) UnmarshalJSON(data []byte) error {
+	*id = transactionId(bytes.Trim(data, `"`))
+	return nil
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run() (err error) {
+	var (
+		at          = flag.String("at", "", "restore as of this RFC3339 time")
+		dst         *os.File
+		logPath     = flag.String("log", "", "path of the bin log")
+		objType     = flag.String("type", "", "object type to restore")
+		opts        = make([]objbinlog.OptRestore, 0, 2)
+		outPath     = flag.String("out", "", "path of the data file to write")
+		restored    int
+		segmentsDir = flag.String(
+			"segments",
+			"",
+			"directory of a segmented bin log",
+		)
+		src         *os.File
+		timestamp   time.Time
+		transaction = flag.String(
+			"transaction",
+			"",
+			"restore as of this transaction id",
+		)
+	)
+
+	flag.Usage = usage
+	flag.Parse()
+
+	if (*logPath == "") == (*segmentsDir == "") {
+		flag.Usage()
+		return fmt.Errorf("exactly one of -log and -segments is required")
+	}
+
+	if *objType == "" || *outPath == "" {
+		flag.Usage()
+		return fmt.Errorf("-type and -out are required")
+	}
+
+	if *at != "" {
+		if timestamp, err = time.Parse(time.RFC3339, *at); err != nil {
+			return err
+		}
+		opts = append(opts, objbinlog.OptAt{Value: timestamp})
+	}
+
+	if *transaction != "" {
+		opts = append(
+			opts,
+			objbinlog.OptAtTransactionId[transactionId]{
+				Value: transactionId(*transaction),
+			},
+		)
+	}
+
+	dst, err = os.OpenFile(*outPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	if *segmentsDir != "" {
+		restored, err = objbinlog.RestoreSegmented[transactionId](
+			objbinlog.NewFileSegmentFactory(*segmentsDir),
+			dst,
+			&jsonl.JsonlMarshalUnmarshaller[any]{},
+			*objType,
+			opts...,
+		)
+	} else {
+		if src, err = os.Open(*logPath); err != nil {
+			return err
+		}
+		defer src.Close()
+
+		restored, err = objbinlog.Restore[transactionId](
+			src,
+			dst,
+			&jsonl.JsonlMarshalUnmarshaller[any]{},
+			*objType,
+			opts...,
+		)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("restored %d records of type %s\n", restored, *objType)
+	fmt.Fprintln(
+		os.Stderr,
+		"open it without recovery or the bin log undoes the restore, "+
+			"see objbinlog.Restore",
+	)
+
+	return nil
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+
+	fmt.Fprintf(
+		out,
+		"usage: %s (-log file | -segments dir) -type type -out file "+
+			"[-at time | -transaction id]\n\n",
+		os.Args[0],
+	)
+	flag.PrintDefaults()
+}
@@ -1,19 +1,43 @@
 package fstln
 
 import (
+	"context"
 	"sync"
 
 	datastruc "github.com/yo3jones/datastruc/pkg"
 	"github.com/yo3jones/stg/pkg/stg"
 )
 
+// Storage is a file of newline separated lines. Every method has a Context
+// variant that returns the context's error instead of starting once the
+// context is done.
 type Storage interface {
 	Delete(pos Position) (err error)
+	DeleteContext(ctx context.Context, pos Position) (err error)
 	Insert(line []byte) (pos Position, err error)
+	InsertContext(ctx context.Context, line []byte) (pos Position, err error)
+	InsertMany(lines [][]byte) (positions []Position, err error)
+	InsertManyContext(
+		ctx context.Context,
+		lines [][]byte,
+	) (positions []Position, err error)
 	Maintenance() (freed int, err error)
+	MaintenanceContext(ctx context.Context) (freed int, err error)
 	Read(line []byte) (pos Position, n int, isPrefix bool, err error)
+	ReadContext(
+		ctx context.Context,
+		line []byte,
+	) (pos Position, n int, isPrefix bool, err error)
+	ReadAt(pos Position) (line []byte, err error)
+	ReadAtContext(ctx context.Context, pos Position) (line []byte, err error)
 	ResetScan() (err error)
+	ResetScanContext(ctx context.Context) (err error)
 	Update(pos Position, line []byte) (afterPos Position, err error)
+	UpdateContext(
+		ctx context.Context,
+		pos Position,
+		line []byte,
+	) (afterPos Position, err error)
 }
 
 type storage struct {
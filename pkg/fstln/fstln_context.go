@@ -0,0 +1,78 @@
+package fstln
+
+import "context"
+
+func (stg *storage) DeleteContext(
+	ctx context.Context,
+	pos Position,
+) (err error) {
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	return stg.Delete(pos)
+}
+
+func (stg *storage) InsertContext(
+	ctx context.Context,
+	line []byte,
+) (pos Position, err error) {
+	if err = ctx.Err(); err != nil {
+		return pos, err
+	}
+
+	return stg.Insert(line)
+}
+
+func (stg *storage) InsertManyContext(
+	ctx context.Context,
+	lines [][]byte,
+) (positions []Position, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return stg.InsertMany(lines)
+}
+
+func (stg *storage) ReadContext(
+	ctx context.Context,
+	line []byte,
+) (pos Position, n int, isPrefix bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return pos, 0, false, err
+	}
+
+	return stg.Read(line)
+}
+
+func (stg *storage) ReadAtContext(
+	ctx context.Context,
+	pos Position,
+) (line []byte, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return stg.ReadAt(pos)
+}
+
+func (stg *storage) ResetScanContext(ctx context.Context) (err error) {
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+
+	return stg.ResetScan()
+}
+
+func (stg *storage) UpdateContext(
+	ctx context.Context,
+	pos Position,
+	line []byte,
+) (afterPos Position, err error) {
+	if err = ctx.Err(); err != nil {
+		return afterPos, err
+	}
+
+	return stg.Update(pos, line)
+}
@@ -0,0 +1,120 @@
+package fstln
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	type test struct {
+		name   string
+		call   func(ctx context.Context, stg *storage) error
+		expect []string
+	}
+
+	tests := []test{
+		{
+			name: "with delete",
+			call: func(ctx context.Context, stg *storage) error {
+				return stg.DeleteContext(ctx, Position{Offset: 0, Len: 4})
+			},
+			expect: []string{"   ", "two"},
+		},
+		{
+			name: "with insert",
+			call: func(ctx context.Context, stg *storage) (err error) {
+				_, err = stg.InsertContext(ctx, []byte("three"))
+				return err
+			},
+			expect: []string{"one", "two", "three"},
+		},
+		{
+			name: "with read",
+			call: func(ctx context.Context, stg *storage) (err error) {
+				_, _, _, err = stg.ReadContext(ctx, make([]byte, 10))
+				return err
+			},
+			expect: []string{"one", "two"},
+		},
+		{
+			name: "with read at",
+			call: func(ctx context.Context, stg *storage) (err error) {
+				_, err = stg.ReadAtContext(ctx, Position{Offset: 4, Len: 4})
+				return err
+			},
+			expect: []string{"one", "two"},
+		},
+		{
+			name: "with reset scan",
+			call: func(ctx context.Context, stg *storage) error {
+				return stg.ResetScanContext(ctx)
+			},
+			expect: []string{"one", "two"},
+		},
+		{
+			name: "with update",
+			call: func(ctx context.Context, stg *storage) (err error) {
+				_, err = stg.UpdateContext(
+					ctx,
+					Position{Offset: 4, Len: 4},
+					[]byte("six"),
+				)
+				return err
+			},
+			expect: []string{"one", "six"},
+		},
+	}
+
+	for _, tc := range tests {
+		for _, canceled := range []bool{false, true} {
+			name := tc.name
+			if canceled {
+				name += " canceled"
+			}
+
+			t.Run(name, func(t *testing.T) {
+				util, _, err := NewTestUtil().
+					SetTest(t).
+					SetName("test.jsonl").
+					SetLines("one", "two").
+					Setup()
+				defer util.Teardown()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+
+				expect := tc.expect
+				if canceled {
+					cancel()
+					expect = []string{"one", "two"}
+				}
+
+				err = tc.call(ctx, util.Stg)
+
+				if !canceled && err != nil {
+					t.Fatal(err)
+				}
+
+				if canceled && err != context.Canceled {
+					t.Errorf(
+						"expected error to be %v but got %v",
+						context.Canceled,
+						err,
+					)
+				}
+
+				got := util.ReadOutput()
+				if got != util.Join(expect...) {
+					t.Errorf(
+						"expected output to be \n%s\n but got \n%s\n",
+						util.Join(expect...),
+						got,
+					)
+				}
+			})
+		}
+	}
+}
@@ -1,6 +1,17 @@
 package fstln
 
+import "context"
+
 func (stg *storage) Maintenance() (freed int, err error) {
+	return stg.MaintenanceContext(context.Background())
+}
+
+// MaintenanceContext is Maintenance that stops between lines once ctx is done.
+// The lines moved so far stay moved and the gap they leave behind is blanked,
+// so nothing is freed but the file stays valid.
+func (stg *storage) MaintenanceContext(
+	ctx context.Context,
+) (freed int, err error) {
 	stg.readLock.Lock()
 	defer stg.readLock.Unlock()
 	stg.writeLock.Lock()
@@ -18,6 +29,10 @@ func (stg *storage) Maintenance() (freed int, err error) {
 	}
 
 	for !stg.scanEof {
+		if err = ctx.Err(); err != nil {
+			return 0, stg.abortMaintenance(emptyLines, err)
+		}
+
 		stg.readPhase = phaseEmpty
 		if pos, readEmptyLines, err = stg.readEmptyLines(); err != nil {
 			return freed, err
@@ -61,3 +76,17 @@ func (stg *storage) Maintenance() (freed int, err error) {
 
 	return emptyLines.Len, nil
 }
+
+// abortMaintenance blanks the gap between the moved lines and the ones not
+// reached yet, which still holds bytes of lines that were moved out of it.
+func (stg *storage) abortMaintenance(emptyLines *Position, err error) error {
+	if emptyLines == nil {
+		return err
+	}
+
+	if deleteErr := stg.delete(*emptyLines); deleteErr != nil {
+		return deleteErr
+	}
+
+	return err
+}
@@ -1,6 +1,7 @@
 package fstln
 
 import (
+	"context"
 	"testing"
 )
 
@@ -168,3 +169,93 @@ func TestMaintenance(t *testing.T) {
 		})
 	}
 }
+
+func TestMaintenanceContext(t *testing.T) {
+	type test struct {
+		name        string
+		errAfter    int
+		expectError bool
+		expectFreed int
+		expect      []string
+	}
+
+	tests := []test{
+		{
+			name:        "with done context",
+			errAfter:    0,
+			expectError: true,
+			expect:      []string{"   ", "one", "two", "   ", "three"},
+		},
+		{
+			name:        "with context done after moving a line",
+			errAfter:    1,
+			expectError: true,
+			expect:      []string{"one", "   ", "two", "   ", "three"},
+		},
+		{
+			name:        "with context not done",
+			errAfter:    100,
+			expectFreed: 8,
+			expect:      []string{"one", "two", "three"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			util, _, err := NewTestUtil().
+				SetTest(t).
+				SetName("test.jsonl").
+				SetLines("   ", "one", "two", "   ", "three").
+				Setup()
+			defer util.Teardown()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := &errAfterContext{Context: context.Background(), n: tc.errAfter}
+
+			gotFreed, err := util.Stg.MaintenanceContext(ctx)
+
+			if !tc.expectError && err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.expectError && err != context.Canceled {
+				t.Errorf("expected error to be %v but got %v", context.Canceled, err)
+			}
+
+			if gotFreed != tc.expectFreed {
+				t.Errorf(
+					"expected maintenance to have freed %d bytes but got %d",
+					tc.expectFreed,
+					gotFreed,
+				)
+			}
+
+			got := util.ReadOutput()
+			expect := util.Join(tc.expect...)
+
+			if got != expect {
+				t.Errorf(
+					"expected output to be \n%s\n but got \n%s\n",
+					expect,
+					got,
+				)
+			}
+		})
+	}
+}
+
+// errAfterContext is a context that is done once Err has been called n times.
+type errAfterContext struct {
+	context.Context
+	n int
+}
+
+func (ctx *errAfterContext) Err() error {
+	if ctx.n <= 0 {
+		return context.Canceled
+	}
+	ctx.n--
+	return nil
+}
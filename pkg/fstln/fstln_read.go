@@ -1,6 +1,7 @@
 package fstln
 
 import (
+	"fmt"
 	"io"
 )
 
@@ -30,6 +31,45 @@ func (stg *storage) Read(
 	return stg.fillInputBuffer(line)
 }
 
+// ReadAt reads the line stored at pos, including its trailing new line. The
+// handle is put back where it was afterwards so that a scan in progress is not
+// disturbed.
+func (stg *storage) ReadAt(pos Position) (line []byte, err error) {
+	stg.readLock.Lock()
+	defer stg.readLock.Unlock()
+
+	var offset int64
+
+	if pos.Len < 1 {
+		return nil, fmt.Errorf("%w, %+v", invalidPositionError, pos)
+	}
+
+	if offset, err = stg.handle.Seek(int64(0), io.SeekCurrent); err != nil {
+		return nil, err
+	}
+
+	if _, err = stg.handle.Seek(int64(pos.Offset), io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	line = make([]byte, pos.Len)
+	_, err = io.ReadFull(stg.handle, line)
+
+	if _, seekErr := stg.handle.Seek(offset, io.SeekStart); err == nil {
+		err = seekErr
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if line[pos.Len-1] != '\n' || line[0] == ' ' || line[0] == '\n' {
+		return nil, fmt.Errorf("%w, %+v", invalidPositionError, pos)
+	}
+
+	return line, nil
+}
+
 func (stg *storage) ResetScan() (err error) {
 	stg.readLock.Lock()
 	defer stg.readLock.Unlock()
@@ -237,9 +277,15 @@ func (stg *storage) resetScanUnsafe() (err error) {
 	stg.lineCurr = 0
 	stg.linePrefix = false
 	stg.offsetEnd = endOffset
-	stg.scanEof = false
+	// An empty file has no lines, so the scan is over before it starts rather
+	// than reading a first line out of a buffer that was never filled.
+	stg.scanEof = endOffset == 0
 	stg.scanCurr = 0
 	stg.scanEnd = endOffset
 
 	return nil
 }
+
+var invalidPositionError = fmt.Errorf(
+	"illegal argument error, no line at position",
+)
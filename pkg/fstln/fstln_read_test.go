@@ -90,6 +90,11 @@ func TestRead(t *testing.T) {
 				"two",
 			},
 		},
+		{
+			name:   "with empty file",
+			lines:  []string{},
+			expect: []string{},
+		},
 		{
 			name: "with small read buffer size",
 			lines: []string{
@@ -221,3 +226,109 @@ func TestRead(t *testing.T) {
 		})
 	}
 }
+
+func TestReadAt(t *testing.T) {
+	type test struct {
+		name        string
+		pos         Position
+		expect      string
+		expectError string
+		error       *mockError
+	}
+
+	tests := []test{
+		{
+			name:   "with line",
+			pos:    Position{Offset: 8, Len: 4},
+			expect: "two\n",
+		},
+		{
+			name:        "with empty line",
+			pos:         Position{Offset: 4, Len: 4},
+			expectError: "illegal argument error, no line at position, {Offset:4 Len:4}",
+		},
+		{
+			name:        "with partial line",
+			pos:         Position{Offset: 0, Len: 2},
+			expectError: "illegal argument error, no line at position, {Offset:0 Len:2}",
+		},
+		{
+			name:        "with zero length",
+			pos:         Position{Offset: 0, Len: 0},
+			expectError: "illegal argument error, no line at position, {Offset:0 Len:0}",
+		},
+		{
+			name:        "past the end",
+			pos:         Position{Offset: 8, Len: 10},
+			expectError: "unexpected EOF",
+		},
+		{
+			name: "with seek error",
+			pos:  Position{Offset: 8, Len: 4},
+			error: &mockError{
+				errorType: mockErrorTypeSeek,
+				errorOn:   2,
+				msg:       "mock seek error",
+			},
+			expectError: "mock seek error",
+		},
+		{
+			name: "with read error",
+			pos:  Position{Offset: 8, Len: 4},
+			error: &mockError{
+				errorType: mockErrorTypeRead,
+				errorOn:   5,
+				msg:       "mock read error",
+			},
+			expectError: "mock read error",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			util, _, err := NewTestUtil().
+				SetTest(t).
+				SetName("test.jsonl").
+				SetLines("one", "   ", "two").
+				SetOptions(OptionBufferSize{2}).
+				SetMockError(tc.error).
+				Setup()
+			defer util.Teardown()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, line, err := util.ReadLine(); err != nil || line != "one\n" {
+				t.Fatalf("expected to read one but got %s, %v", line, err)
+			}
+
+			got, err := util.Stg.ReadAt(tc.pos)
+
+			if tc.expectError != "" {
+				if err == nil {
+					t.Fatalf("expected an error but got nil")
+				}
+				if err.Error() != tc.expectError {
+					t.Fatalf(
+						"expected an error with message \n%s\n but got \n%s\n",
+						tc.expectError,
+						err.Error(),
+					)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(got) != tc.expect {
+				t.Errorf("expected line \n%s\n but got \n%s\n", tc.expect, got)
+			}
+
+			if _, line, _ := util.ReadLine(); line != "two\n" {
+				t.Errorf("expected scan to continue with two but got %s", line)
+			}
+		})
+	}
+}
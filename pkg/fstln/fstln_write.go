@@ -20,6 +20,50 @@ func (stg *storage) Insert(line []byte) (position Position, err error) {
 	return stg.insertUnsafe(context)
 }
 
+// InsertMany appends lines to the end of the file with a single write, which
+// is much faster than inserting them one at a time. The empty lines left by
+// deletes are not reused.
+func (stg *storage) InsertMany(
+	lines [][]byte,
+) (positions []Position, err error) {
+	stg.writeLock.Lock()
+	defer stg.writeLock.Unlock()
+
+	var (
+		buffer = make([]byte, 0, len(lines)*64)
+		offset = int(stg.offsetEnd)
+	)
+
+	positions = make([]Position, 0, len(lines))
+
+	for _, line := range lines {
+		context := newWriteLineContext(line)
+
+		buffer = append(buffer, line...)
+		if !context.hasTrailingNewLine {
+			buffer = append(buffer, '\n')
+		}
+
+		positions = append(positions, Position{
+			Offset: offset,
+			Len:    context.effectiveLen,
+		})
+		offset += context.effectiveLen
+	}
+
+	if len(buffer) == 0 {
+		return positions, nil
+	}
+
+	if _, err = stg.handle.WriteAt(buffer, stg.offsetEnd); err != nil {
+		return nil, err
+	}
+
+	stg.offsetEnd += int64(len(buffer))
+
+	return positions, nil
+}
+
 func (stg *storage) Update(
 	pos Position,
 	line []byte,
@@ -363,6 +363,119 @@ func TestInsert(t *testing.T) {
 	})
 }
 
+func TestInsertMany(t *testing.T) {
+	type test struct {
+		name            string
+		lines           []string
+		insert          []string
+		expect          []string
+		expectPositions []Position
+		error           *mockError
+	}
+
+	tests := []test{
+		{
+			name:   "with append",
+			lines:  []string{"   ", "one"},
+			insert: []string{"two", "three\n", ""},
+			expect: []string{"   ", "one", "two", "three", ""},
+			expectPositions: []Position{
+				{Offset: 8, Len: 4},
+				{Offset: 12, Len: 6},
+				{Offset: 18, Len: 1},
+			},
+		},
+		{
+			name:            "without lines",
+			lines:           []string{"one"},
+			insert:          []string{},
+			expect:          []string{"one"},
+			expectPositions: []Position{},
+		},
+		{
+			name:   "with write error",
+			lines:  []string{"one"},
+			insert: []string{"two"},
+			error: &mockError{
+				errorType: mockErrorTypeWriteAt,
+				errorOn:   0,
+				msg:       "mock write error",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				err   error
+				got   []Position
+				lines = make([][]byte, 0, len(tc.insert))
+				util  *TestUtil
+			)
+
+			util, _, err = NewTestUtil().
+				SetTest(t).
+				SetName("test.jsonl").
+				SetLines(tc.lines...).
+				SetMockError(tc.error).
+				Setup()
+			defer util.Teardown()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, line := range tc.insert {
+				lines = append(lines, []byte(line))
+			}
+
+			got, err = util.Stg.InsertMany(lines)
+
+			if tc.error == nil && err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.error != nil {
+				if err == nil || err.Error() != tc.error.msg {
+					t.Errorf("expected error %s but got %v", tc.error.msg, err)
+				}
+				return
+			}
+
+			if !reflect.DeepEqual(got, tc.expectPositions) {
+				t.Errorf(
+					"expected positions to be %v but got %v",
+					tc.expectPositions,
+					got,
+				)
+			}
+
+			next, err := util.Stg.Insert([]byte("last"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectOffset := len(util.Join(tc.expect...))
+			if next.Offset != expectOffset {
+				t.Errorf(
+					"expected next insert at %d but got %d",
+					expectOffset,
+					next.Offset,
+				)
+			}
+
+			output := util.ReadOutput()
+			expect := util.Join(append(tc.expect, "last")...)
+
+			if output != expect {
+				t.Errorf(
+					"expected output to be \n%s\n but got \n%s\n",
+					expect,
+					output,
+				)
+			}
+		})
+	}
+}
+
 func TestUpdate(t *testing.T) {
 	var (
 		longer = func(pos Position, line string, stg *storage) (err error) {
@@ -1,7 +1,11 @@
 package jsonl
 
 import (
+	"bytes"
 	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
 )
 
 type JsonlMarshalUnmarshaller[S any] struct{}
@@ -13,3 +17,149 @@ func (*JsonlMarshalUnmarshaller[S]) Marshal(v S) ([]byte, error) {
 func (*JsonlMarshalUnmarshaller[S]) Unmarshal(data []byte, v S) error {
 	return json.Unmarshal(data, v)
 }
+
+// UnmarshalFields decodes only the top level members of data named in fields
+// into v and leaves the other struct fields alone. Members are matched to
+// fields the way encoding/json matches them, preferring an exact match and
+// otherwise ignoring case. The members that are not wanted are scanned but
+// never decoded or copied. When v is not a pointer to a struct, or a
+// name does not match one of its fields, the whole of data is decoded instead.
+func (marshalUnmarshaller *JsonlMarshalUnmarshaller[S]) UnmarshalFields(
+	data []byte,
+	v S,
+	fields []string,
+) (err error) {
+	var (
+		field   reflect.Value
+		key     string
+		matched string
+		token   json.Token
+		value   = reflect.ValueOf(v)
+		wanted  = make(map[string]bool, len(fields))
+	)
+
+	if value.Kind() != reflect.Pointer ||
+		value.IsNil() ||
+		value.Elem().Kind() != reflect.Struct {
+		return marshalUnmarshaller.Unmarshal(data, v)
+	}
+
+	value = value.Elem()
+	set := structFields(value.Type())
+
+	for _, name := range fields {
+		if matched = set.match(name); matched == "" {
+			return marshalUnmarshaller.Unmarshal(data, v)
+		}
+		wanted[matched] = true
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+
+	if token, err = decoder.Token(); err != nil {
+		return err
+	}
+	if token != json.Delim('{') {
+		return marshalUnmarshaller.Unmarshal(data, v)
+	}
+
+	for decoder.More() {
+		if token, err = decoder.Token(); err != nil {
+			return err
+		}
+		key, _ = token.(string)
+
+		if matched = set.match(key); !wanted[matched] {
+			if err = decoder.Decode(&skipped{}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if field, err = value.FieldByIndexErr(set.indexes[matched]); err != nil {
+			return marshalUnmarshaller.Unmarshal(data, v)
+		}
+
+		if err = decoder.Decode(field.Addr().Interface()); err != nil {
+			return err
+		}
+	}
+
+	if _, err = decoder.Token(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// skipped is decoded in place of the members that are not wanted. The decoder
+// only scans them and hands their bytes to UnmarshalJSON, which keeps none.
+type skipped struct{}
+
+func (*skipped) UnmarshalJSON([]byte) error {
+	return nil
+}
+
+// fieldSet holds the JSON member names of a struct type's fields, in field
+// order, and their index.
+type fieldSet struct {
+	indexes map[string][]int
+	names   []string
+}
+
+// match returns the name of the field that encoding/json decodes the member
+// key into, an exact match or else the first that only differs in case, and
+// an empty string when there is none.
+func (set *fieldSet) match(key string) string {
+	if _, found := set.indexes[key]; found {
+		return key
+	}
+
+	for _, name := range set.names {
+		if strings.EqualFold(name, key) {
+			return name
+		}
+	}
+
+	return ""
+}
+
+var fieldSetCache sync.Map
+
+// structFields maps the JSON member names of a struct type's fields to their
+// index, following the same tags encoding/json does.
+func structFields(t reflect.Type) *fieldSet {
+	if cached, found := fieldSetCache.Load(t); found {
+		return cached.(*fieldSet)
+	}
+
+	set := &fieldSet{indexes: map[string][]int{}}
+
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" && field.Anonymous {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		if _, found := set.indexes[name]; !found {
+			set.indexes[name] = field.Index
+			set.names = append(set.names, name)
+		}
+	}
+
+	fieldSetCache.Store(t, set)
+
+	return set
+}
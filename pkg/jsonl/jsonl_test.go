@@ -1,7 +1,10 @@
 package jsonl
 
 import (
+	"encoding/json"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -52,3 +55,208 @@ func TestUnmarshal(t *testing.T) {
 		t.Errorf("expected unmarshalled value to be %v but got %v", expect, got)
 	}
 }
+
+func TestUnmarshalFields(t *testing.T) {
+	type embedded struct {
+		Bar string `json:"bar"`
+	}
+
+	type wideSpec struct {
+		Id int `json:"id"`
+		embedded
+		Skip  string `json:"-"`
+		Plain string
+	}
+
+	type test struct {
+		name        string
+		data        string
+		fields      []string
+		got         any
+		expect      any
+		expectError bool
+	}
+
+	tests := []test{
+		{
+			name:   "with some fields",
+			data:   `{"id":1,"foo":"foo","bar":"bar"}`,
+			fields: []string{"id", "bar"},
+			got:    &TestSpec{},
+			expect: &TestSpec{Id: 1, Bar: "bar"},
+		},
+		{
+			name:   "with missing member",
+			data:   `{"id":1}`,
+			fields: []string{"id", "bar"},
+			got:    &TestSpec{},
+			expect: &TestSpec{Id: 1},
+		},
+		{
+			name:   "with embedded and untagged fields",
+			data:   `{"id":1,"bar":"bar","Skip":"skip","Plain":"plain"}`,
+			fields: []string{"bar", "Plain"},
+			got:    &wideSpec{},
+			expect: &wideSpec{embedded: embedded{Bar: "bar"}, Plain: "plain"},
+		},
+		{
+			name:   "with member names in another case",
+			data:   `{"ID":1,"Foo":"foo","BAR":"bar"}`,
+			fields: []string{"id", "bar"},
+			got:    &TestSpec{},
+			expect: &TestSpec{Id: 1, Bar: "bar"},
+		},
+		{
+			name:   "with field names in another case",
+			data:   `{"id":1,"foo":"foo","bar":"bar"}`,
+			fields: []string{"Id", "BAR"},
+			got:    &TestSpec{},
+			expect: &TestSpec{Id: 1, Bar: "bar"},
+		},
+		{
+			name:   "with nested members skipped",
+			data:   `{"foo":{"id":2,"bar":["x",{"y":[]}]},"id":1,"bar":"bar"}`,
+			fields: []string{"id", "bar"},
+			got:    &TestSpec{},
+			expect: &TestSpec{Id: 1, Bar: "bar"},
+		},
+		{
+			name:   "with null",
+			data:   `null`,
+			fields: []string{"id"},
+			got:    &TestSpec{},
+			expect: &TestSpec{},
+		},
+		{
+			name:   "with unknown field",
+			data:   `{"id":1,"foo":"foo","bar":"bar"}`,
+			fields: []string{"id", "fiz"},
+			got:    &TestSpec{},
+			expect: &TestSpec{Id: 1, Foo: "foo", Bar: "bar"},
+		},
+		{
+			name:   "with map",
+			data:   `{"id":1,"foo":"foo"}`,
+			fields: []string{"id"},
+			got:    &map[string]any{},
+			expect: &map[string]any{"id": float64(1), "foo": "foo"},
+		},
+		{
+			name:        "with invalid data",
+			data:        `{"id":1,`,
+			fields:      []string{"id"},
+			got:         &TestSpec{},
+			expectError: true,
+		},
+		{
+			name:        "with invalid member",
+			data:        `{"id":"one"}`,
+			fields:      []string{"id"},
+			got:         &TestSpec{},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			jsonlMarshalUnmarshaller := &JsonlMarshalUnmarshaller[any]{}
+
+			err := jsonlMarshalUnmarshaller.UnmarshalFields(
+				[]byte(tc.data),
+				tc.got,
+				tc.fields,
+			)
+
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("expected an error but got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tc.got, tc.expect) {
+				t.Errorf(
+					"expected unmarshalled value to be %v but got %v",
+					tc.expect,
+					tc.got,
+				)
+			}
+		})
+	}
+}
+
+// wideSpec stands for a record with many members of which a select only
+// projects a few.
+type wideSpec struct {
+	Id     int               `json:"id"`
+	Foo    string            `json:"foo"`
+	Bar    string            `json:"bar"`
+	Tags   []string          `json:"tags"`
+	Labels map[string]string `json:"labels"`
+	Body   string            `json:"body"`
+	Items  []wideItem        `json:"items"`
+}
+
+type wideItem struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
+
+func wideData(b *testing.B) []byte {
+	spec := &wideSpec{
+		Id:     1,
+		Foo:    "foo",
+		Bar:    "bar",
+		Labels: map[string]string{},
+		Body:   strings.Repeat("lorem ipsum ", 100),
+	}
+
+	for i := 0; i < 50; i++ {
+		spec.Tags = append(spec.Tags, fmt.Sprintf("tag%d", i))
+		spec.Labels[fmt.Sprintf("label%d", i)] = fmt.Sprintf("value%d", i)
+		spec.Items = append(spec.Items, wideItem{fmt.Sprintf("item%d", i), 1.5})
+	}
+
+	data, err := json.Marshal(spec)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return data
+}
+
+func BenchmarkUnmarshalFields(b *testing.B) {
+	data := wideData(b)
+	jsonlMarshalUnmarshaller := &JsonlMarshalUnmarshaller[*wideSpec]{}
+	fields := []string{"id", "foo"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := jsonlMarshalUnmarshaller.UnmarshalFields(
+			data,
+			&wideSpec{},
+			fields,
+		); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshal(b *testing.B) {
+	data := wideData(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := json.Unmarshal(data, &wideSpec{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
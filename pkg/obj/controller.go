@@ -1,13 +1,19 @@
 package obj
 
-import "github.com/yo3jones/stg/pkg/fstln"
+import (
+	"context"
+	"sync"
+
+	"github.com/yo3jones/stg/pkg/fstln"
+)
 
 type specMsg[S any] struct {
-	op     op
-	pos    fstln.Position
-	raw    []byte
-	source string
-	spec   S
+	fromPos fstln.Position
+	op      op
+	pos     fstln.Position
+	raw     []byte
+	source  string
+	spec    S
 }
 
 type op int
@@ -20,10 +26,56 @@ const (
 	opDone
 )
 
+type optAggregate[S any] struct {
+	value func() func(s S)
+}
+
+type optClaim[S any] struct {
+	value func(s S, pos fstln.Position) error
+}
+
+type optContext struct {
+	value context.Context
+}
+
+type optExpectVersion struct {
+	value int64
+}
+
+type optFields struct {
+	value []string
+}
+
 type optOp struct {
 	value op
 }
 
+type optPositions struct {
+	value []fstln.Position
+}
+
+type optVersion[S any] struct {
+	value Accessor[S, int64]
+}
+
 // type optSource struct {
 // 	value string
 // }
+
+// reportCanceled reports whether ctx is done. The first worker to notice sends
+// the context's error on errCh, the others just stop.
+func reportCanceled(
+	ctx context.Context,
+	once *sync.Once,
+	errCh chan error,
+) (canceled bool) {
+	var err error
+
+	if err = ctx.Err(); err == nil {
+		return false
+	}
+
+	once.Do(func() { errCh <- err })
+
+	return true
+}
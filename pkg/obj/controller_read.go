@@ -1,6 +1,7 @@
 package obj
 
 import (
+	"context"
 	"io"
 	"sync"
 
@@ -9,23 +10,34 @@ import (
 )
 
 type readController[S any] struct {
+	aggregate           func() func(s S)
 	bufferLen           int
 	ch                  chan specMsg[S]
+	cancelOnce          sync.Once
 	concurrency         int
+	ctx                 context.Context
 	errCh               chan error
 	factory             SpecFactory[S]
+	fields              []string
 	filters             Matcher[S]
 	lock                sync.Mutex
 	op                  op
+	positions           []fstln.Position
+	positionsCurr       int
 	source              string
 	stg                 fstln.Storage
 	marshalUnmarshaller stg.MarshalUnmarshaller[S]
+	useIndex            bool
 }
 
 type readControllerOpt interface {
 	isReadControllerOpt() bool
 }
 
+func (opt optAggregate[S]) isReadControllerOpt() bool {
+	return true
+}
+
 func (opt optBufferLen) isReadControllerOpt() bool {
 	return true
 }
@@ -34,10 +46,22 @@ func (opt optConcurrency) isReadControllerOpt() bool {
 	return true
 }
 
+func (opt optContext) isReadControllerOpt() bool {
+	return true
+}
+
+func (opt optFields) isReadControllerOpt() bool {
+	return true
+}
+
 func (opt optOp) isReadControllerOpt() bool {
 	return true
 }
 
+func (opt optPositions) isReadControllerOpt() bool {
+	return true
+}
+
 // func (opt optSource) isReadControllerOpt() bool {
 // 	return true
 // }
@@ -55,6 +79,7 @@ func newReadController[S any](
 		bufferLen:           1000,
 		ch:                  ch,
 		concurrency:         10,
+		ctx:                 context.Background(),
 		errCh:               errCh,
 		factory:             factory,
 		filters:             filters,
@@ -67,12 +92,21 @@ func newReadController[S any](
 	for _, opt := range opts {
 		opt.isReadControllerOpt()
 		switch opt := opt.(type) {
+		case optAggregate[S]:
+			controller.aggregate = opt.value
 		case optBufferLen:
 			controller.bufferLen = opt.value
 		case optConcurrency:
 			controller.concurrency = opt.value
+		case optContext:
+			controller.ctx = opt.value
+		case optFields:
+			controller.fields = opt.value
 		case optOp:
 			controller.op = opt.value
+		case optPositions:
+			controller.positions = opt.value
+			controller.useIndex = true
 			// case optSource:
 			// 	controller.source = opt.value
 		}
@@ -87,8 +121,12 @@ func (controller *readController[S]) Start() {
 		waitGroup sync.WaitGroup
 	)
 
-	if err = controller.stg.ResetScan(); err != nil {
+	if err = controller.resetScan(); err != nil {
 		controller.errCh <- err
+		controller.ch <- specMsg[S]{
+			op:     opDone,
+			source: controller.source,
+		}
 		return
 	}
 
@@ -110,13 +148,24 @@ func (controller *readController[S]) Start() {
 
 func (controller *readController[S]) startProc() {
 	var (
+		add  func(s S)
 		data []byte
 		err  error
 		pos  fstln.Position
 		msg  specMsg[S]
 	)
 
+	// When aggregating each worker folds the records it reads into its own
+	// partial result instead of sending them on.
+	if controller.aggregate != nil {
+		add = controller.aggregate()
+	}
+
 	for {
+		if controller.canceled() {
+			break
+		}
+
 		if pos, data, err = controller.read(); err != nil && err != io.EOF {
 			controller.errCh <- err
 			break
@@ -133,7 +182,35 @@ func (controller *readController[S]) startProc() {
 			continue
 		}
 
-		controller.ch <- msg
+		if add != nil {
+			add(msg.spec)
+			continue
+		}
+
+		if sent := controller.send(msg); !sent {
+			break
+		}
+	}
+}
+
+func (controller *readController[S]) canceled() bool {
+	return reportCanceled(
+		controller.ctx,
+		&controller.cancelOnce,
+		controller.errCh,
+	)
+}
+
+// send hands msg on unless the context is done first, in which case it
+// reports false. The done message is always sent so that the consumer can
+// tell every worker has stopped.
+func (controller *readController[S]) send(msg specMsg[S]) (sent bool) {
+	select {
+	case controller.ch <- msg:
+		return true
+	case <-controller.ctx.Done():
+		controller.canceled()
+		return false
 	}
 }
 
@@ -143,7 +220,13 @@ func (controller *readController[S]) unmarshal(
 ) (msg specMsg[S], err error) {
 	s := controller.factory.New()
 
-	err = controller.marshalUnmarshaller.Unmarshal(data, s)
+	unmarshaller := controller.marshalUnmarshaller
+	fieldUnmarshaller, ok := unmarshaller.(stg.FieldUnmarshaller[S])
+	if ok && len(controller.fields) > 0 {
+		err = fieldUnmarshaller.UnmarshalFields(data, s, controller.fields)
+	} else {
+		err = unmarshaller.Unmarshal(data, s)
+	}
 	if err != nil {
 		return msg, err
 	}
@@ -161,6 +244,10 @@ func (controller *readController[S]) read() (pos fstln.Position, data []byte, er
 	controller.lock.Lock()
 	defer controller.lock.Unlock()
 
+	if controller.useIndex {
+		return controller.readAt()
+	}
+
 	data = make([]byte, controller.bufferLen)
 
 	var (
@@ -170,7 +257,10 @@ func (controller *readController[S]) read() (pos fstln.Position, data []byte, er
 	)
 
 	for {
-		pos, _, isPrefix, err = controller.stg.Read(buffer)
+		pos, _, isPrefix, err = controller.stg.ReadContext(
+			controller.ctx,
+			buffer,
+		)
 		if err != nil && err != io.EOF {
 			return pos, nil, err
 		} else if pos == fstln.EOF {
@@ -188,3 +278,33 @@ func (controller *readController[S]) read() (pos fstln.Position, data []byte, er
 		buffer = data[dataLen:]
 	}
 }
+
+// readAt reads the next of the positions an index narrowed the read down to.
+func (controller *readController[S]) readAt() (
+	pos fstln.Position,
+	data []byte,
+	err error,
+) {
+	if controller.positionsCurr >= len(controller.positions) {
+		return fstln.EOF, nil, io.EOF
+	}
+
+	pos = controller.positions[controller.positionsCurr]
+	controller.positionsCurr++
+
+	data, err = controller.stg.ReadAtContext(controller.ctx, pos)
+	if err != nil {
+		return pos, nil, err
+	}
+
+	return pos, data[:pos.Len-1], nil
+}
+
+func (controller *readController[S]) resetScan() (err error) {
+	if controller.useIndex {
+		controller.positionsCurr = 0
+		return nil
+	}
+
+	return controller.stg.ResetScanContext(controller.ctx)
+}
@@ -1,6 +1,7 @@
 package obj
 
 import (
+	"context"
 	"sync"
 	"time"
 
@@ -11,8 +12,12 @@ import (
 
 type writeController[I comparable, S any] struct {
 	binLogTrans         objbinlog.Transaction
+	cancelOnce          sync.Once
+	claim               func(s S, pos fstln.Position) error
 	concurrency         int
+	ctx                 context.Context
 	errCh               chan error
+	expectVersion       *int64
 	idAccessor          Accessor[S, I]
 	inCh                chan specMsg[S]
 	outCh               chan specMsg[S]
@@ -22,6 +27,7 @@ type writeController[I comparable, S any] struct {
 	source              string
 	stg                 fstln.Storage
 	updatedAtAccessor   Accessor[S, time.Time]
+	versionAccessor     Accessor[S, int64]
 }
 
 func newWriteController[I comparable, S any](
@@ -39,6 +45,7 @@ func newWriteController[I comparable, S any](
 	controller := &writeController[I, S]{
 		binLogTrans:         binLogTrans,
 		concurrency:         10,
+		ctx:                 context.Background(),
 		idAccessor:          idAccessor,
 		inCh:                inCh,
 		outCh:               outCh,
@@ -54,8 +61,16 @@ func newWriteController[I comparable, S any](
 	for _, opt := range opts {
 		opt.isWriteControllerOpt()
 		switch opt := opt.(type) {
+		case optClaim[S]:
+			controller.claim = opt.value
 		case optConcurrency:
 			controller.concurrency = opt.value
+		case optContext:
+			controller.ctx = opt.value
+		case optExpectVersion:
+			controller.expectVersion = &opt.value
+		case optVersion[S]:
+			controller.versionAccessor = opt.value
 			// case optSource:
 			// 	controller.source = opt.value
 		}
@@ -68,10 +83,26 @@ type writeControllerOpt interface {
 	isWriteControllerOpt() bool
 }
 
+func (opt optClaim[S]) isWriteControllerOpt() bool {
+	return true
+}
+
 func (opt optConcurrency) isWriteControllerOpt() bool {
 	return true
 }
 
+func (opt optContext) isWriteControllerOpt() bool {
+	return true
+}
+
+func (opt optExpectVersion) isWriteControllerOpt() bool {
+	return true
+}
+
+func (opt optVersion[S]) isWriteControllerOpt() bool {
+	return true
+}
+
 // func (opt optSource) isWriteControllerOpt() bool {
 // 	return true
 // }
@@ -113,6 +144,12 @@ func (controller *writeController[I, S]) processMsg() (done bool) {
 		return true
 	}
 
+	if msg.op != opDone && controller.canceled() {
+		// Keep taking messages so that the read controller is not left
+		// blocked, but write none of them.
+		return false
+	}
+
 	switch msg.op {
 	case opDone:
 		close(controller.inCh)
@@ -126,6 +163,14 @@ func (controller *writeController[I, S]) processMsg() (done bool) {
 	return false
 }
 
+func (controller *writeController[I, S]) canceled() bool {
+	return reportCanceled(
+		controller.ctx,
+		&controller.cancelOnce,
+		controller.errCh,
+	)
+}
+
 func (controller *writeController[I, S]) processDeleteMsg(msg specMsg[S]) {
 	var err error
 
@@ -138,7 +183,7 @@ func (controller *writeController[I, S]) processDeleteMsg(msg specMsg[S]) {
 		return
 	}
 
-	if err = controller.stg.Delete(msg.pos); err != nil {
+	if err = controller.stg.DeleteContext(controller.ctx, msg.pos); err != nil {
 		controller.errCh <- err
 		return
 	}
@@ -152,8 +197,17 @@ func (controller *writeController[I, S]) processUpdateMsg(msg specMsg[S]) {
 		data     []byte
 		err      error
 		mutators = make([]Mutator[S], 0, len(controller.mutators)+1)
+		version  int64
 	)
 
+	if controller.versionAccessor != nil {
+		if err = controller.checkVersion(msg.spec); err != nil {
+			controller.errCh <- err
+			return
+		}
+		version = controller.versionAccessor.Get(msg.spec)
+	}
+
 	mutators = append(
 		mutators,
 		NewMutator(controller.updatedAtAccessor, controller.now),
@@ -164,6 +218,18 @@ func (controller *writeController[I, S]) processUpdateMsg(msg specMsg[S]) {
 		mutator.Mutate(msg.spec)
 	}
 
+	// The version is set last so that no mutator can keep it from moving on.
+	if controller.versionAccessor != nil {
+		controller.versionAccessor.Set(msg.spec, version+1)
+	}
+
+	if controller.claim != nil {
+		if err = controller.claim(msg.spec, msg.pos); err != nil {
+			controller.errCh <- err
+			return
+		}
+	}
+
 	if data, err = controller.marshalUnmarshaller.Marshal(msg.spec); err != nil {
 		controller.errCh <- err
 		return
@@ -179,15 +245,32 @@ func (controller *writeController[I, S]) processUpdateMsg(msg specMsg[S]) {
 		return
 	}
 
-	if afterPos, err = controller.stg.Update(msg.pos, data); err != nil {
+	afterPos, err = controller.stg.UpdateContext(controller.ctx, msg.pos, data)
+	if err != nil {
 		controller.errCh <- err
 		return
 	}
 
 	controller.outCh <- specMsg[S]{
-		op:     msg.op,
-		pos:    afterPos,
-		source: msg.source,
-		spec:   msg.spec,
+		fromPos: msg.pos,
+		op:      msg.op,
+		pos:     afterPos,
+		raw:     msg.raw,
+		source:  msg.source,
+		spec:    msg.spec,
+	}
+}
+
+func (controller *writeController[I, S]) checkVersion(s S) (err error) {
+	actual := controller.versionAccessor.Get(s)
+
+	if controller.expectVersion != nil && *controller.expectVersion != actual {
+		return &ErrVersionConflict{
+			Id:       controller.idAccessor.Get(s),
+			Expected: *controller.expectVersion,
+			Actual:   actual,
+		}
 	}
+
+	return nil
 }
@@ -1,6 +1,9 @@
 package obj
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"sync"
 	"time"
 
@@ -11,37 +14,362 @@ import (
 	"golang.org/x/exp/slices"
 )
 
+// Storage keeps records of type S in a line file. Every operation that reads
+// or writes lines has a Context variant; once the context is done no further
+// lines are read or written, the goroutines serving the call stop and a write
+// is rolled back.
 type Storage[S any] interface {
+	Begin() Transaction[S]
 	Delete(filters Matcher[S]) (deleted []S, err error)
+	DeleteContext(
+		ctx context.Context,
+		filters Matcher[S],
+	) (deleted []S, err error)
 	Insert(mutators []Mutator[S]) (inserted S, err error)
+	InsertContext(
+		ctx context.Context,
+		mutators []Mutator[S],
+	) (inserted S, err error)
+	InsertMany(mutators [][]Mutator[S]) (inserted []S, err error)
+	InsertManyContext(
+		ctx context.Context,
+		mutators [][]Mutator[S],
+	) (inserted []S, err error)
+	Iterate(
+		ctx context.Context,
+		filters Matcher[S],
+		fn func(s S) error,
+	) (err error)
 	Select(
 		filters Matcher[S],
 		orderBys []Lesser[S],
 	) (results []S, err error)
+	SelectContext(
+		ctx context.Context,
+		filters Matcher[S],
+		orderBys []Lesser[S],
+	) (results []S, err error)
+	NewAggregateBuilder() AggregateBuilder[S]
 	NewDeleteBuilder() DeleteBuilder[S]
 	NewInsertBuilder() InsertBuilder[S]
 	NewSelectBuilder() SelectBuilder[S]
+	NewUpdateBuilder() UpdateBuilder[S]
+	NewUpsertBuilder() UpsertBuilder[S]
+	Purge(retention time.Duration) (purged int, err error)
+	PurgeContext(
+		ctx context.Context,
+		retention time.Duration,
+	) (purged int, err error)
+	Recover() (recovered int, err error)
+	RecoverContext(ctx context.Context) (recovered int, err error)
+	RunSweeper(ctx context.Context, interval time.Duration) (err error)
+	Sweep() (swept int, err error)
+	SweepContext(ctx context.Context) (swept int, err error)
+	Undelete(filters Matcher[S]) (restored []S, err error)
+	UndeleteContext(
+		ctx context.Context,
+		filters Matcher[S],
+	) (restored []S, err error)
 	Update(
 		filters Matcher[S],
 		mutators []Mutator[S],
 		orderBys []Lesser[S],
 	) (updated []S, err error)
+	UpdateContext(
+		ctx context.Context,
+		filters Matcher[S],
+		mutators []Mutator[S],
+		orderBys []Lesser[S],
+	) (updated []S, err error)
+	Upsert(
+		key Matcher[S],
+		mutators []Mutator[S],
+	) (upserted S, inserted bool, err error)
+	UpsertContext(
+		ctx context.Context,
+		key Matcher[S],
+		mutators []Mutator[S],
+	) (upserted S, inserted bool, err error)
+	Watch(filters Matcher[S]) Subscription[S]
+}
+
+// IdStorage is a Storage that can also fetch records by id. The position of
+// every id is kept in memory so that these lookups, and Equals filters on the
+// id accessor, read only the lines they need.
+type IdStorage[I comparable, S any] interface {
+	Storage[S]
+	Get(id I) (result S, found bool, err error)
+	GetContext(ctx context.Context, id I) (result S, found bool, err error)
+	GetMany(ids []I) (results []S, err error)
+	GetManyContext(ctx context.Context, ids []I) (results []S, err error)
+	UpdateIf(
+		id I,
+		expectedVersion int64,
+		mutators []Mutator[S],
+	) (updated S, found bool, err error)
+	UpdateIfContext(
+		ctx context.Context,
+		id I,
+		expectedVersion int64,
+		mutators []Mutator[S],
+	) (updated S, found bool, err error)
+}
+
+type operator[S any] interface {
+	aggregate(
+		ctx context.Context,
+		filters Matcher[S],
+		grouper Grouper[S],
+		aggregates []Aggregate[S],
+	) (groups []Group, err error)
+	DeleteContext(
+		ctx context.Context,
+		filters Matcher[S],
+	) (deleted []S, err error)
+	deleteChanges(
+		ctx context.Context,
+		filters Matcher[S],
+	) (changes []Change[S], err error)
+	InsertContext(
+		ctx context.Context,
+		mutators []Mutator[S],
+	) (inserted S, err error)
+	InsertManyContext(
+		ctx context.Context,
+		mutators [][]Mutator[S],
+	) (inserted []S, err error)
+	Iterate(
+		ctx context.Context,
+		filters Matcher[S],
+		fn func(s S) error,
+	) (err error)
+	SelectContext(
+		ctx context.Context,
+		filters Matcher[S],
+		orderBys []Lesser[S],
+	) (results []S, err error)
+	update(
+		ctx context.Context,
+		filters Matcher[S],
+		mutators []Mutator[S],
+		orderBys []Lesser[S],
+		opts ...writeControllerOpt,
+	) (updated []S, err error)
+	updateChanges(
+		ctx context.Context,
+		filters Matcher[S],
+		mutators []Mutator[S],
+		orderBys []Lesser[S],
+		opts ...writeControllerOpt,
+	) (changes []Change[S], err error)
+	UpsertContext(
+		ctx context.Context,
+		key Matcher[S],
+		mutators []Mutator[S],
+	) (upserted S, inserted bool, err error)
+	selectPage(
+		ctx context.Context,
+		filters Matcher[S],
+		orderBys []Lesser[S],
+		page page,
+		fields []string,
+	) (results []S, next Cursor, err error)
 }
 
 type storage[I comparable, S any] struct {
 	binLogStg           objbinlog.BinLogStorage
 	bufferLen           int
+	claimLock           sync.Mutex
 	concurrency         int
 	createdAtAccessor   Accessor[S, time.Time]
+	deletedAtAccessor   Accessor[S, time.Time]
+	expiresAtAccessor   Accessor[S, time.Time]
 	factory             SpecFactory[S]
 	idAccessor          Accessor[S, I]
 	idFactory           stg.IdFactory[I]
+	ids                 map[fstln.Position]I
+	indexes             []Index[S]
 	lock                sync.Mutex
 	nower               stg.Nower
 	objType             string
+	positions           map[I]fstln.Position
 	stg                 fstln.Storage
 	marshalUnmarshaller stg.MarshalUnmarshaller[S]
+	recover             bool
 	updatedAtAccessor   Accessor[S, time.Time]
+	versionAccessor     Accessor[S, int64]
+	watchBufferLen      int
+	watchers            map[*subscription[S]]struct{}
+	watchLock           sync.Mutex
+}
+
+func New[I comparable, S any](
+	handle stg.Handle,
+	factory SpecFactory[S],
+	idAccessor Accessor[S, I],
+	createdAtAccessor Accessor[S, time.Time],
+	updatedAtAccessor Accessor[S, time.Time],
+	idFactory stg.IdFactory[I],
+	marshalUnmarshaller stg.MarshalUnmarshaller[S],
+	binLogStg objbinlog.BinLogStorage,
+	opts ...OptStorage,
+) (IdStorage[I, S], error) {
+	return newStorage(
+		handle,
+		factory,
+		idAccessor,
+		createdAtAccessor,
+		updatedAtAccessor,
+		idFactory,
+		marshalUnmarshaller,
+		binLogStg,
+		opts...,
+	)
+}
+
+func newStorage[I comparable, S any](
+	handle stg.Handle,
+	factory SpecFactory[S],
+	idAccessor Accessor[S, I],
+	createdAtAccessor Accessor[S, time.Time],
+	updatedAtAccessor Accessor[S, time.Time],
+	idFactory stg.IdFactory[I],
+	marshalUnmarshaller stg.MarshalUnmarshaller[S],
+	binLogStg objbinlog.BinLogStorage,
+	opts ...OptStorage,
+) (objStg *storage[I, S], err error) {
+	var fstlnStg fstln.Storage
+
+	required := []struct {
+		name    string
+		missing bool
+	}{
+		{"handle", handle == nil},
+		{"spec factory", factory == nil},
+		{"id accessor", idAccessor == nil},
+		{"createdAt accessor", createdAtAccessor == nil},
+		{"updatedAt accessor", updatedAtAccessor == nil},
+		{"id factory", idFactory == nil},
+		{"marshal unmarshaller", marshalUnmarshaller == nil},
+		{"bin log storage", binLogStg == nil},
+	}
+
+	for _, arg := range required {
+		if arg.missing {
+			return nil, fmt.Errorf(
+				"%w, %s is required",
+				illegalArgumentError,
+				arg.name,
+			)
+		}
+	}
+
+	objStg = &storage[I, S]{
+		binLogStg:           binLogStg,
+		bufferLen:           1000,
+		concurrency:         10,
+		createdAtAccessor:   createdAtAccessor,
+		factory:             factory,
+		idAccessor:          idAccessor,
+		idFactory:           idFactory,
+		ids:                 map[fstln.Position]I{},
+		indexes:             make([]Index[S], 0),
+		nower:               stg.NewNower(),
+		objType:             "",
+		positions:           map[I]fstln.Position{},
+		marshalUnmarshaller: marshalUnmarshaller,
+		recover:             true,
+		updatedAtAccessor:   updatedAtAccessor,
+		watchBufferLen:      100,
+		watchers:            map[*subscription[S]]struct{}{},
+	}
+
+	for _, opt := range opts {
+		opt.isStorageOpt()
+		switch opt := opt.(type) {
+		case OptBufferLen:
+			objStg.bufferLen = opt.Value
+		case OptConcurrency:
+			objStg.concurrency = opt.Value
+		case OptDeletedAt[S]:
+			objStg.deletedAtAccessor = opt.Value
+		case OptExpiresAt[S]:
+			objStg.expiresAtAccessor = opt.Value
+		case OptIndex[S]:
+			objStg.indexes = append(objStg.indexes, opt.Value)
+		case OptNower:
+			objStg.nower = opt.Value
+		case OptObjType:
+			objStg.objType = opt.Value
+		case OptRecover:
+			objStg.recover = opt.Value
+		case OptVersion[S]:
+			objStg.versionAccessor = opt.Value
+		case OptWatchBufferLen:
+			objStg.watchBufferLen = opt.Value
+		}
+	}
+
+	if objStg.bufferLen < 1 {
+		return nil, fmt.Errorf(
+			"%w, buffer length must be at least 1 but got %d",
+			illegalArgumentError,
+			objStg.bufferLen,
+		)
+	}
+
+	if objStg.concurrency < 1 {
+		return nil, fmt.Errorf(
+			"%w, concurrency must be at least 1 but got %d",
+			illegalArgumentError,
+			objStg.concurrency,
+		)
+	}
+
+	if objStg.watchBufferLen < 1 {
+		return nil, fmt.Errorf(
+			"%w, watch buffer length must be at least 1 but got %d",
+			illegalArgumentError,
+			objStg.watchBufferLen,
+		)
+	}
+
+	if objStg.nower == nil {
+		return nil, fmt.Errorf("%w, nower is required", illegalArgumentError)
+	}
+
+	indexNames := map[string]bool{}
+	for _, index := range objStg.indexes {
+		if index == nil {
+			return nil, fmt.Errorf("%w, index is required", illegalArgumentError)
+		}
+
+		if indexNames[index.Name()] {
+			return nil, fmt.Errorf(
+				"%w, index %s is declared more than once",
+				illegalArgumentError,
+				index.Name(),
+			)
+		}
+		indexNames[index.Name()] = true
+	}
+
+	if fstlnStg, err = fstln.New(handle); err != nil {
+		return nil, err
+	}
+	objStg.stg = fstlnStg
+
+	if objStg.recover {
+		_, err = objStg.Recover()
+	} else {
+		err = objStg.buildIndexes()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return objStg, nil
 }
 
 type SpecFactory[S any] interface {
@@ -131,6 +459,14 @@ func (matcher *equals[S, T]) Match(s S) bool {
 	return matcher.accessor.Get(s) == matcher.value
 }
 
+func (matcher *equals[S, T]) keyMutator() Mutator[S] {
+	return NewMutator(matcher.accessor, matcher.value)
+}
+
+func (matcher *equals[S, T]) accessorName() string {
+	return matcher.accessor.Name()
+}
+
 func Equals[S any, T comparable](
 	accessor Accessor[S, T],
 	value T,
@@ -168,6 +504,26 @@ func (lesser *orderBy[S, T]) Less(i, j S) int {
 	return result
 }
 
+func (lesser *orderBy[S, T]) accessorName() string {
+	return lesser.accessor.Name()
+}
+
+func (lesser *orderBy[S, T]) cursorKey(s S) any {
+	return lesser.accessor.Get(s)
+}
+
+func (lesser *orderBy[S, T]) setCursorKey(s S, data json.RawMessage) error {
+	var value T
+
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	lesser.accessor.Set(s, value)
+
+	return nil
+}
+
 func OrderBy[S any, T constraints.Ordered](
 	accessor Accessor[S, T],
 ) Lesser[S] {
@@ -204,3 +560,53 @@ type optBufferLen struct {
 type optConcurrency struct {
 	value int
 }
+
+type OptStorage interface {
+	isStorageOpt() bool
+}
+
+type OptBufferLen struct {
+	Value int
+}
+
+func (opt OptBufferLen) isStorageOpt() bool {
+	return true
+}
+
+type OptConcurrency struct {
+	Value int
+}
+
+func (opt OptConcurrency) isStorageOpt() bool {
+	return true
+}
+
+type OptNower struct {
+	Value stg.Nower
+}
+
+func (opt OptNower) isStorageOpt() bool {
+	return true
+}
+
+type OptObjType struct {
+	Value string
+}
+
+func (opt OptObjType) isStorageOpt() bool {
+	return true
+}
+
+type OptRecover struct {
+	Value bool
+}
+
+func (opt OptRecover) isStorageOpt() bool {
+	return true
+}
+
+var illegalArgumentError = fmt.Errorf("illegal argument error")
+
+// ErrIllegalState is wrapped by the errors of operations that find the data
+// file in a state they cannot safely change.
+var ErrIllegalState = fmt.Errorf("illegal state error")
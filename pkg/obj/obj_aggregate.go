@@ -0,0 +1,447 @@
+package obj
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/constraints"
+	"golang.org/x/exp/slices"
+)
+
+type Number interface {
+	constraints.Integer | constraints.Float
+}
+
+// Aggregate computes one value over the records of a group. The values of
+// Count and Sum are an int and a T, those of Avg a float64, and those of Min
+// and Max a T, or nil when the group has no records.
+type Aggregate[S any] interface {
+	accumulator() accumulator[S]
+}
+
+type accumulator[S any] interface {
+	add(s S)
+	merge(other accumulator[S])
+	result() any
+}
+
+// Grouper returns the key of the group a record belongs to.
+type Grouper[S any] interface {
+	Key(s S) any
+}
+
+// Group holds the values of the aggregates for the records sharing Key, in
+// the order the aggregates were given. Key is nil when there is no GroupBy.
+type Group struct {
+	Key    any
+	Values []any
+}
+
+type grouper[S any, K comparable] struct {
+	accessor Accessor[S, K]
+}
+
+func (grouper *grouper[S, K]) Key(s S) any {
+	return grouper.accessor.Get(s)
+}
+
+func NewGrouper[S any, K comparable](accessor Accessor[S, K]) Grouper[S] {
+	return &grouper[S, K]{accessor}
+}
+
+func (stg *storage[I, S]) NewAggregateBuilder() AggregateBuilder[S] {
+	return &aggregateBuilder[S]{filters: Noop[S](), stg: stg}
+}
+
+type AggregateBuilder[S any] interface {
+	Aggregate(aggregates ...Aggregate[S]) AggregateBuilder[S]
+	GroupBy(grouper Grouper[S]) AggregateBuilder[S]
+	Run() (groups []Group, err error)
+	RunContext(ctx context.Context) (groups []Group, err error)
+	Where(filters ...Matcher[S]) AggregateBuilder[S]
+}
+
+type aggregateBuilder[S any] struct {
+	aggregates []Aggregate[S]
+	filters    Matcher[S]
+	grouper    Grouper[S]
+	stg        operator[S]
+}
+
+func (builder *aggregateBuilder[S]) Aggregate(
+	aggregates ...Aggregate[S],
+) AggregateBuilder[S] {
+	builder.aggregates = aggregates
+	return builder
+}
+
+// GroupBy splits the records by the key grouper returns. Groups come back
+// ordered by their key when the keys are numbers, strings or times, and by
+// the string form of their key otherwise.
+func (builder *aggregateBuilder[S]) GroupBy(
+	grouper Grouper[S],
+) AggregateBuilder[S] {
+	builder.grouper = grouper
+	return builder
+}
+
+func (builder *aggregateBuilder[S]) Run() (groups []Group, err error) {
+	return builder.RunContext(context.Background())
+}
+
+func (builder *aggregateBuilder[S]) RunContext(
+	ctx context.Context,
+) (groups []Group, err error) {
+	return builder.stg.aggregate(
+		ctx,
+		builder.filters,
+		builder.grouper,
+		builder.aggregates,
+	)
+}
+
+func (builder *aggregateBuilder[S]) Where(
+	filters ...Matcher[S],
+) AggregateBuilder[S] {
+	builder.filters = And(filters...)
+	return builder
+}
+
+func (stg *storage[I, S]) aggregate(
+	ctx context.Context,
+	filters Matcher[S],
+	grouper Grouper[S],
+	aggregates []Aggregate[S],
+) (groups []Group, err error) {
+	stg.lock.Lock()
+	defer stg.lock.Unlock()
+
+	return stg.aggregateSpecs(ctx, filters, grouper, aggregates)
+}
+
+// aggregateSpecs has every read worker fold the records it reads into a
+// partial result of its own and merges them once the read is done, so no
+// record is kept past its fold.
+func (stg *storage[I, S]) aggregateSpecs(
+	ctx context.Context,
+	filters Matcher[S],
+	grouper Grouper[S],
+	aggregates []Aggregate[S],
+) (groups []Group, err error) {
+	var (
+		ch       = make(chan specMsg[S], stg.concurrency)
+		errCh    = make(chan error, stg.concurrency)
+		lock     sync.Mutex
+		partials []*aggregation[S]
+	)
+
+	newPartial := func() func(s S) {
+		partial := newAggregation(grouper, aggregates)
+
+		lock.Lock()
+		partials = append(partials, partial)
+		lock.Unlock()
+
+		return partial.add
+	}
+
+	controller := stg.newReadController(
+		ctx,
+		ch,
+		errCh,
+		stg.live(filters),
+		opNoop,
+		optAggregate[S]{newPartial},
+	)
+
+	go controller.Start()
+
+	if err = stg.gatherEach(ch, errCh, func(specMsg[S]) {}); err != nil {
+		return nil, err
+	}
+
+	result := newAggregation(grouper, aggregates)
+	for _, partial := range partials {
+		result.merge(partial)
+	}
+
+	return result.groups(), nil
+}
+
+type aggregation[S any] struct {
+	aggregates []Aggregate[S]
+	grouper    Grouper[S]
+	keys       []any
+	values     map[any][]accumulator[S]
+}
+
+func newAggregation[S any](
+	grouper Grouper[S],
+	aggregates []Aggregate[S],
+) *aggregation[S] {
+	aggregation := &aggregation[S]{
+		aggregates: aggregates,
+		grouper:    grouper,
+		values:     map[any][]accumulator[S]{},
+	}
+
+	// Without a grouper there is a single group, even when nothing matches.
+	if grouper == nil {
+		aggregation.group(nil)
+	}
+
+	return aggregation
+}
+
+func (aggregation *aggregation[S]) add(s S) {
+	var key any
+
+	if aggregation.grouper != nil {
+		key = aggregation.grouper.Key(s)
+	}
+
+	for _, accumulator := range aggregation.group(key) {
+		accumulator.add(s)
+	}
+}
+
+func (aggregation *aggregation[S]) group(key any) []accumulator[S] {
+	accumulators, found := aggregation.values[key]
+	if found {
+		return accumulators
+	}
+
+	accumulators = make([]accumulator[S], 0, len(aggregation.aggregates))
+	for _, aggregate := range aggregation.aggregates {
+		accumulators = append(accumulators, aggregate.accumulator())
+	}
+
+	aggregation.keys = append(aggregation.keys, key)
+	aggregation.values[key] = accumulators
+
+	return accumulators
+}
+
+func (aggregation *aggregation[S]) merge(other *aggregation[S]) {
+	for _, key := range other.keys {
+		accumulators := aggregation.group(key)
+		for i, accumulator := range other.values[key] {
+			accumulators[i].merge(accumulator)
+		}
+	}
+}
+
+func (aggregation *aggregation[S]) groups() (groups []Group) {
+	groups = make([]Group, 0, len(aggregation.keys))
+
+	for _, key := range aggregation.keys {
+		values := make([]any, 0, len(aggregation.aggregates))
+		for _, accumulator := range aggregation.values[key] {
+			values = append(values, accumulator.result())
+		}
+
+		groups = append(groups, Group{Key: key, Values: values})
+	}
+
+	slices.SortFunc(groups, func(a, b Group) bool {
+		return compareKeys(a.Key, b.Key) < 0
+	})
+
+	return groups
+}
+
+// compareKeys orders two group keys by value when they are numbers, strings
+// or times of the same type, and by their string form otherwise.
+func compareKeys(a, b any) int {
+	aValue, bValue := reflect.ValueOf(a), reflect.ValueOf(b)
+
+	if !aValue.IsValid() || !bValue.IsValid() ||
+		aValue.Type() != bValue.Type() {
+		return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+	}
+
+	switch aValue.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		return compareOrdered(aValue.Int(), bValue.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		return compareOrdered(aValue.Uint(), bValue.Uint())
+	case reflect.Float32, reflect.Float64:
+		return compareOrdered(aValue.Float(), bValue.Float())
+	case reflect.String:
+		return strings.Compare(aValue.String(), bValue.String())
+	}
+
+	if aTime, ok := a.(time.Time); ok {
+		bTime := b.(time.Time)
+		switch {
+		case aTime.Before(bTime):
+			return -1
+		case aTime.After(bTime):
+			return 1
+		}
+		return 0
+	}
+
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+func compareOrdered[T constraints.Ordered](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+type count[S any] struct{}
+
+func (*count[S]) accumulator() accumulator[S] {
+	return &countAccumulator[S]{}
+}
+
+type countAccumulator[S any] struct {
+	count int
+}
+
+func (accumulator *countAccumulator[S]) add(_ S) {
+	accumulator.count++
+}
+
+func (accumulator *countAccumulator[S]) merge(other accumulator[S]) {
+	accumulator.count += other.(*countAccumulator[S]).count
+}
+
+func (accumulator *countAccumulator[S]) result() any {
+	return accumulator.count
+}
+
+func Count[S any]() Aggregate[S] {
+	return &count[S]{}
+}
+
+type sum[S any, T Number] struct {
+	accessor Accessor[S, T]
+}
+
+func (aggregate *sum[S, T]) accumulator() accumulator[S] {
+	return &sumAccumulator[S, T]{accessor: aggregate.accessor}
+}
+
+type sumAccumulator[S any, T Number] struct {
+	accessor Accessor[S, T]
+	count    int
+	sum      T
+}
+
+func (accumulator *sumAccumulator[S, T]) add(s S) {
+	accumulator.count++
+	accumulator.sum += accumulator.accessor.Get(s)
+}
+
+func (accumulator *sumAccumulator[S, T]) merge(other accumulator[S]) {
+	o := other.(*sumAccumulator[S, T])
+	accumulator.count += o.count
+	accumulator.sum += o.sum
+}
+
+func (accumulator *sumAccumulator[S, T]) result() any {
+	return accumulator.sum
+}
+
+func Sum[S any, T Number](accessor Accessor[S, T]) Aggregate[S] {
+	return &sum[S, T]{accessor}
+}
+
+type avg[S any, T Number] struct {
+	accessor Accessor[S, T]
+}
+
+func (aggregate *avg[S, T]) accumulator() accumulator[S] {
+	return &avgAccumulator[S, T]{
+		sumAccumulator[S, T]{accessor: aggregate.accessor},
+	}
+}
+
+type avgAccumulator[S any, T Number] struct {
+	sumAccumulator[S, T]
+}
+
+func (accumulator *avgAccumulator[S, T]) merge(other accumulator[S]) {
+	accumulator.sumAccumulator.merge(
+		&other.(*avgAccumulator[S, T]).sumAccumulator,
+	)
+}
+
+func (accumulator *avgAccumulator[S, T]) result() any {
+	if accumulator.count == 0 {
+		return nil
+	}
+
+	return float64(accumulator.sum) / float64(accumulator.count)
+}
+
+func Avg[S any, T Number](accessor Accessor[S, T]) Aggregate[S] {
+	return &avg[S, T]{accessor}
+}
+
+type extreme[S any, T constraints.Ordered] struct {
+	accessor Accessor[S, T]
+	max      bool
+}
+
+func (aggregate *extreme[S, T]) accumulator() accumulator[S] {
+	return &extremeAccumulator[S, T]{
+		accessor: aggregate.accessor,
+		max:      aggregate.max,
+	}
+}
+
+type extremeAccumulator[S any, T constraints.Ordered] struct {
+	accessor Accessor[S, T]
+	max      bool
+	set      bool
+	value    T
+}
+
+func (accumulator *extremeAccumulator[S, T]) add(s S) {
+	accumulator.offer(accumulator.accessor.Get(s))
+}
+
+func (accumulator *extremeAccumulator[S, T]) merge(other accumulator[S]) {
+	if o := other.(*extremeAccumulator[S, T]); o.set {
+		accumulator.offer(o.value)
+	}
+}
+
+func (accumulator *extremeAccumulator[S, T]) offer(value T) {
+	if !accumulator.set ||
+		accumulator.max && value > accumulator.value ||
+		!accumulator.max && value < accumulator.value {
+		accumulator.set = true
+		accumulator.value = value
+	}
+}
+
+func (accumulator *extremeAccumulator[S, T]) result() any {
+	if !accumulator.set {
+		return nil
+	}
+
+	return accumulator.value
+}
+
+func Min[S any, T constraints.Ordered](accessor Accessor[S, T]) Aggregate[S] {
+	return &extreme[S, T]{accessor: accessor}
+}
+
+func Max[S any, T constraints.Ordered](accessor Accessor[S, T]) Aggregate[S] {
+	return &extreme[S, T]{accessor: accessor, max: true}
+}
@@ -0,0 +1,154 @@
+package obj
+
+import (
+	"reflect"
+	"testing"
+)
+
+// grouperFunc groups records by a key computed from them.
+type grouperFunc func(s *TestSpec) any
+
+func (key grouperFunc) Key(s *TestSpec) any {
+	return key(s)
+}
+
+func TestAggregate(t *testing.T) {
+	type test struct {
+		name        string
+		filters     Matcher[*TestSpec]
+		grouper     Grouper[*TestSpec]
+		aggregates  []Aggregate[*TestSpec]
+		mockError   *mockErr
+		expectError string
+		expect      []Group
+	}
+
+	tests := []test{
+		{
+			name:       "with count",
+			aggregates: []Aggregate[*TestSpec]{Count[*TestSpec]()},
+			expect:     []Group{{Key: nil, Values: []any{5}}},
+		},
+		{
+			name:       "with count and filter",
+			filters:    BarEquals("bar"),
+			aggregates: []Aggregate[*TestSpec]{Count[*TestSpec]()},
+			expect:     []Group{{Key: nil, Values: []any{2}}},
+		},
+		{
+			name: "with numeric aggregates",
+			aggregates: []Aggregate[*TestSpec]{
+				Sum[*TestSpec, int](IdAccessor),
+				Avg[*TestSpec, int](IdAccessor),
+				Min[*TestSpec, int](IdAccessor),
+				Max[*TestSpec, int](IdAccessor),
+			},
+			expect: []Group{{Key: nil, Values: []any{15, 3.0, 1, 5}}},
+		},
+		{
+			name:    "without matching records",
+			filters: FooEquals("none"),
+			aggregates: []Aggregate[*TestSpec]{
+				Count[*TestSpec](),
+				Sum[*TestSpec, int](IdAccessor),
+				Avg[*TestSpec, int](IdAccessor),
+				Min[*TestSpec, int](IdAccessor),
+				Max[*TestSpec, int](IdAccessor),
+			},
+			expect: []Group{{Key: nil, Values: []any{0, 0, nil, nil, nil}}},
+		},
+		{
+			name:    "with group by",
+			grouper: NewGrouper[*TestSpec, string](BarAccessor),
+			aggregates: []Aggregate[*TestSpec]{
+				Count[*TestSpec](),
+				Sum[*TestSpec, int](IdAccessor),
+				Min[*TestSpec, string](FooAccessor),
+				Max[*TestSpec, string](FooAccessor),
+			},
+			expect: []Group{
+				{Key: "bar", Values: []any{2, 4, "buz", "foo"}},
+				{Key: "buz", Values: []any{2, 7, "bar", "fiz"}},
+				{Key: "foo", Values: []any{1, 4, "baz", "baz"}},
+			},
+		},
+		{
+			name:       "with numeric group keys",
+			grouper:    grouperFunc(func(s *TestSpec) any { return s.Id * 5 }),
+			aggregates: []Aggregate[*TestSpec]{Count[*TestSpec]()},
+			expect: []Group{
+				{Key: 5, Values: []any{1}},
+				{Key: 10, Values: []any{1}},
+				{Key: 15, Values: []any{1}},
+				{Key: 20, Values: []any{1}},
+				{Key: 25, Values: []any{1}},
+			},
+		},
+		{
+			name:       "with group by without matching records",
+			filters:    FooEquals("none"),
+			grouper:    NewGrouper[*TestSpec, string](BarAccessor),
+			aggregates: []Aggregate[*TestSpec]{Count[*TestSpec]()},
+			expect:     []Group{},
+		},
+		{
+			name:       "with read error",
+			aggregates: []Aggregate[*TestSpec]{Count[*TestSpec]()},
+			mockError: &mockErr{
+				mockErrType: mockErrTypeRead,
+				errorOn:     2,
+				msg:         "with read error",
+			},
+			expectError: "with read error",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+
+			util := &testUtil{
+				test: t,
+				lines: []string{
+					`{"id":1,"foo":"foo","bar":"bar"}`,
+					`{"id":2,"foo":"fiz","bar":"buz"}`,
+					`{"id":3,"foo":"buz","bar":"bar"}`,
+					`{"id":4,"foo":"baz","bar":"foo"}`,
+					`{"id":5,"foo":"bar","bar":"buz"}`,
+				},
+				mockError:   tc.mockError,
+				expectError: tc.expectError,
+			}
+
+			err = util.setup()
+			defer util.teardown()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			builder := util.stg.NewAggregateBuilder().
+				Aggregate(tc.aggregates...)
+			if tc.filters != nil {
+				builder.Where(tc.filters)
+			}
+			if tc.grouper != nil {
+				builder.GroupBy(tc.grouper)
+			}
+
+			got, err := builder.Run()
+
+			if done := util.handleExpectError(err); done {
+				return
+			}
+
+			if !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf(
+					"expected aggregate groups to be \n%v\n but got \n%v\n",
+					tc.expect,
+					got,
+				)
+			}
+		})
+	}
+}
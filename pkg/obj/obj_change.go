@@ -0,0 +1,57 @@
+package obj
+
+import "golang.org/x/exp/slices"
+
+// Change holds the image a record had before a write and the one it has
+// after it. After is the zero value for deleted records.
+type Change[S any] struct {
+	Before S
+	After  S
+}
+
+// changes pairs the image each message was read with to the one it was
+// written with. Updates are ordered by their after images and deletes by
+// their before images.
+func (stg *storage[I, S]) changes(
+	msgs []specMsg[S],
+	op op,
+	orderBys ...Lesser[S],
+) (changes []Change[S], err error) {
+	changes = make([]Change[S], 0, len(msgs))
+
+	for _, msg := range msgs {
+		if op == opDelete {
+			changes = append(changes, Change[S]{Before: msg.spec})
+			continue
+		}
+
+		before := stg.factory.New()
+		if err = stg.marshalUnmarshaller.Unmarshal(msg.raw, before); err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, Change[S]{Before: before, After: msg.spec})
+	}
+
+	if len(orderBys) == 0 {
+		return changes, nil
+	}
+
+	image := func(change Change[S]) S {
+		if op == opDelete {
+			return change.Before
+		}
+		return change.After
+	}
+
+	slices.SortFunc(changes, func(a, b Change[S]) bool {
+		for _, lesser := range orderBys {
+			if res := lesser.Less(image(a), image(b)); res != 0 {
+				return res < 0
+			}
+		}
+		return false
+	})
+
+	return changes, nil
+}
@@ -0,0 +1,253 @@
+package obj
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestChanges(t *testing.T) {
+	type test struct {
+		name        string
+		run         func(stg Storage[*TestSpec]) ([]Change[*TestSpec], error)
+		mockErr     *mockErr
+		expectError string
+		expect      []Change[*TestSpec]
+		expectAfter []*TestSpec
+	}
+
+	var (
+		foo = &TestSpec{Id: 1, Foo: "foo", Bar: "bar"}
+		fiz = &TestSpec{Id: 2, Foo: "fiz", Bar: "bar"}
+		fam = &TestSpec{Id: 3, Foo: "fam", Bar: "baz"}
+	)
+
+	tests := []test{
+		{
+			name: "with update",
+			run: func(stg Storage[*TestSpec]) ([]Change[*TestSpec], error) {
+				return stg.NewUpdateBuilder().
+					Where(BarEquals("bar")).
+					Set(MutateFoo("FOO")).
+					OrderBy(OrderById).
+					RunChanges()
+			},
+			expect: []Change[*TestSpec]{
+				{
+					Before: foo,
+					After: &TestSpec{
+						Id:        1,
+						Foo:       "FOO",
+						Bar:       "bar",
+						UpdatedAt: GetTestNow(),
+					},
+				},
+				{
+					Before: fiz,
+					After: &TestSpec{
+						Id:        2,
+						Foo:       "FOO",
+						Bar:       "bar",
+						UpdatedAt: GetTestNow(),
+					},
+				},
+			},
+			expectAfter: []*TestSpec{
+				{Id: 1, Foo: "FOO", Bar: "bar", UpdatedAt: GetTestNow()},
+				{Id: 2, Foo: "FOO", Bar: "bar", UpdatedAt: GetTestNow()},
+				fam,
+			},
+		},
+		{
+			name: "with update ordered by the after image",
+			run: func(stg Storage[*TestSpec]) ([]Change[*TestSpec], error) {
+				return stg.NewUpdateBuilder().
+					Where(Noop[*TestSpec]()).
+					Set(MutateBar("BAR")).
+					OrderBy(OrderByFoo).
+					RunChanges()
+			},
+			expect: []Change[*TestSpec]{
+				{
+					Before: fam,
+					After: &TestSpec{
+						Id:        3,
+						Foo:       "fam",
+						Bar:       "BAR",
+						UpdatedAt: GetTestNow(),
+					},
+				},
+				{
+					Before: fiz,
+					After: &TestSpec{
+						Id:        2,
+						Foo:       "fiz",
+						Bar:       "BAR",
+						UpdatedAt: GetTestNow(),
+					},
+				},
+				{
+					Before: foo,
+					After: &TestSpec{
+						Id:        1,
+						Foo:       "foo",
+						Bar:       "BAR",
+						UpdatedAt: GetTestNow(),
+					},
+				},
+			},
+			expectAfter: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "BAR", UpdatedAt: GetTestNow()},
+				{Id: 2, Foo: "fiz", Bar: "BAR", UpdatedAt: GetTestNow()},
+				{Id: 3, Foo: "fam", Bar: "BAR", UpdatedAt: GetTestNow()},
+			},
+		},
+		{
+			name: "with update in a transaction",
+			run: func(stg Storage[*TestSpec]) (
+				changes []Change[*TestSpec],
+				err error,
+			) {
+				tx := stg.Begin()
+				if changes, err = tx.NewUpdateBuilder().
+					Where(FooEquals("fam")).
+					Set(MutateBar("BAZ")).
+					RunChanges(); err != nil {
+					_ = tx.Rollback()
+					return nil, err
+				}
+				return changes, tx.Commit()
+			},
+			expect: []Change[*TestSpec]{
+				{
+					Before: fam,
+					After: &TestSpec{
+						Id:        3,
+						Foo:       "fam",
+						Bar:       "BAZ",
+						UpdatedAt: GetTestNow(),
+					},
+				},
+			},
+			expectAfter: []*TestSpec{
+				foo,
+				fiz,
+				{Id: 3, Foo: "fam", Bar: "BAZ", UpdatedAt: GetTestNow()},
+			},
+		},
+		{
+			name: "with update error",
+			run: func(stg Storage[*TestSpec]) ([]Change[*TestSpec], error) {
+				return stg.NewUpdateBuilder().
+					Where(BarEquals("bar")).
+					Set(MutateFoo("FOO")).
+					RunChanges()
+			},
+			mockErr: &mockErr{
+				mockErrType: mockErrTypeUpdate,
+				errorOn:     0,
+				msg:         "with update error",
+			},
+			expectError: "with update error",
+			expectAfter: []*TestSpec{foo, fiz, fam},
+		},
+		{
+			name: "with delete",
+			run: func(stg Storage[*TestSpec]) ([]Change[*TestSpec], error) {
+				return stg.NewDeleteBuilder().
+					Where(BarEquals("bar")).
+					RunChanges()
+			},
+			expect: []Change[*TestSpec]{
+				{Before: foo},
+				{Before: fiz},
+			},
+			expectAfter: []*TestSpec{fam},
+		},
+		{
+			name: "with delete in a transaction",
+			run: func(stg Storage[*TestSpec]) (
+				changes []Change[*TestSpec],
+				err error,
+			) {
+				tx := stg.Begin()
+				if changes, err = tx.NewDeleteBuilder().
+					Where(FooEquals("fiz")).
+					RunChanges(); err != nil {
+					_ = tx.Rollback()
+					return nil, err
+				}
+				return changes, tx.Commit()
+			},
+			expect: []Change[*TestSpec]{
+				{Before: fiz},
+			},
+			expectAfter: []*TestSpec{foo, fam},
+		},
+		{
+			name: "with delete error",
+			run: func(stg Storage[*TestSpec]) ([]Change[*TestSpec], error) {
+				return stg.NewDeleteBuilder().
+					Where(BarEquals("bar")).
+					RunChanges()
+			},
+			mockErr: &mockErr{
+				mockErrType: mockErrTypeDelete,
+				errorOn:     0,
+				msg:         "with delete error",
+			},
+			expectError: "with delete error",
+			expectAfter: []*TestSpec{foo, fiz, fam},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				err error
+				got []Change[*TestSpec]
+			)
+
+			util := &testUtil{
+				test: t,
+				lines: []string{
+					`{"id":1,"foo":"foo","bar":"bar"}`,
+					`{"id":2,"foo":"fiz","bar":"bar"}`,
+					`{"id":3,"foo":"fam","bar":"baz"}`,
+				},
+				mockError:   tc.mockErr,
+				expectError: tc.expectError,
+				filters:     Noop[*TestSpec](),
+				orderBys:    []Lesser[*TestSpec]{OrderById},
+				expect:      tc.expectAfter,
+			}
+
+			err = util.setup()
+			defer util.teardown()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err = tc.run(util.stg)
+
+			if done := util.handleExpectError(err); !done {
+				// Deletes come back in the order they were read.
+				if len(got) > 0 && got[0].After == nil {
+					slices.SortFunc(got, func(a, b Change[*TestSpec]) bool {
+						return a.Before.Id < b.Before.Id
+					})
+				}
+
+				if !reflect.DeepEqual(got, tc.expect) {
+					t.Errorf("expected changes\n%v\nbut got\n%v", tc.expect, got)
+				}
+			}
+
+			util.expectError = ""
+			util.mockError = nil
+			util.expectSelect()
+		})
+	}
+}
@@ -1,17 +1,34 @@
 package obj
 
 import (
+	"context"
+	"fmt"
 	"time"
 
+	"github.com/yo3jones/stg/pkg/fstln"
 	"github.com/yo3jones/stg/pkg/objbinlog"
 )
 
 func (stg *storage[I, S]) newReadController(
+	ctx context.Context,
 	ch chan specMsg[S],
 	errCh chan error,
 	filters Matcher[S],
 	op op,
+	extraOpts ...readControllerOpt,
 ) *readController[S] {
+	opts := []readControllerOpt{
+		optBufferLen{stg.bufferLen},
+		optConcurrency{stg.concurrency},
+		optContext{ctx},
+		optOp{op},
+	}
+	opts = append(opts, extraOpts...)
+
+	if positions, ok := stg.plan(filters); ok {
+		opts = append(opts, optPositions{positions})
+	}
+
 	return newReadController(
 		ch,
 		errCh,
@@ -19,20 +36,71 @@ func (stg *storage[I, S]) newReadController(
 		filters,
 		stg.stg,
 		stg.marshalUnmarshaller,
-		optBufferLen{stg.bufferLen},
-		optConcurrency{stg.concurrency},
-		optOp{op},
+		opts...,
 	)
 }
 
+// unfiltered marks filters that match every record, tombstones and expired
+// records included. It is unwrapped by live.
+type unfiltered[S any] struct {
+	matcher Matcher[S]
+}
+
+func (matcher *unfiltered[S]) Match(s S) bool {
+	return matcher.matcher.Match(s)
+}
+
+// live narrows filters to the records that reads and writes see: neither the
+// tombstones of soft deletes, unless the filters are marked withDeleted, nor
+// expired records, unless they are marked unfiltered.
+func (stg *storage[I, S]) live(filters Matcher[S]) Matcher[S] {
+	var hidden []Matcher[S]
+
+	switch marked := filters.(type) {
+	case *unfiltered[S]:
+		return marked.matcher
+	case *withDeleted[S]:
+		filters = marked.matcher
+	default:
+		if stg.deletedAtAccessor != nil {
+			hidden = append(hidden, IsZero(stg.deletedAtAccessor))
+		}
+	}
+
+	if stg.expiresAtAccessor != nil {
+		hidden = append(hidden, stg.unexpired())
+	}
+
+	if len(hidden) == 0 {
+		return filters
+	}
+
+	if filters == nil {
+		filters = Noop[S]()
+	}
+
+	return And(append([]Matcher[S]{filters}, hidden...)...)
+}
+
 func (stg *storage[I, S]) newWriteController(
+	ctx context.Context,
 	inCh chan specMsg[S],
 	outCh chan specMsg[S],
 	errCh chan error,
 	binLogTrans objbinlog.Transaction,
 	mutators []Mutator[S],
 	now time.Time,
+	claims *claims[I, S],
+	extraOpts ...writeControllerOpt,
 ) *writeController[I, S] {
+	opts := []writeControllerOpt{
+		optClaim[S]{claims.claim},
+		optConcurrency{stg.concurrency},
+		optContext{ctx},
+		optVersion[S]{stg.versionAccessor},
+	}
+	opts = append(opts, extraOpts...)
+
 	return newWriteController(
 		inCh,
 		outCh,
@@ -44,45 +112,130 @@ func (stg *storage[I, S]) newWriteController(
 		stg.idAccessor,
 		stg.updatedAtAccessor,
 		now,
-		optConcurrency{stg.concurrency},
+		opts...,
 	)
 }
 
 func (stg *storage[I, S]) runReadWrite(
+	ctx context.Context,
+	tx *transaction[I, S],
 	op op,
 	filters Matcher[S],
 	mutators []Mutator[S],
-	orderBys ...Lesser[S],
+	orderBys []Lesser[S],
+	opts ...writeControllerOpt,
 ) (result []S, err error) {
+	var msgs []specMsg[S]
+
+	msgs, err = stg.readWrite(ctx, tx, op, filters, mutators, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return stg.specs(msgs, orderBys...), nil
+}
+
+func (stg *storage[I, S]) runReadWriteChanges(
+	ctx context.Context,
+	tx *transaction[I, S],
+	op op,
+	filters Matcher[S],
+	mutators []Mutator[S],
+	orderBys []Lesser[S],
+	opts ...writeControllerOpt,
+) (changes []Change[S], err error) {
+	var msgs []specMsg[S]
+
+	msgs, err = stg.readWrite(ctx, tx, op, filters, mutators, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return stg.changes(msgs, op, orderBys...)
+}
+
+func (stg *storage[I, S]) readWrite(
+	ctx context.Context,
+	tx *transaction[I, S],
+	op op,
+	filters Matcher[S],
+	mutators []Mutator[S],
+	opts ...writeControllerOpt,
+) (msgs []specMsg[S], err error) {
 	var (
-		binLogTrans objbinlog.Transaction
-		inCh        = make(chan specMsg[S], stg.concurrency)
-		outCh       = make(chan specMsg[S], stg.concurrency)
-		errCh       = make(chan error, stg.concurrency)
-		now         = stg.nower.Now()
+		claims  = &claims[I, S]{stg: stg}
+		errCh   = make(chan error, stg.concurrency)
+		inCh    = make(chan specMsg[S], stg.concurrency)
+		now     = stg.nower.Now()
+		outCh   = make(chan specMsg[S], stg.concurrency)
+		written = map[fstln.Position]struct{}{}
 	)
 
-	binLogTrans = stg.binLogStg.StartTransaction(stg.objType)
-	defer binLogTrans.End()
+	if err = stg.checkVersionSupported(opts); err != nil {
+		return nil, err
+	}
 
-	readController := stg.newReadController(inCh, errCh, filters, op)
+	readController := stg.newReadController(
+		ctx,
+		inCh,
+		errCh,
+		stg.live(filters),
+		op,
+	)
 	writeController := stg.newWriteController(
+		ctx,
 		inCh,
 		outCh,
 		errCh,
-		binLogTrans,
+		tx.binLogTrans,
 		mutators,
 		now,
+		claims,
+		opts...,
 	)
 
 	go readController.Start()
 	go writeController.Start()
 
-	if result, err = stg.gatherResults(outCh, errCh, orderBys...); err != nil {
+	msgs, err = stg.gatherMsgs(outCh, errCh)
+
+	for _, msg := range msgs {
+		tx.record(stg.idAccessor.Get(msg.spec), msg.raw, msg.pos, op != opDelete)
+
+		if op == opDelete {
+			stg.unindex(msg.spec, msg.pos)
+		} else {
+			stg.unindex(msg.spec, msg.fromPos)
+			written[msg.fromPos] = struct{}{}
+		}
+	}
+
+	// Every old position is dropped before the new ones are added since a
+	// record may have moved into a line another one left behind.
+	for _, msg := range msgs {
+		if op != opDelete {
+			stg.index(msg.spec, msg.pos)
+		}
+	}
+
+	if err == nil {
+		err = tx.queueChanges(msgs, op)
+	}
+
+	if err != nil {
+		// The unique indexes may still hold values claimed for records that
+		// were never written.
+		if releaseErr := claims.release(written); releaseErr != nil {
+			return nil, fmt.Errorf(
+				"%w, and releasing its unique claims failed: %v",
+				err,
+				releaseErr,
+			)
+		}
 		return nil, err
 	}
 
-	return result, nil
+	return msgs, nil
 }
 
 func (stg *storage[I, S]) gatherResults(
@@ -90,40 +243,77 @@ func (stg *storage[I, S]) gatherResults(
 	errCh chan error,
 	orderBys ...Lesser[S],
 ) (results []S, err error) {
-	var (
-		done bool
-		s    S
-	)
-	results = make([]S, 0, 100)
+	var msgs []specMsg[S]
 
-	for {
-		if s, done, err = stg.gatherResult(ch, errCh); err != nil {
-			return nil, err
-		} else if done {
-			break
-		} else {
-			results = append(results, s)
-		}
+	if msgs, err = stg.gatherMsgs(ch, errCh); err != nil {
+		return nil, err
 	}
 
-	if len(orderBys) > 0 {
-		Sort(results, orderBys...)
-	}
+	return stg.specs(msgs, orderBys...), nil
+}
+
+// gatherMsgs collects messages until the controllers report they are done.
+// It keeps draining after an error so that no controller is left blocked on
+// a send and every applied write is returned for the caller to account for.
+func (stg *storage[I, S]) gatherMsgs(
+	ch chan specMsg[S],
+	errCh chan error,
+) (msgs []specMsg[S], err error) {
+	msgs = make([]specMsg[S], 0, 100)
+
+	err = stg.gatherEach(ch, errCh, func(msg specMsg[S]) {
+		msgs = append(msgs, msg)
+	})
 
-	return results, nil
+	return msgs, err
 }
 
-func (*storage[I, S]) gatherResult(
+// gatherEach hands every message to fn until the controllers report they are
+// done, draining like gatherMsgs.
+func (*storage[I, S]) gatherEach(
 	ch chan specMsg[S],
 	errCh chan error,
-) (s S, done bool, err error) {
-	select {
-	case msg := <-ch:
-		if msg.op == opDone {
-			return s, true, nil
+	fn func(msg specMsg[S]),
+) (err error) {
+	for {
+		select {
+		case msg := <-ch:
+			if msg.op != opDone {
+				fn(msg)
+				continue
+			}
+
+			for {
+				select {
+				case e := <-errCh:
+					if err == nil {
+						err = e
+					}
+				default:
+					return err
+				}
+			}
+		case e := <-errCh:
+			if err == nil {
+				err = e
+			}
 		}
-		return msg.spec, false, nil
-	case err := <-errCh:
-		return s, false, err
 	}
 }
+
+func (*storage[I, S]) specs(
+	msgs []specMsg[S],
+	orderBys ...Lesser[S],
+) (results []S) {
+	results = make([]S, 0, len(msgs))
+
+	for _, msg := range msgs {
+		results = append(results, msg.spec)
+	}
+
+	if len(orderBys) > 0 {
+		Sort(results, orderBys...)
+	}
+
+	return results
+}
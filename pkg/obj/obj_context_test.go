@@ -0,0 +1,214 @@
+package obj
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestContext(t *testing.T) {
+	type test struct {
+		name        string
+		cancelAfter int
+		run         func(ctx context.Context, stg *storage[int, *TestSpec]) error
+	}
+
+	tests := []test{
+		{
+			name: "with select",
+			run: func(ctx context.Context, stg *storage[int, *TestSpec]) error {
+				_, err := stg.SelectContext(ctx, Noop[*TestSpec](), nil)
+				return err
+			},
+		},
+		{
+			name:        "with select canceled while reading",
+			cancelAfter: 3,
+			run: func(ctx context.Context, stg *storage[int, *TestSpec]) error {
+				_, err := stg.NewSelectBuilder().
+					Where(Noop[*TestSpec]()).
+					RunContext(ctx)
+				return err
+			},
+		},
+		{
+			name:        "with page canceled while reading",
+			cancelAfter: 3,
+			run: func(ctx context.Context, stg *storage[int, *TestSpec]) error {
+				_, _, err := stg.NewSelectBuilder().
+					Where(Noop[*TestSpec]()).
+					OrderBy(OrderById).
+					Limit(2).
+					PageContext(ctx)
+				return err
+			},
+		},
+		{
+			name:        "with get canceled while reading",
+			cancelAfter: 1,
+			run: func(ctx context.Context, stg *storage[int, *TestSpec]) error {
+				_, err := stg.GetManyContext(ctx, []int{1, 2, 3})
+				return err
+			},
+		},
+		{
+			name:        "with update canceled while writing",
+			cancelAfter: 4,
+			run: func(ctx context.Context, stg *storage[int, *TestSpec]) error {
+				_, err := stg.NewUpdateBuilder().
+					Where(Noop[*TestSpec]()).
+					Set(MutateFoo("a much longer foo")).
+					RunContext(ctx)
+				return err
+			},
+		},
+		{
+			name:        "with delete canceled while writing",
+			cancelAfter: 4,
+			run: func(ctx context.Context, stg *storage[int, *TestSpec]) error {
+				_, err := stg.NewDeleteBuilder().
+					Where(Noop[*TestSpec]()).
+					RunContext(ctx)
+				return err
+			},
+		},
+		{
+			name: "with insert",
+			run: func(ctx context.Context, stg *storage[int, *TestSpec]) error {
+				_, err := stg.NewInsertBuilder().
+					Set(MutateFoo("new")).
+					RunContext(ctx)
+				return err
+			},
+		},
+		{
+			name:        "with transaction update canceled while writing",
+			cancelAfter: 4,
+			run: func(
+				ctx context.Context,
+				stg *storage[int, *TestSpec],
+			) (err error) {
+				tx := stg.Begin()
+				defer tx.Rollback()
+
+				_, err = tx.UpdateContext(
+					ctx,
+					Noop[*TestSpec](),
+					[]Mutator[*TestSpec]{MutateBar("bar")},
+					nil,
+				)
+				return err
+			},
+		},
+		{
+			name:        "with iterate canceled while reading",
+			cancelAfter: 2,
+			run: func(ctx context.Context, stg *storage[int, *TestSpec]) error {
+				return stg.Iterate(
+					ctx,
+					Noop[*TestSpec](),
+					func(s *TestSpec) error { return nil },
+				)
+			},
+		},
+		{
+			name: "with recover",
+			run: func(ctx context.Context, stg *storage[int, *TestSpec]) error {
+				_, err := stg.RecoverContext(ctx)
+				return err
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+
+			util := &testUtil{
+				test: t,
+				lines: []string{
+					`{"id":1,"foo":"foo","bar":"bar"}`,
+					`{"id":2,"foo":"fiz","bar":"buz"}`,
+					`{"id":3,"foo":"buz","bar":"baz"}`,
+					`{"id":4,"foo":"baz","bar":"foo"}`,
+					`{"id":5,"foo":"bar","bar":"fiz"}`,
+				},
+				filters:  Noop[*TestSpec](),
+				orderBys: []Lesser[*TestSpec]{OrderById},
+				expect: []*TestSpec{
+					{Id: 1, Foo: "foo", Bar: "bar"},
+					{Id: 2, Foo: "fiz", Bar: "buz"},
+					{Id: 3, Foo: "buz", Bar: "baz"},
+					{Id: 4, Foo: "baz", Bar: "foo"},
+					{Id: 5, Foo: "bar", Bar: "fiz"},
+				},
+			}
+
+			err = util.setup()
+			defer util.teardown()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			goroutines := runtime.NumGoroutine()
+
+			ctx := newCancelAfterContext(tc.cancelAfter)
+			defer ctx.cancel()
+
+			if err = tc.run(ctx, util.stg); err != context.Canceled {
+				t.Errorf(
+					"expected error to be %v but got %v",
+					context.Canceled,
+					err,
+				)
+			}
+
+			expectGoroutines(t, goroutines)
+
+			util.expectSelect()
+		})
+	}
+}
+
+// cancelAfterContext is canceled by the call to Err after the first n.
+type cancelAfterContext struct {
+	context.Context
+	cancel context.CancelFunc
+	lock   sync.Mutex
+	n      int
+}
+
+func newCancelAfterContext(n int) *cancelAfterContext {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &cancelAfterContext{Context: ctx, cancel: cancel, n: n}
+}
+
+func (ctx *cancelAfterContext) Err() error {
+	ctx.lock.Lock()
+	defer ctx.lock.Unlock()
+
+	if ctx.n <= 0 {
+		ctx.cancel()
+	}
+	ctx.n--
+
+	return ctx.Context.Err()
+}
+
+// expectGoroutines fails the test when the goroutines started by a call are
+// still around shortly after it returned.
+func expectGoroutines(t *testing.T, expect int) {
+	var got int
+
+	for i := 0; i < 100; i++ {
+		if got = runtime.NumGoroutine(); got <= expect {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Errorf("expected at most %d goroutines but got %d", expect, got)
+}
@@ -1,11 +1,35 @@
 package obj
 
+import "context"
+
 func (stg *storage[I, S]) Delete(
 	filters Matcher[S],
 ) (deleted []S, err error) {
-	stg.lock.Lock()
-	defer stg.lock.Unlock()
-	return stg.runReadWrite(opDelete, filters, []Mutator[S]{})
+	return stg.DeleteContext(context.Background(), filters)
+}
+
+func (stg *storage[I, S]) DeleteContext(
+	ctx context.Context,
+	filters Matcher[S],
+) (deleted []S, err error) {
+	tx := stg.begin()
+	defer func() { err = tx.end(err) }()
+
+	op, mutators := stg.deleteOp()
+
+	return stg.runReadWrite(ctx, tx, op, filters, mutators, nil)
+}
+
+func (stg *storage[I, S]) deleteChanges(
+	ctx context.Context,
+	filters Matcher[S],
+) (changes []Change[S], err error) {
+	tx := stg.begin()
+	defer func() { err = tx.end(err) }()
+
+	op, mutators := stg.deleteOp()
+
+	return stg.runReadWriteChanges(ctx, tx, op, filters, mutators, nil)
 }
 
 func (stg *storage[I, S]) NewDeleteBuilder() DeleteBuilder[S] {
@@ -17,10 +41,13 @@ func (stg *storage[I, S]) NewDeleteBuilder() DeleteBuilder[S] {
 type DeleteBuilder[S any] interface {
 	Where(filters ...Matcher[S]) DeleteBuilder[S]
 	Run() (deleted []S, err error)
+	RunContext(ctx context.Context) (deleted []S, err error)
+	RunChanges() (changes []Change[S], err error)
+	RunChangesContext(ctx context.Context) (changes []Change[S], err error)
 }
 
 type deleteBuilder[S any] struct {
-	stg     Storage[S]
+	stg     operator[S]
 	filters Matcher[S]
 }
 
@@ -30,5 +57,23 @@ func (builder *deleteBuilder[S]) Where(filters ...Matcher[S]) DeleteBuilder[S] {
 }
 
 func (builder *deleteBuilder[S]) Run() (deleted []S, err error) {
-	return builder.stg.Delete(builder.filters)
+	return builder.RunContext(context.Background())
+}
+
+func (builder *deleteBuilder[S]) RunContext(
+	ctx context.Context,
+) (deleted []S, err error) {
+	return builder.stg.DeleteContext(ctx, builder.filters)
+}
+
+// RunChanges deletes like Run but returns the image each record had before
+// it was deleted as the Before of its change.
+func (builder *deleteBuilder[S]) RunChanges() (changes []Change[S], err error) {
+	return builder.RunChangesContext(context.Background())
+}
+
+func (builder *deleteBuilder[S]) RunChangesContext(
+	ctx context.Context,
+) (changes []Change[S], err error) {
+	return builder.stg.deleteChanges(ctx, builder.filters)
 }
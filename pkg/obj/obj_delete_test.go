@@ -36,12 +36,16 @@ func TestDelete(t *testing.T) {
 			},
 			expectBinLog: [][]string{
 				{
+					`{"transaction":200,"type":"test","op":"begin","ts":"2022-07-06T16:18:00-04:00"}`,
 					`{"transaction":200,"type":"test","id":1,"ts":"2022-07-06T16:18:00-04:00","from":{"id":1,"foo":"foo","bar":"bar"},"to":null}`,
 					`{"transaction":200,"type":"test","id":2,"ts":"2022-07-06T16:18:00-04:00","from":{"id":2,"foo":"fiz","bar":"bar"},"to":null}`,
+					`{"transaction":200,"type":"test","op":"commit","ts":"2022-07-06T16:18:00-04:00"}`,
 				},
 				{
+					`{"transaction":200,"type":"test","op":"begin","ts":"2022-07-06T16:18:00-04:00"}`,
 					`{"transaction":200,"type":"test","id":2,"ts":"2022-07-06T16:18:00-04:00","from":{"id":2,"foo":"fiz","bar":"bar"},"to":null}`,
 					`{"transaction":200,"type":"test","id":1,"ts":"2022-07-06T16:18:00-04:00","from":{"id":1,"foo":"foo","bar":"bar"},"to":null}`,
+					`{"transaction":200,"type":"test","op":"commit","ts":"2022-07-06T16:18:00-04:00"}`,
 				},
 			},
 		},
@@ -0,0 +1,117 @@
+package obj
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// OptExpiresAt gives the records a time to live. A record whose expiresAt is
+// set and no longer after the storage's Nower is expired: from then on every
+// select, get, aggregate, update and delete skips it, even before it is
+// removed. Expired records are removed from the data file by Sweep, or by
+// RunSweeper in the background, and their deletes are written to the bin log
+// like any other. Until then they keep their values in the indexes, so a
+// unique index still counts them.
+type OptExpiresAt[S any] struct {
+	Value Accessor[S, time.Time]
+}
+
+func (opt OptExpiresAt[S]) isStorageOpt() bool {
+	return true
+}
+
+// unexpired matches the records that have no expiresAt or whose expiresAt is
+// still ahead.
+func (stg *storage[I, S]) unexpired() Matcher[S] {
+	return Or(
+		IsZero(stg.expiresAtAccessor),
+		After(stg.expiresAtAccessor, stg.nower.Now()),
+	)
+}
+
+func (stg *storage[I, S]) isExpired(s S) bool {
+	return stg.expiresAtAccessor != nil && !stg.unexpired().Match(s)
+}
+
+func (stg *storage[I, S]) checkExpiresAt() (err error) {
+	if stg.expiresAtAccessor == nil {
+		return fmt.Errorf(
+			"%w, an expiresAt accessor is required to sweep records",
+			illegalArgumentError,
+		)
+	}
+
+	return nil
+}
+
+// Sweep removes the records that have expired and returns how many it
+// removed.
+func (stg *storage[I, S]) Sweep() (swept int, err error) {
+	return stg.SweepContext(context.Background())
+}
+
+func (stg *storage[I, S]) SweepContext(
+	ctx context.Context,
+) (swept int, err error) {
+	var results []S
+
+	if err = stg.checkExpiresAt(); err != nil {
+		return 0, err
+	}
+
+	tx := stg.begin()
+	defer func() { err = tx.end(err) }()
+
+	results, err = stg.runReadWrite(
+		ctx,
+		tx,
+		opDelete,
+		&unfiltered[S]{And(
+			Not(IsZero(stg.expiresAtAccessor)),
+			Not(After(stg.expiresAtAccessor, stg.nower.Now())),
+		)},
+		[]Mutator[S]{},
+		nil,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(results), nil
+}
+
+// RunSweeper sweeps every interval until ctx is done, which is the error it
+// returns then, or until a sweep fails. It is meant to run in a goroutine of
+// its own.
+func (stg *storage[I, S]) RunSweeper(
+	ctx context.Context,
+	interval time.Duration,
+) (err error) {
+	if err = stg.checkExpiresAt(); err != nil {
+		return err
+	}
+
+	if interval <= 0 {
+		return fmt.Errorf(
+			"%w, sweep interval must be positive but got %s",
+			illegalArgumentError,
+			interval,
+		)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if _, err = stg.SweepContext(ctx); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package obj
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExpire(t *testing.T) {
+	type test struct {
+		name                  string
+		noExpiry              bool
+		run                   func(stg *storage[int, *TestSpec]) (int, error)
+		expectIllegalArgument bool
+		expectCount           int
+		expect                []*TestSpec
+		expectAll             []*TestSpec
+		expectBinLog          [][]string
+	}
+
+	var (
+		anHourAgo = parseTestTime(t, "2022-07-06T15:18:00-04:00")
+		inAnHour  = parseTestTime(t, "2022-07-06T17:18:00-04:00")
+		foo       = &TestSpec{Id: 1, Foo: "foo"}
+		fiz       = &TestSpec{Id: 2, Foo: "fiz", ExpiresAt: &inAnHour}
+		bar       = &TestSpec{Id: 3, Foo: "bar", ExpiresAt: &anHourAgo}
+		swept     = [][]string{{
+			`{"transaction":200,"type":"test","op":"begin","ts":"2022-07-06T16:18:00-04:00"}`,
+			`{"transaction":200,"type":"test","id":3,"ts":"2022-07-06T16:18:00-04:00","from":{"id":3,"foo":"bar","expiresAt":"2022-07-06T15:18:00-04:00"},"to":null}`,
+			`{"transaction":200,"type":"test","op":"commit","ts":"2022-07-06T16:18:00-04:00"}`,
+		}}
+	)
+
+	tests := []test{
+		{
+			name: "with select",
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				results, err := stg.Select(Noop[*TestSpec](), nil)
+				return len(results), err
+			},
+			expectCount: 2,
+			expect:      []*TestSpec{foo, fiz},
+			expectAll:   []*TestSpec{foo, fiz, bar},
+		},
+		{
+			name: "with projected select",
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				results, err := stg.NewSelectBuilder().
+					Where(Noop[*TestSpec]()).
+					Project(FooAccessor).
+					Run()
+				return len(results), err
+			},
+			expectCount: 2,
+			expect:      []*TestSpec{foo, fiz},
+			expectAll:   []*TestSpec{foo, fiz, bar},
+		},
+		{
+			name: "with get",
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				results, err := stg.GetMany([]int{2, 3})
+				return len(results), err
+			},
+			expectCount: 1,
+			expect:      []*TestSpec{foo, fiz},
+			expectAll:   []*TestSpec{foo, fiz, bar},
+		},
+		{
+			name: "with update",
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				updated, err := stg.Update(
+					FooEquals("bar"),
+					[]Mutator[*TestSpec]{MutateBar("BAR")},
+					nil,
+				)
+				return len(updated), err
+			},
+			expectCount: 0,
+			expect:      []*TestSpec{foo, fiz},
+			expectAll:   []*TestSpec{foo, fiz, bar},
+		},
+		{
+			name:     "with no expiry",
+			noExpiry: true,
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				results, err := stg.Select(Noop[*TestSpec](), nil)
+				return len(results), err
+			},
+			expectCount: 3,
+			expect:      []*TestSpec{foo, fiz, bar},
+			expectAll:   []*TestSpec{foo, fiz, bar},
+		},
+		{
+			name: "with sweep",
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				return stg.Sweep()
+			},
+			expectCount:  1,
+			expect:       []*TestSpec{foo, fiz},
+			expectAll:    []*TestSpec{foo, fiz},
+			expectBinLog: swept,
+		},
+		{
+			name: "with sweeper",
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				ctx, cancel := context.WithTimeout(
+					context.Background(),
+					20*time.Millisecond,
+				)
+				defer cancel()
+
+				err := stg.RunSweeper(ctx, time.Millisecond)
+				if !errors.Is(err, context.DeadlineExceeded) {
+					return 0, err
+				}
+				return 0, nil
+			},
+			expect:       []*TestSpec{foo, fiz},
+			expectAll:    []*TestSpec{foo, fiz},
+			expectBinLog: swept,
+		},
+		{
+			name: "with sweeper of a zero interval",
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				return 0, stg.RunSweeper(context.Background(), 0)
+			},
+			expectIllegalArgument: true,
+			expect:                []*TestSpec{foo, fiz},
+			expectAll:             []*TestSpec{foo, fiz, bar},
+		},
+		{
+			name:     "with sweep without an expiresAt accessor",
+			noExpiry: true,
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				return stg.Sweep()
+			},
+			expectIllegalArgument: true,
+			expect:                []*TestSpec{foo, fiz, bar},
+			expectAll:             []*TestSpec{foo, fiz, bar},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				all     []*TestSpec
+				count   int
+				err     error
+				results []*TestSpec
+			)
+
+			util := &testUtil{
+				test: t,
+				lines: []string{
+					`{"id":1,"foo":"foo"}`,
+					`{"id":2,"foo":"fiz","expiresAt":"2022-07-06T17:18:00-04:00"}`,
+					`{"id":3,"foo":"bar","expiresAt":"2022-07-06T15:18:00-04:00"}`,
+				},
+				expectBinLog: tc.expectBinLog,
+			}
+
+			err = util.setup()
+			defer util.teardown()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !tc.noExpiry {
+				util.stg.expiresAtAccessor = ExpiresAtAccessor
+			}
+
+			count, err = tc.run(util.stg)
+
+			switch {
+			case tc.expectIllegalArgument:
+				if !errors.Is(err, illegalArgumentError) {
+					t.Errorf("expected an illegal argument error but got %v", err)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case count != tc.expectCount:
+				t.Errorf("expected count %d but got %d", tc.expectCount, count)
+			}
+
+			orderBys := []Lesser[*TestSpec]{OrderById}
+
+			if results, err = util.stg.Select(
+				Noop[*TestSpec](),
+				orderBys,
+			); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(results, tc.expect) {
+				t.Errorf(
+					"expected select result to be \n%s\n but got \n%s\n",
+					testSpecSliceString(tc.expect),
+					testSpecSliceString(results),
+				)
+			}
+
+			if all, err = util.stg.Select(
+				&unfiltered[*TestSpec]{Noop[*TestSpec]()},
+				orderBys,
+			); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(all, tc.expectAll) {
+				t.Errorf(
+					"expected all records to be \n%s\n but got \n%s\n",
+					testSpecSliceString(tc.expectAll),
+					testSpecSliceString(all),
+				)
+			}
+
+			if tc.expectBinLog != nil {
+				util.handleExpectBinLog()
+			}
+		})
+	}
+}
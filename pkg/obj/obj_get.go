@@ -0,0 +1,69 @@
+package obj
+
+import "context"
+
+// Get returns the record with id. found is false when there is none.
+func (stg *storage[I, S]) Get(id I) (result S, found bool, err error) {
+	return stg.GetContext(context.Background(), id)
+}
+
+func (stg *storage[I, S]) GetContext(
+	ctx context.Context,
+	id I,
+) (result S, found bool, err error) {
+	var results []S
+
+	if results, err = stg.GetManyContext(ctx, []I{id}); err != nil {
+		return result, false, err
+	}
+
+	if len(results) == 0 {
+		return result, false, nil
+	}
+
+	return results[0], true, nil
+}
+
+// GetMany returns the records with ids in the order the ids are given. Ids
+// without a record are skipped.
+func (stg *storage[I, S]) GetMany(ids []I) (results []S, err error) {
+	return stg.GetManyContext(context.Background(), ids)
+}
+
+func (stg *storage[I, S]) GetManyContext(
+	ctx context.Context,
+	ids []I,
+) (results []S, err error) {
+	stg.lock.Lock()
+	defer stg.lock.Unlock()
+
+	return stg.getMany(ctx, ids)
+}
+
+func (stg *storage[I, S]) getMany(
+	ctx context.Context,
+	ids []I,
+) (results []S, err error) {
+	var (
+		byId  = make(map[I]S, len(ids))
+		specs []S
+	)
+
+	filters := In(stg.idAccessor, ids...)
+	if specs, err = stg.selectSpecs(ctx, filters, nil); err != nil {
+		return nil, err
+	}
+
+	for _, spec := range specs {
+		byId[stg.idAccessor.Get(spec)] = spec
+	}
+
+	results = make([]S, 0, len(specs))
+	for _, id := range ids {
+		if spec, found := byId[id]; found {
+			results = append(results, spec)
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,190 @@
+package obj
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	type test struct {
+		name        string
+		run         func(stg *storage[int, *TestSpec]) error
+		id          int
+		mockError   *mockErr
+		expectError string
+		expectFound bool
+		expect      *TestSpec
+	}
+
+	tests := []test{
+		{
+			name:        "with found",
+			id:          2,
+			expectFound: true,
+			expect:      &TestSpec{Id: 2, Foo: "fiz", Bar: "buz"},
+		},
+		{
+			name: "with not found",
+			id:   3,
+		},
+		{
+			name: "after update",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewUpdateBuilder().
+					Where(FooEquals("foo")).
+					Set(MutateFoo("a much longer foo")).
+					Run()
+				return err
+			},
+			id:          1,
+			expectFound: true,
+			expect: &TestSpec{
+				Id:        1,
+				Foo:       "a much longer foo",
+				Bar:       "bar",
+				UpdatedAt: GetTestNow(),
+			},
+		},
+		{
+			name: "after delete",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewDeleteBuilder().Where(FooEquals("foo")).Run()
+				return err
+			},
+			id: 1,
+		},
+		{
+			name: "after insert",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewInsertBuilder().Set(MutateFoo("new")).Run()
+				return err
+			},
+			id:          100,
+			expectFound: true,
+			expect: &TestSpec{
+				Id:        100,
+				Foo:       "new",
+				UpdatedAt: GetTestNow(),
+				CreatedAt: GetTestNow(),
+			},
+		},
+		{
+			name: "with read at error",
+			id:   1,
+			mockError: &mockErr{
+				mockErrType: mockErrTypeReadAt,
+				errorOn:     0,
+				msg:         "with read at error",
+			},
+			expectError: "with read at error",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+
+			util := &testUtil{
+				test: t,
+				lines: []string{
+					`{"id":1,"foo":"foo","bar":"bar"}`,
+					`{"id":2,"foo":"fiz","bar":"buz"}`,
+				},
+				mockError:   tc.mockError,
+				expectError: tc.expectError,
+			}
+
+			err = util.setup()
+			defer util.teardown()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.run != nil {
+				if err = tc.run(util.stg); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, found, err := util.stg.Get(tc.id)
+
+			if done := util.handleExpectError(err); done {
+				return
+			}
+
+			if found != tc.expectFound {
+				t.Errorf("expected found to be %t but got %t", tc.expectFound, found)
+			}
+
+			if !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf(
+					"expected get result to be \n%s\n but got \n%s\n",
+					tc.expect,
+					got,
+				)
+			}
+		})
+	}
+}
+
+func TestGetMany(t *testing.T) {
+	type test struct {
+		name   string
+		ids    []int
+		expect []*TestSpec
+	}
+
+	tests := []test{
+		{
+			name: "with ids",
+			ids:  []int{3, 1},
+			expect: []*TestSpec{
+				{Id: 3, Foo: "buz", Bar: "baz"},
+				{Id: 1, Foo: "foo", Bar: "bar"},
+			},
+		},
+		{
+			name: "with missing ids",
+			ids:  []int{4, 2, 5},
+			expect: []*TestSpec{
+				{Id: 2, Foo: "fiz", Bar: "buz"},
+			},
+		},
+		{
+			name:   "without ids",
+			ids:    []int{},
+			expect: []*TestSpec{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+
+			util := &testUtil{
+				test: t,
+				lines: []string{
+					`{"id":1,"foo":"foo","bar":"bar"}`,
+					`{"id":2,"foo":"fiz","bar":"buz"}`,
+					`{"id":3,"foo":"buz","bar":"baz"}`,
+				},
+				expect: tc.expect,
+			}
+
+			err = util.setup()
+			defer util.teardown()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := util.stg.GetMany(tc.ids)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			util.expectSpecs(got...)
+		})
+	}
+}
@@ -0,0 +1,463 @@
+package obj
+
+import (
+	"context"
+
+	"github.com/yo3jones/stg/pkg/fstln"
+	"golang.org/x/exp/constraints"
+	"golang.org/x/exp/slices"
+)
+
+// Index maps the values an accessor returns to the positions of the records
+// holding them so that a filter on that accessor can be answered with a
+// lookup instead of a scan of the whole data file. Indexes are declared with
+// OptIndex, built when the storage is opened and kept up to date on every
+// insert, update and delete. An index belongs to a single storage and must
+// not be shared.
+type Index[S any] interface {
+	Name() string
+	add(s S, pos fstln.Position)
+	clear()
+	conflict(s S, pos fstln.Position) (conflicting fstln.Position, found bool)
+	isUnique() bool
+	lookup(filter Matcher[S]) (positions map[fstln.Position]struct{}, ok bool)
+	remove(pos fstln.Position)
+}
+
+type hashIndex[S any, T comparable] struct {
+	accessor  Accessor[S, T]
+	positions map[T]map[fstln.Position]struct{}
+	unique    bool
+	values    map[fstln.Position]T
+}
+
+// NewHashIndex creates an index that answers Equals filters on accessor.
+func NewHashIndex[S any, T comparable](accessor Accessor[S, T]) Index[S] {
+	return newHashIndex(accessor)
+}
+
+func newHashIndex[S any, T comparable](
+	accessor Accessor[S, T],
+) *hashIndex[S, T] {
+	return &hashIndex[S, T]{
+		accessor:  accessor,
+		positions: map[T]map[fstln.Position]struct{}{},
+		values:    map[fstln.Position]T{},
+	}
+}
+
+func (index *hashIndex[S, T]) Name() string {
+	return index.accessor.Name()
+}
+
+func (index *hashIndex[S, T]) add(s S, pos fstln.Position) {
+	index.addValue(index.accessor.Get(s), pos)
+}
+
+func (index *hashIndex[S, T]) addValue(
+	value T,
+	pos fstln.Position,
+) (added bool) {
+	positions, found := index.positions[value]
+	if !found {
+		positions = map[fstln.Position]struct{}{}
+		index.positions[value] = positions
+	}
+
+	positions[pos] = struct{}{}
+	index.values[pos] = value
+
+	return !found
+}
+
+func (index *hashIndex[S, T]) clear() {
+	index.positions = map[T]map[fstln.Position]struct{}{}
+	index.values = map[fstln.Position]T{}
+}
+
+func (index *hashIndex[S, T]) conflict(
+	s S,
+	pos fstln.Position,
+) (conflicting fstln.Position, found bool) {
+	if !index.unique {
+		return conflicting, false
+	}
+
+	for conflicting = range index.positions[index.accessor.Get(s)] {
+		if conflicting != pos {
+			return conflicting, true
+		}
+	}
+
+	return conflicting, false
+}
+
+func (index *hashIndex[S, T]) isUnique() bool {
+	return index.unique
+}
+
+func (index *hashIndex[S, T]) lookup(
+	filter Matcher[S],
+) (positions map[fstln.Position]struct{}, ok bool) {
+	switch matcher := filter.(type) {
+	case *equals[S, T]:
+		if matcher.accessor.Name() != index.Name() {
+			return nil, false
+		}
+
+		return index.positions[matcher.value], true
+	case *in[S, T]:
+		if matcher.accessor.Name() != index.Name() {
+			return nil, false
+		}
+
+		positions = map[fstln.Position]struct{}{}
+		for value := range matcher.values {
+			for pos := range index.positions[value] {
+				positions[pos] = struct{}{}
+			}
+		}
+
+		return positions, true
+	}
+
+	return nil, false
+}
+
+func (index *hashIndex[S, T]) remove(pos fstln.Position) {
+	index.removeValue(pos)
+}
+
+func (index *hashIndex[S, T]) removeValue(
+	pos fstln.Position,
+) (value T, removed bool) {
+	value, found := index.values[pos]
+	if !found {
+		return value, false
+	}
+
+	delete(index.values, pos)
+
+	positions := index.positions[value]
+	delete(positions, pos)
+	if len(positions) > 0 {
+		return value, false
+	}
+
+	delete(index.positions, value)
+
+	return value, true
+}
+
+type orderedIndex[S any, T constraints.Ordered] struct {
+	*hashIndex[S, T]
+	keys []T
+}
+
+// NewOrderedIndex creates an index that answers Equals filters on accessor
+// and keeps its values sorted so that range filters can be answered too.
+func NewOrderedIndex[S any, T constraints.Ordered](
+	accessor Accessor[S, T],
+) Index[S] {
+	return &orderedIndex[S, T]{
+		hashIndex: newHashIndex(accessor),
+		keys:      make([]T, 0),
+	}
+}
+
+func (index *orderedIndex[S, T]) add(s S, pos fstln.Position) {
+	value := index.accessor.Get(s)
+
+	if added := index.addValue(value, pos); !added {
+		return
+	}
+
+	i, _ := slices.BinarySearch(index.keys, value)
+	index.keys = slices.Insert(index.keys, i, value)
+}
+
+func (index *orderedIndex[S, T]) clear() {
+	index.hashIndex.clear()
+	index.keys = index.keys[:0]
+}
+
+func (index *orderedIndex[S, T]) lookup(
+	filter Matcher[S],
+) (positions map[fstln.Position]struct{}, ok bool) {
+	if positions, ok = index.hashIndex.lookup(filter); ok {
+		return positions, true
+	}
+
+	matcher, ok := filter.(rangeMatcher[S, T])
+	if !ok || matcher.accessorName() != index.Name() {
+		return nil, false
+	}
+
+	lower, upper := matcher.bounds()
+
+	start := 0
+	if lower.set {
+		start, _ = slices.BinarySearch(index.keys, lower.value)
+	}
+
+	positions = map[fstln.Position]struct{}{}
+	for _, key := range index.keys[start:] {
+		if lower.set && !lower.inclusive && key == lower.value {
+			continue
+		}
+
+		if upper.set &&
+			(key > upper.value || !upper.inclusive && key == upper.value) {
+			break
+		}
+
+		for pos := range index.positions[key] {
+			positions[pos] = struct{}{}
+		}
+	}
+
+	return positions, true
+}
+
+func (index *orderedIndex[S, T]) remove(pos fstln.Position) {
+	value, removed := index.removeValue(pos)
+	if !removed {
+		return
+	}
+
+	if i, found := slices.BinarySearch(index.keys, value); found {
+		index.keys = slices.Delete(index.keys, i, i+1)
+	}
+}
+
+// rangeMatcher is implemented by filters that select a range of values of a
+// single accessor which an ordered index can answer.
+type rangeMatcher[S any, T constraints.Ordered] interface {
+	Matcher[S]
+	accessorName() string
+	bounds() (lower, upper bound[T])
+}
+
+type bound[T any] struct {
+	inclusive bool
+	set       bool
+	value     T
+}
+
+// buildIndexes rebuilds the position of every id and every declared index
+// from a scan of the data file.
+func (stg *storage[I, S]) buildIndexes() (err error) {
+	var (
+		ch    = make(chan specMsg[S], stg.concurrency)
+		errCh = make(chan error, stg.concurrency)
+		msgs  []specMsg[S]
+	)
+
+	stg.ids = map[fstln.Position]I{}
+	stg.positions = map[I]fstln.Position{}
+	for _, index := range stg.indexes {
+		index.clear()
+	}
+
+	controller := stg.newReadController(
+		context.Background(),
+		ch,
+		errCh,
+		Noop[S](),
+		opNoop,
+	)
+
+	go controller.Start()
+
+	if msgs, err = stg.gatherMsgs(ch, errCh); err != nil {
+		return err
+	}
+
+	for _, msg := range msgs {
+		stg.index(msg.spec, msg.pos)
+	}
+
+	return nil
+}
+
+// readSpec reads the record on the line at pos.
+func (stg *storage[I, S]) readSpec(pos fstln.Position) (s S, err error) {
+	var data []byte
+
+	if data, err = stg.stg.ReadAt(pos); err != nil {
+		return s, err
+	}
+
+	s = stg.factory.New()
+	if err = stg.marshalUnmarshaller.Unmarshal(data[:pos.Len-1], s); err != nil {
+		return s, err
+	}
+
+	return s, nil
+}
+
+func (stg *storage[I, S]) index(s S, pos fstln.Position) {
+	id := stg.idAccessor.Get(s)
+	stg.ids[pos] = id
+	stg.positions[id] = pos
+
+	for _, index := range stg.indexes {
+		index.add(s, pos)
+	}
+}
+
+// plan returns the positions of the records that can match filters when the
+// indexes are able to narrow them down. ok is false when the data file has to
+// be scanned instead. Filters are still applied to every record read so the
+// positions only need to be a superset of the matches.
+func (stg *storage[I, S]) plan(
+	filters Matcher[S],
+) (positions []fstln.Position, ok bool) {
+	var set map[fstln.Position]struct{}
+
+	if set, ok = stg.planSet(filters); !ok {
+		return nil, false
+	}
+
+	positions = make([]fstln.Position, 0, len(set))
+	for pos := range set {
+		positions = append(positions, pos)
+	}
+
+	slices.SortFunc(positions, func(a, b fstln.Position) bool {
+		return a.Offset < b.Offset
+	})
+
+	return positions, true
+}
+
+func (stg *storage[I, S]) planSet(
+	filters Matcher[S],
+) (positions map[fstln.Position]struct{}, ok bool) {
+	if positions, ok = stg.planIds(filters); ok {
+		return positions, true
+	}
+
+	for _, index := range stg.indexes {
+		if positions, ok = index.lookup(filters); ok {
+			return positions, true
+		}
+	}
+
+	switch filters := filters.(type) {
+	case *and[S]:
+		return stg.planAnd(filters.matchers)
+	case *or[S]:
+		return stg.planOr(filters.matchers)
+	}
+
+	return nil, false
+}
+
+func (stg *storage[I, S]) planAnd(
+	matchers []Matcher[S],
+) (positions map[fstln.Position]struct{}, ok bool) {
+	for _, matcher := range matchers {
+		set, planned := stg.planSet(matcher)
+		if !planned {
+			continue
+		}
+
+		if !ok {
+			positions, ok = set, true
+			continue
+		}
+
+		intersection := map[fstln.Position]struct{}{}
+		for pos := range set {
+			if _, found := positions[pos]; found {
+				intersection[pos] = struct{}{}
+			}
+		}
+		positions = intersection
+	}
+
+	return positions, ok
+}
+
+// planIds answers Equals and In filters on the id accessor from the position
+// kept for every id.
+func (stg *storage[I, S]) planIds(
+	filters Matcher[S],
+) (positions map[fstln.Position]struct{}, ok bool) {
+	var ids []I
+
+	switch matcher := filters.(type) {
+	case *equals[S, I]:
+		if matcher.accessor.Name() != stg.idAccessor.Name() {
+			return nil, false
+		}
+		ids = []I{matcher.value}
+	case *in[S, I]:
+		if matcher.accessor.Name() != stg.idAccessor.Name() {
+			return nil, false
+		}
+		for id := range matcher.values {
+			ids = append(ids, id)
+		}
+	default:
+		return nil, false
+	}
+
+	positions = map[fstln.Position]struct{}{}
+	for _, id := range ids {
+		if pos, found := stg.positions[id]; found {
+			positions[pos] = struct{}{}
+		}
+	}
+
+	return positions, true
+}
+
+func (stg *storage[I, S]) planOr(
+	matchers []Matcher[S],
+) (positions map[fstln.Position]struct{}, ok bool) {
+	if len(matchers) == 0 {
+		return nil, false
+	}
+
+	positions = map[fstln.Position]struct{}{}
+
+	for _, matcher := range matchers {
+		set, planned := stg.planSet(matcher)
+		if !planned {
+			return nil, false
+		}
+
+		for pos := range set {
+			positions[pos] = struct{}{}
+		}
+	}
+
+	return positions, true
+}
+
+func (stg *storage[I, S]) unindex(s S, pos fstln.Position) {
+	stg.unindexId(stg.idAccessor.Get(s), pos)
+}
+
+func (stg *storage[I, S]) unindexId(id I, pos fstln.Position) {
+	if stg.positions[id] == pos {
+		delete(stg.positions, id)
+	}
+	delete(stg.ids, pos)
+
+	for _, index := range stg.indexes {
+		index.remove(pos)
+	}
+}
+
+// OptIndex declares an index the storage maintains and uses to answer
+// filters. It may be given more than once, with a distinct name each time.
+type OptIndex[S any] struct {
+	Value Index[S]
+}
+
+func (opt OptIndex[S]) isStorageOpt() bool {
+	return true
+}
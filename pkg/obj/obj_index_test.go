@@ -0,0 +1,326 @@
+package obj
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIndex(t *testing.T) {
+	type test struct {
+		name          string
+		run           func(stg *storage[int, *TestSpec]) error
+		filters       Matcher[*TestSpec]
+		mockError     *mockErr
+		expectPlanned int
+		expectError   string
+		expect        []*TestSpec
+	}
+
+	tests := []test{
+		{
+			name:          "with hash index",
+			filters:       FooEquals("foo"),
+			expectPlanned: 2,
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "foo", Bar: "buz"},
+			},
+		},
+		{
+			name:          "with ordered index",
+			filters:       BarEquals("buz"),
+			expectPlanned: 2,
+			expect: []*TestSpec{
+				{Id: 2, Foo: "foo", Bar: "buz"},
+				{Id: 4, Foo: "fiz", Bar: "buz"},
+			},
+		},
+		{
+			name:          "with range",
+			filters:       Between[*TestSpec, string](BarAccessor, "bar", "bur"),
+			expectPlanned: 2,
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 3, Foo: "fiz", Bar: "bar"},
+			},
+		},
+		{
+			name:          "with open range",
+			filters:       GreaterThan[*TestSpec, string](BarAccessor, "bar"),
+			expectPlanned: 2,
+			expect: []*TestSpec{
+				{Id: 2, Foo: "foo", Bar: "buz"},
+				{Id: 4, Foo: "fiz", Bar: "buz"},
+			},
+		},
+		{
+			name:          "with prefix",
+			filters:       HasPrefix[*TestSpec](BarAccessor, "bu"),
+			expectPlanned: 2,
+			expect: []*TestSpec{
+				{Id: 2, Foo: "foo", Bar: "buz"},
+				{Id: 4, Foo: "fiz", Bar: "buz"},
+			},
+		},
+		{
+			name:          "with prefix on hash index",
+			filters:       HasPrefix[*TestSpec](FooAccessor, "fo"),
+			expectPlanned: -1,
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "foo", Bar: "buz"},
+			},
+		},
+		{
+			name:          "with in",
+			filters:       In[*TestSpec, string](FooAccessor, "fiz", "missing"),
+			expectPlanned: 2,
+			expect: []*TestSpec{
+				{Id: 3, Foo: "fiz", Bar: "bar"},
+				{Id: 4, Foo: "fiz", Bar: "buz"},
+			},
+		},
+		{
+			name:          "with in on ids",
+			filters:       In[*TestSpec, int](IdAccessor, 4, 1, 5),
+			expectPlanned: 2,
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 4, Foo: "fiz", Bar: "buz"},
+			},
+		},
+		{
+			name:          "with not",
+			filters:       NotEquals[*TestSpec, string](FooAccessor, "foo"),
+			expectPlanned: -1,
+			expect: []*TestSpec{
+				{Id: 3, Foo: "fiz", Bar: "bar"},
+				{Id: 4, Foo: "fiz", Bar: "buz"},
+			},
+		},
+		{
+			name:          "with missing value",
+			filters:       FooEquals("missing"),
+			expectPlanned: 0,
+			expect:        []*TestSpec{},
+		},
+		{
+			name:          "with and",
+			filters:       And(FooEquals("fiz"), BarEquals("bar")),
+			expectPlanned: 1,
+			expect: []*TestSpec{
+				{Id: 3, Foo: "fiz", Bar: "bar"},
+			},
+		},
+		{
+			name: "with and on unindexed accessor",
+			filters: And(
+				FooEquals("fiz"),
+				Equals[*TestSpec, time.Time](UpdatedAtAccessor, time.Time{}),
+			),
+			expectPlanned: 2,
+			expect: []*TestSpec{
+				{Id: 3, Foo: "fiz", Bar: "bar"},
+				{Id: 4, Foo: "fiz", Bar: "buz"},
+			},
+		},
+		{
+			name:          "with or",
+			filters:       Or(FooEquals("foo"), BarEquals("bar")),
+			expectPlanned: 3,
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "foo", Bar: "buz"},
+				{Id: 3, Foo: "fiz", Bar: "bar"},
+			},
+		},
+		{
+			name: "with or on unindexed accessor",
+			filters: Or(
+				FooEquals("foo"),
+				Equals[*TestSpec, time.Time](UpdatedAtAccessor, GetTestNow()),
+			),
+			expectPlanned: -1,
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "foo", Bar: "buz"},
+			},
+		},
+		{
+			name:          "with id",
+			filters:       Equals[*TestSpec, int](IdAccessor, 4),
+			expectPlanned: 1,
+			expect: []*TestSpec{
+				{Id: 4, Foo: "fiz", Bar: "buz"},
+			},
+		},
+		{
+			name:          "with missing id",
+			filters:       Equals[*TestSpec, int](IdAccessor, 5),
+			expectPlanned: 0,
+			expect:        []*TestSpec{},
+		},
+		{
+			name:          "without filters",
+			filters:       Noop[*TestSpec](),
+			expectPlanned: -1,
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "foo", Bar: "buz"},
+				{Id: 3, Foo: "fiz", Bar: "bar"},
+				{Id: 4, Foo: "fiz", Bar: "buz"},
+			},
+		},
+		{
+			name: "after insert",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewInsertBuilder().
+					Set(MutateFoo("foo"), MutateBar("new")).
+					Run()
+				return err
+			},
+			filters:       FooEquals("foo"),
+			expectPlanned: 3,
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "foo", Bar: "buz"},
+				{
+					Id:        100,
+					Foo:       "foo",
+					Bar:       "new",
+					UpdatedAt: GetTestNow(),
+					CreatedAt: GetTestNow(),
+				},
+			},
+		},
+		{
+			name: "after update",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewUpdateBuilder().
+					Where(BarEquals("bar")).
+					Set(MutateFoo("a much longer foo")).
+					Run()
+				return err
+			},
+			filters:       Or(FooEquals("foo"), FooEquals("a much longer foo")),
+			expectPlanned: 3,
+			expect: []*TestSpec{
+				{
+					Id:        1,
+					Foo:       "a much longer foo",
+					Bar:       "bar",
+					UpdatedAt: GetTestNow(),
+				},
+				{Id: 2, Foo: "foo", Bar: "buz"},
+				{
+					Id:        3,
+					Foo:       "a much longer foo",
+					Bar:       "bar",
+					UpdatedAt: GetTestNow(),
+				},
+			},
+		},
+		{
+			name: "after delete",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewDeleteBuilder().Where(BarEquals("bar")).Run()
+				return err
+			},
+			filters:       Or(FooEquals("foo"), BarEquals("bar")),
+			expectPlanned: 1,
+			expect: []*TestSpec{
+				{Id: 2, Foo: "foo", Bar: "buz"},
+			},
+		},
+		{
+			name: "after rollback",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				tx := stg.Begin()
+				if _, err = tx.NewUpdateBuilder().
+					Where(FooEquals("foo")).
+					Set(MutateFoo("a much longer foo")).
+					Run(); err != nil {
+					return err
+				}
+				if _, err = tx.NewDeleteBuilder().
+					Where(FooEquals("fiz")).
+					Run(); err != nil {
+					return err
+				}
+				return tx.Rollback()
+			},
+			filters:       Or(FooEquals("foo"), FooEquals("fiz")),
+			expectPlanned: 4,
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "foo", Bar: "buz"},
+				{Id: 3, Foo: "fiz", Bar: "bar"},
+				{Id: 4, Foo: "fiz", Bar: "buz"},
+			},
+		},
+		{
+			name:          "with read at error",
+			filters:       FooEquals("foo"),
+			expectPlanned: 2,
+			mockError: &mockErr{
+				mockErrType: mockErrTypeReadAt,
+				errorOn:     0,
+				msg:         "with read at error",
+			},
+			expectError: "with read at error",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+
+			util := &testUtil{
+				test: t,
+				lines: []string{
+					`{"id":1,"foo":"foo","bar":"bar"}`,
+					`{"id":2,"foo":"foo","bar":"buz"}`,
+					`{"id":3,"foo":"fiz","bar":"bar"}`,
+					`{"id":4,"foo":"fiz","bar":"buz"}`,
+				},
+				indexes: []Index[*TestSpec]{
+					NewHashIndex[*TestSpec, string](FooAccessor),
+					NewOrderedIndex[*TestSpec, string](BarAccessor),
+				},
+				filters:     tc.filters,
+				orderBys:    []Lesser[*TestSpec]{OrderById},
+				mockError:   tc.mockError,
+				expectError: tc.expectError,
+				expect:      tc.expect,
+			}
+
+			err = util.setup()
+			defer util.teardown()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.run != nil {
+				if err = tc.run(util.stg); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			gotPlanned := -1
+			if positions, ok := util.stg.plan(tc.filters); ok {
+				gotPlanned = len(positions)
+			}
+
+			if gotPlanned != tc.expectPlanned {
+				t.Errorf(
+					"expected %d planned positions but got %d",
+					tc.expectPlanned,
+					gotPlanned,
+				)
+			}
+
+			util.expectSelect()
+		})
+	}
+}
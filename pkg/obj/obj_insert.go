@@ -1,56 +1,196 @@
 package obj
 
+import (
+	"context"
+	"time"
+
+	"github.com/yo3jones/stg/pkg/fstln"
+)
+
 func (stg *storage[I, S]) Insert(
 	mutators []Mutator[S],
 ) (inserted S, err error) {
-	stg.lock.Lock()
-	defer stg.lock.Unlock()
+	return stg.InsertContext(context.Background(), mutators)
+}
+
+func (stg *storage[I, S]) InsertContext(
+	ctx context.Context,
+	mutators []Mutator[S],
+) (inserted S, err error) {
+	tx := stg.begin()
+	defer func() { err = tx.end(err) }()
+
+	return stg.insert(ctx, tx, mutators)
+}
+
+func (stg *storage[I, S]) insert(
+	ctx context.Context,
+	tx *transaction[I, S],
+	mutators []Mutator[S],
+) (inserted S, err error) {
 	var (
-		data  []byte
-		trans = stg.binLogStg.StartTransaction(stg.objType)
+		data []byte
+		pos  fstln.Position
 	)
-	defer trans.End()
 
-	inserted = stg.factory.New()
+	if err = ctx.Err(); err != nil {
+		return inserted, err
+	}
 
-	now := stg.nower.Now()
-	stg.idAccessor.Set(inserted, stg.idFactory.New())
-	stg.updatedAtAccessor.Set(inserted, now)
-	stg.createdAtAccessor.Set(inserted, now)
+	inserted = stg.newSpec(mutators, stg.nower.Now())
 
-	for _, mutator := range mutators {
-		mutator.Mutate(inserted)
+	if err = stg.checkUnique(inserted, fstln.EOF); err != nil {
+		return inserted, err
 	}
 
 	if data, err = stg.marshalUnmarshaller.Marshal(inserted); err != nil {
 		return inserted, err
 	}
 
-	if err = trans.LogInsert(stg.idAccessor.Get(inserted), data); err != nil {
+	err = tx.binLogTrans.LogInsert(stg.idAccessor.Get(inserted), data)
+	if err != nil {
 		return inserted, err
 	}
 
-	if _, err = stg.stg.Insert(data); err != nil {
+	if pos, err = stg.stg.InsertContext(ctx, data); err != nil {
 		return inserted, err
 	}
 
-	// TODO handle mutation log
+	tx.record(stg.idAccessor.Get(inserted), nil, pos, true)
+	tx.queueEvent(Event[S]{After: inserted, Type: EventInsert})
+	stg.index(inserted, pos)
+
+	return inserted, nil
+}
+
+func (stg *storage[I, S]) InsertMany(
+	mutators [][]Mutator[S],
+) (inserted []S, err error) {
+	return stg.InsertManyContext(context.Background(), mutators)
+}
+
+// InsertManyContext inserts a record for each set of mutators in a single
+// transaction and appends them all to the data file with one write.
+func (stg *storage[I, S]) InsertManyContext(
+	ctx context.Context,
+	mutators [][]Mutator[S],
+) (inserted []S, err error) {
+	tx := stg.begin()
+	defer func() { err = tx.end(err) }()
+
+	return stg.insertMany(ctx, tx, mutators)
+}
+
+func (stg *storage[I, S]) insertMany(
+	ctx context.Context,
+	tx *transaction[I, S],
+	mutators [][]Mutator[S],
+) (inserted []S, err error) {
+	var (
+		data      []byte
+		lines     = make([][]byte, 0, len(mutators))
+		now       = stg.nower.Now()
+		positions []fstln.Position
+	)
+
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// The records are indexed at a made up position of their own until they
+	// are written so that they are also checked against each other for
+	// unique violations. Those positions are dropped again on failure.
+	placeholder := func(i int) fstln.Position {
+		return fstln.Position{Offset: -1 - i}
+	}
+	inserted = make([]S, 0, len(mutators))
+	defer func() {
+		if err != nil {
+			for i, s := range inserted {
+				stg.unindex(s, placeholder(i))
+			}
+		}
+	}()
+
+	for i, recordMutators := range mutators {
+		s := stg.newSpec(recordMutators, now)
+
+		if err = stg.checkUnique(s, fstln.EOF); err != nil {
+			return nil, err
+		}
+		stg.index(s, placeholder(i))
+		inserted = append(inserted, s)
+
+		if data, err = stg.marshalUnmarshaller.Marshal(s); err != nil {
+			return nil, err
+		}
+
+		if err = tx.binLogTrans.LogInsert(stg.idAccessor.Get(s), data); err != nil {
+			return nil, err
+		}
+
+		lines = append(lines, data)
+	}
+
+	if positions, err = stg.stg.InsertManyContext(ctx, lines); err != nil {
+		return nil, err
+	}
+
+	for i, s := range inserted {
+		stg.unindex(s, placeholder(i))
+	}
+
+	for i, s := range inserted {
+		tx.record(stg.idAccessor.Get(s), nil, positions[i], true)
+		tx.queueEvent(Event[S]{After: s, Type: EventInsert})
+		stg.index(s, positions[i])
+	}
 
 	return inserted, nil
 }
 
+// newSpec creates a record with a new id and both timestamps set to now, and
+// then applies mutators to it.
+func (stg *storage[I, S]) newSpec(mutators []Mutator[S], now time.Time) S {
+	s := stg.factory.New()
+
+	stg.idAccessor.Set(s, stg.idFactory.New())
+	stg.updatedAtAccessor.Set(s, now)
+	stg.createdAtAccessor.Set(s, now)
+	if stg.versionAccessor != nil {
+		stg.versionAccessor.Set(s, 1)
+	}
+
+	for _, mutator := range mutators {
+		mutator.Mutate(s)
+	}
+
+	return s
+}
+
 func (stg *storage[I, S]) NewInsertBuilder() InsertBuilder[S] {
 	return &insertBuilder[S]{stg: stg}
 }
 
 type InsertBuilder[S any] interface {
+	Add(mutators ...Mutator[S]) InsertBuilder[S]
 	Set(mutators ...Mutator[S]) InsertBuilder[S]
 	Run() (inserted S, err error)
+	RunContext(ctx context.Context) (inserted S, err error)
+	RunMany() (inserted []S, err error)
+	RunManyContext(ctx context.Context) (inserted []S, err error)
 }
 
 type insertBuilder[S any] struct {
+	batch    [][]Mutator[S]
 	mutators []Mutator[S]
-	stg      Storage[S]
+	stg      operator[S]
+}
+
+// Add queues a record to be inserted with mutators by RunMany.
+func (builder *insertBuilder[S]) Add(mutators ...Mutator[S]) InsertBuilder[S] {
+	builder.batch = append(builder.batch, mutators)
+	return builder
 }
 
 func (builder *insertBuilder[S]) Set(mutators ...Mutator[S]) InsertBuilder[S] {
@@ -59,5 +199,22 @@ func (builder *insertBuilder[S]) Set(mutators ...Mutator[S]) InsertBuilder[S] {
 }
 
 func (builder *insertBuilder[S]) Run() (inserted S, err error) {
-	return builder.stg.Insert(builder.mutators)
+	return builder.RunContext(context.Background())
+}
+
+func (builder *insertBuilder[S]) RunContext(
+	ctx context.Context,
+) (inserted S, err error) {
+	return builder.stg.InsertContext(ctx, builder.mutators)
+}
+
+func (builder *insertBuilder[S]) RunMany() (inserted []S, err error) {
+	return builder.RunManyContext(context.Background())
+}
+
+// RunManyContext inserts every record queued with Add, see InsertMany.
+func (builder *insertBuilder[S]) RunManyContext(
+	ctx context.Context,
+) (inserted []S, err error) {
+	return builder.stg.InsertManyContext(ctx, builder.batch)
 }
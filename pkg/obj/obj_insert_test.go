@@ -43,7 +43,9 @@ func TestInsert(t *testing.T) {
 			},
 			expectBinLog: [][]string{
 				{
+					`{"transaction":200,"type":"test","op":"begin","ts":"2022-07-06T16:18:00-04:00"}`,
 					`{"transaction":200,"type":"test","id":100,"ts":"2022-07-06T16:18:00-04:00","from":null,"to":{"id":100,"type":"","foo":"foo","bar":"bar","updatedAt":"2022-07-06T16:18:00-04:00","createdAt":"2022-07-06T16:18:00-04:00"}}`,
+					`{"transaction":200,"type":"test","op":"commit","ts":"2022-07-06T16:18:00-04:00"}`,
 				},
 			},
 		},
@@ -126,3 +128,176 @@ func TestInsert(t *testing.T) {
 		})
 	}
 }
+
+func TestInsertMany(t *testing.T) {
+	type test struct {
+		name         string
+		indexes      []Index[*TestSpec]
+		batch        [][]Mutator[*TestSpec]
+		mockErr      *mockErr
+		expectError  string
+		expect       []*TestSpec
+		expectLines  [][]string
+		expectBinLog [][]string
+	}
+
+	var (
+		existing = []*TestSpec{
+			{Id: 1, Foo: "foo", Bar: "bar"},
+			{Id: 2, Foo: "fiz", Bar: "buz"},
+		}
+		batch = [][]Mutator[*TestSpec]{
+			{MutateFoo("one")},
+			{MutateFoo("two"), MutateBar("bar")},
+		}
+	)
+
+	tests := []test{
+		{
+			name:  "with success",
+			batch: batch,
+			expect: append(
+				existing,
+				&TestSpec{
+					Id:        100,
+					Foo:       "one",
+					UpdatedAt: GetTestNow(),
+					CreatedAt: GetTestNow(),
+				},
+				&TestSpec{
+					Id:        101,
+					Foo:       "two",
+					Bar:       "bar",
+					UpdatedAt: GetTestNow(),
+					CreatedAt: GetTestNow(),
+				},
+			),
+			expectLines: [][]string{
+				{
+					`{"id":1,"foo":"foo","bar":"bar"}`,
+					`{"id":2,"foo":"fiz","bar":"buz"}`,
+					`{"id":100,"type":"","foo":"one","bar":"","updatedAt":"2022-07-06T16:18:00-04:00","createdAt":"2022-07-06T16:18:00-04:00"}`,
+					`{"id":101,"type":"","foo":"two","bar":"bar","updatedAt":"2022-07-06T16:18:00-04:00","createdAt":"2022-07-06T16:18:00-04:00"}`,
+				},
+			},
+			expectBinLog: [][]string{
+				{
+					`{"transaction":200,"type":"test","op":"begin","ts":"2022-07-06T16:18:00-04:00"}`,
+					`{"transaction":200,"type":"test","id":100,"ts":"2022-07-06T16:18:00-04:00","from":null,"to":{"id":100,"type":"","foo":"one","bar":"","updatedAt":"2022-07-06T16:18:00-04:00","createdAt":"2022-07-06T16:18:00-04:00"}}`,
+					`{"transaction":200,"type":"test","id":101,"ts":"2022-07-06T16:18:00-04:00","from":null,"to":{"id":101,"type":"","foo":"two","bar":"bar","updatedAt":"2022-07-06T16:18:00-04:00","createdAt":"2022-07-06T16:18:00-04:00"}}`,
+					`{"transaction":200,"type":"test","op":"commit","ts":"2022-07-06T16:18:00-04:00"}`,
+				},
+			},
+		},
+		{
+			name:   "without records",
+			batch:  [][]Mutator[*TestSpec]{},
+			expect: existing,
+		},
+		{
+			name: "with unique violation within the batch",
+			indexes: []Index[*TestSpec]{
+				NewUniqueIndex[*TestSpec, string](FooAccessor),
+			},
+			batch: [][]Mutator[*TestSpec]{
+				{MutateFoo("one")},
+				{MutateFoo("one")},
+			},
+			expectError: "unique violation error, foo is already taken by 100",
+		},
+		{
+			name: "with unique violation against a record",
+			indexes: []Index[*TestSpec]{
+				NewUniqueIndex[*TestSpec, string](FooAccessor),
+			},
+			batch: [][]Mutator[*TestSpec]{
+				{MutateFoo("one")},
+				{MutateFoo("fiz")},
+			},
+			expectError: "unique violation error, foo is already taken by 2",
+		},
+		{
+			name:  "with insert error",
+			batch: batch,
+			mockErr: &mockErr{
+				mockErrType: mockErrTypeInsert,
+				errorOn:     0,
+				msg:         "with insert error",
+			},
+			expectError: "with insert error",
+		},
+		{
+			name:  "with log insert error",
+			batch: batch,
+			mockErr: &mockErr{
+				mockErrType: mockErrTypeBinLog,
+				errorOn:     1,
+				msg:         "with log insert error",
+			},
+			expectError: "with log insert error",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+
+			util := &testUtil{
+				test: t,
+				lines: []string{
+					`{"id":1,"foo":"foo","bar":"bar"}`,
+					`{"id":2,"foo":"fiz","bar":"buz"}`,
+				},
+				indexes:      tc.indexes,
+				mockError:    tc.mockErr,
+				expectError:  tc.expectError,
+				filters:      Noop[*TestSpec](),
+				orderBys:     []Lesser[*TestSpec]{OrderById},
+				expect:       tc.expect,
+				expectLines:  tc.expectLines,
+				expectBinLog: tc.expectBinLog,
+			}
+
+			err = util.setup()
+			defer util.teardown()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			builder := util.stg.NewInsertBuilder()
+			for _, mutators := range tc.batch {
+				builder.Add(mutators...)
+			}
+
+			inserted, err := builder.RunMany()
+
+			if done := util.handleExpectError(err); done {
+				// Nothing of a failed batch is left behind.
+				util.expectError = ""
+				util.expect = existing
+				util.stg.stg = util.fstlnstg.(*mockStg).stg
+				util.expectSelect()
+				return
+			}
+
+			if len(inserted) != len(tc.batch) {
+				t.Errorf(
+					"expected %d inserted records but got %d",
+					len(tc.batch),
+					len(inserted),
+				)
+			}
+
+			util.expectSelect()
+
+			if tc.expectLines != nil {
+				util.handleExpectLines()
+			}
+
+			if tc.expectBinLog != nil {
+				util.handleExpectBinLog()
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package obj
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrStopIteration may be returned by the function given to Iterate, wrapped
+// or not, to stop early without Iterate reporting an error.
+var ErrStopIteration = fmt.Errorf("stop iteration")
+
+// Iterate calls fn with every record matching filters as it is read instead
+// of collecting them first, so records come in no particular order. It stops
+// at the first error fn returns, when ctx is done or when reading fails, and
+// returns only once the reader has stopped.
+//
+// fn runs while the storage is locked and the data file is being read, so it
+// must not call the storage or a transaction of it, which would deadlock.
+// Collect what fn needs to write and write it once Iterate returns.
+func (stg *storage[I, S]) Iterate(
+	ctx context.Context,
+	filters Matcher[S],
+	fn func(s S) error,
+) (err error) {
+	stg.lock.Lock()
+	defer stg.lock.Unlock()
+
+	return stg.iterate(ctx, filters, fn)
+}
+
+func (stg *storage[I, S]) iterate(
+	ctx context.Context,
+	filters Matcher[S],
+	fn func(s S) error,
+) (err error) {
+	var (
+		ch    = make(chan specMsg[S], stg.concurrency)
+		errCh = make(chan error, stg.concurrency)
+	)
+
+	readCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	controller := stg.newReadController(
+		readCtx,
+		ch,
+		errCh,
+		stg.live(filters),
+		opNoop,
+	)
+
+	go controller.Start()
+
+	// stop cancels the read and waits for the controller to report that all
+	// of its workers are done, dropping whatever they still send.
+	stop := func(err error) error {
+		cancel()
+		stg.gatherEach(ch, errCh, func(msg specMsg[S]) {})
+
+		if errors.Is(err, ErrStopIteration) {
+			return nil
+		}
+		return err
+	}
+
+	for {
+		if err = ctx.Err(); err != nil {
+			return stop(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return stop(ctx.Err())
+		case err = <-errCh:
+			return stop(err)
+		case msg := <-ch:
+			if msg.op == opDone {
+				return stg.drainErr(errCh)
+			}
+
+			if err = fn(msg.spec); err != nil {
+				return stop(err)
+			}
+		}
+	}
+}
+
+// drainErr returns the first error left on errCh without waiting for one.
+func (*storage[I, S]) drainErr(errCh chan error) (err error) {
+	select {
+	case err = <-errCh:
+		return err
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,131 @@
+package obj
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestIterate(t *testing.T) {
+	type test struct {
+		name        string
+		ctx         func() context.Context
+		stopAfter   int
+		fnError     error
+		mockError   *mockErr
+		expectError string
+		expectCount int
+	}
+
+	canceled := func() context.Context {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		return ctx
+	}
+
+	tests := []test{
+		{
+			name:        "with all records",
+			expectCount: 5,
+		},
+		{
+			name:        "with stop iteration",
+			stopAfter:   2,
+			fnError:     ErrStopIteration,
+			expectCount: 2,
+		},
+		{
+			name:        "with wrapped stop iteration",
+			stopAfter:   2,
+			fnError:     fmt.Errorf("%w, found enough", ErrStopIteration),
+			expectCount: 2,
+		},
+		{
+			name:        "with fn error",
+			stopAfter:   1,
+			fnError:     fmt.Errorf("with fn error"),
+			expectError: "with fn error",
+		},
+		{
+			name:        "with canceled context",
+			ctx:         canceled,
+			expectError: "context canceled",
+		},
+		{
+			name: "with read error",
+			mockError: &mockErr{
+				mockErrType: mockErrTypeRead,
+				errorOn:     1,
+				msg:         "with read error",
+			},
+			expectError: "with read error",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				err   error
+				count int
+				ctx   = context.Background()
+			)
+
+			util := &testUtil{
+				test: t,
+				lines: []string{
+					`{"id":1,"foo":"foo","bar":"bar"}`,
+					`{"id":2,"foo":"fiz","bar":"buz"}`,
+					`{"id":3,"foo":"buz","bar":"baz"}`,
+					`{"id":4,"foo":"baz","bar":"foo"}`,
+					`{"id":5,"foo":"bar","bar":"fiz"}`,
+				},
+				mockError:   tc.mockError,
+				expectError: tc.expectError,
+				filters:     Noop[*TestSpec](),
+				orderBys:    []Lesser[*TestSpec]{OrderById},
+			}
+
+			err = util.setup()
+			defer util.teardown()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.ctx != nil {
+				ctx = tc.ctx()
+			}
+
+			err = util.stg.NewSelectBuilder().
+				Where(Noop[*TestSpec]()).
+				Iterate(ctx, func(s *TestSpec) error {
+					count++
+					if count == tc.stopAfter {
+						return tc.fnError
+					}
+					return nil
+				})
+
+			if done := util.handleExpectError(err); !done &&
+				count != tc.expectCount {
+				t.Errorf(
+					"expected fn to be called %d times but got %d",
+					tc.expectCount,
+					count,
+				)
+			}
+
+			// the storage must be usable again once Iterate returns
+			util.expectError = ""
+			util.stg.stg = util.fstlnstg.(*mockStg).stg
+			util.expect = []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "fiz", Bar: "buz"},
+				{Id: 3, Foo: "buz", Bar: "baz"},
+				{Id: 4, Foo: "baz", Bar: "foo"},
+				{Id: 5, Foo: "bar", Bar: "fiz"},
+			}
+			util.expectSelect()
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package obj
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/constraints"
+)
+
+// MatchFunc adapts an ordinary function to a Matcher.
+type MatchFunc[S any] func(s S) bool
+
+func (fn MatchFunc[S]) Match(s S) bool {
+	return fn(s)
+}
+
+type not[S any] struct {
+	matcher Matcher[S]
+}
+
+func (matcher *not[S]) Match(s S) bool {
+	return !matcher.matcher.Match(s)
+}
+
+func Not[S any](matcher Matcher[S]) Matcher[S] {
+	return &not[S]{matcher}
+}
+
+func NotEquals[S any, T comparable](
+	accessor Accessor[S, T],
+	value T,
+) Matcher[S] {
+	return Not(Equals(accessor, value))
+}
+
+// IsZero matches records whose accessor returns the zero value of its type.
+func IsZero[S any, T comparable](accessor Accessor[S, T]) Matcher[S] {
+	var zero T
+	return Equals(accessor, zero)
+}
+
+type in[S any, T comparable] struct {
+	accessor Accessor[S, T]
+	values   map[T]struct{}
+}
+
+func (matcher *in[S, T]) Match(s S) bool {
+	_, found := matcher.values[matcher.accessor.Get(s)]
+	return found
+}
+
+func (matcher *in[S, T]) accessorName() string {
+	return matcher.accessor.Name()
+}
+
+func In[S any, T comparable](
+	accessor Accessor[S, T],
+	values ...T,
+) Matcher[S] {
+	set := make(map[T]struct{}, len(values))
+	for _, value := range values {
+		set[value] = struct{}{}
+	}
+
+	return &in[S, T]{accessor, set}
+}
+
+func NotIn[S any, T comparable](
+	accessor Accessor[S, T],
+	values ...T,
+) Matcher[S] {
+	return Not(In(accessor, values...))
+}
+
+type between[S any, T constraints.Ordered] struct {
+	accessor Accessor[S, T]
+	lower    bound[T]
+	upper    bound[T]
+}
+
+func (matcher *between[S, T]) Match(s S) bool {
+	var (
+		lower = matcher.lower
+		upper = matcher.upper
+		value = matcher.accessor.Get(s)
+	)
+
+	if lower.set &&
+		(value < lower.value || !lower.inclusive && value == lower.value) {
+		return false
+	}
+
+	if upper.set &&
+		(value > upper.value || !upper.inclusive && value == upper.value) {
+		return false
+	}
+
+	return true
+}
+
+func (matcher *between[S, T]) accessorName() string {
+	return matcher.accessor.Name()
+}
+
+func (matcher *between[S, T]) bounds() (lower, upper bound[T]) {
+	return matcher.lower, matcher.upper
+}
+
+func GreaterThan[S any, T constraints.Ordered](
+	accessor Accessor[S, T],
+	value T,
+) Matcher[S] {
+	return &between[S, T]{
+		accessor: accessor,
+		lower:    bound[T]{inclusive: false, set: true, value: value},
+	}
+}
+
+func GreaterThanOrEquals[S any, T constraints.Ordered](
+	accessor Accessor[S, T],
+	value T,
+) Matcher[S] {
+	return &between[S, T]{
+		accessor: accessor,
+		lower:    bound[T]{inclusive: true, set: true, value: value},
+	}
+}
+
+func LessThan[S any, T constraints.Ordered](
+	accessor Accessor[S, T],
+	value T,
+) Matcher[S] {
+	return &between[S, T]{
+		accessor: accessor,
+		upper:    bound[T]{inclusive: false, set: true, value: value},
+	}
+}
+
+func LessThanOrEquals[S any, T constraints.Ordered](
+	accessor Accessor[S, T],
+	value T,
+) Matcher[S] {
+	return &between[S, T]{
+		accessor: accessor,
+		upper:    bound[T]{inclusive: true, set: true, value: value},
+	}
+}
+
+// Between matches records whose accessor returns a value from from to to,
+// both included.
+func Between[S any, T constraints.Ordered](
+	accessor Accessor[S, T],
+	from T,
+	to T,
+) Matcher[S] {
+	return &between[S, T]{
+		accessor: accessor,
+		lower:    bound[T]{inclusive: true, set: true, value: from},
+		upper:    bound[T]{inclusive: true, set: true, value: to},
+	}
+}
+
+type hasPrefix[S any] struct {
+	accessor Accessor[S, string]
+	prefix   string
+}
+
+func (matcher *hasPrefix[S]) Match(s S) bool {
+	return strings.HasPrefix(matcher.accessor.Get(s), matcher.prefix)
+}
+
+func (matcher *hasPrefix[S]) accessorName() string {
+	return matcher.accessor.Name()
+}
+
+// bounds lets an ordered index start at the prefix. Every value with the
+// prefix sorts after it, and the values past the last one are filtered out
+// when the records are read.
+func (matcher *hasPrefix[S]) bounds() (lower, upper bound[string]) {
+	return bound[string]{inclusive: true, set: true, value: matcher.prefix},
+		bound[string]{}
+}
+
+func HasPrefix[S any](
+	accessor Accessor[S, string],
+	prefix string,
+) Matcher[S] {
+	return &hasPrefix[S]{accessor, prefix}
+}
+
+type contains[S any] struct {
+	accessor Accessor[S, string]
+	substr   string
+}
+
+func (matcher *contains[S]) Match(s S) bool {
+	return strings.Contains(matcher.accessor.Get(s), matcher.substr)
+}
+
+func (matcher *contains[S]) accessorName() string {
+	return matcher.accessor.Name()
+}
+
+func Contains[S any](
+	accessor Accessor[S, string],
+	substr string,
+) Matcher[S] {
+	return &contains[S]{accessor, substr}
+}
+
+type matchRegexp[S any] struct {
+	accessor Accessor[S, string]
+	regexp   *regexp.Regexp
+}
+
+func (matcher *matchRegexp[S]) Match(s S) bool {
+	return matcher.regexp.MatchString(matcher.accessor.Get(s))
+}
+
+func (matcher *matchRegexp[S]) accessorName() string {
+	return matcher.accessor.Name()
+}
+
+func MatchRegexp[S any](
+	accessor Accessor[S, string],
+	regexp *regexp.Regexp,
+) Matcher[S] {
+	return &matchRegexp[S]{accessor, regexp}
+}
+
+type before[S any] struct {
+	accessor Accessor[S, time.Time]
+	value    time.Time
+}
+
+func (matcher *before[S]) Match(s S) bool {
+	return matcher.accessor.Get(s).Before(matcher.value)
+}
+
+func (matcher *before[S]) accessorName() string {
+	return matcher.accessor.Name()
+}
+
+func Before[S any](
+	accessor Accessor[S, time.Time],
+	value time.Time,
+) Matcher[S] {
+	return &before[S]{accessor, value}
+}
+
+type after[S any] struct {
+	accessor Accessor[S, time.Time]
+	value    time.Time
+}
+
+func (matcher *after[S]) Match(s S) bool {
+	return matcher.accessor.Get(s).After(matcher.value)
+}
+
+func (matcher *after[S]) accessorName() string {
+	return matcher.accessor.Name()
+}
+
+func After[S any](
+	accessor Accessor[S, time.Time],
+	value time.Time,
+) Matcher[S] {
+	return &after[S]{accessor, value}
+}
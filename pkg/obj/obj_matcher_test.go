@@ -0,0 +1,189 @@
+package obj
+
+import (
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestMatchers(t *testing.T) {
+	type test struct {
+		name    string
+		matcher Matcher[*TestSpec]
+		expect  bool
+	}
+
+	var (
+		now  = GetTestNow()
+		spec = &TestSpec{Id: 5, Foo: "foo", Bar: "", UpdatedAt: now}
+	)
+
+	tests := []test{
+		{
+			name:    "with not equals",
+			matcher: NotEquals[*TestSpec, string](FooAccessor, "fiz"),
+			expect:  true,
+		},
+		{
+			name:    "with not equals matching value",
+			matcher: NotEquals[*TestSpec, string](FooAccessor, "foo"),
+			expect:  false,
+		},
+		{
+			name:    "with greater than",
+			matcher: GreaterThan[*TestSpec, int](IdAccessor, 4),
+			expect:  true,
+		},
+		{
+			name:    "with greater than equal value",
+			matcher: GreaterThan[*TestSpec, int](IdAccessor, 5),
+			expect:  false,
+		},
+		{
+			name:    "with greater than or equals",
+			matcher: GreaterThanOrEquals[*TestSpec, int](IdAccessor, 5),
+			expect:  true,
+		},
+		{
+			name:    "with less than",
+			matcher: LessThan[*TestSpec, int](IdAccessor, 6),
+			expect:  true,
+		},
+		{
+			name:    "with less than equal value",
+			matcher: LessThan[*TestSpec, int](IdAccessor, 5),
+			expect:  false,
+		},
+		{
+			name:    "with less than or equals",
+			matcher: LessThanOrEquals[*TestSpec, int](IdAccessor, 5),
+			expect:  true,
+		},
+		{
+			name:    "with between",
+			matcher: Between[*TestSpec, int](IdAccessor, 5, 7),
+			expect:  true,
+		},
+		{
+			name:    "with between below",
+			matcher: Between[*TestSpec, int](IdAccessor, 6, 7),
+			expect:  false,
+		},
+		{
+			name:    "with between above",
+			matcher: Between[*TestSpec, string](FooAccessor, "bar", "fiz"),
+			expect:  false,
+		},
+		{
+			name:    "with in",
+			matcher: In[*TestSpec, string](FooAccessor, "fiz", "foo"),
+			expect:  true,
+		},
+		{
+			name:    "with in missing value",
+			matcher: In[*TestSpec, string](FooAccessor, "fiz", "buz"),
+			expect:  false,
+		},
+		{
+			name:    "with not in",
+			matcher: NotIn[*TestSpec, string](FooAccessor, "fiz", "buz"),
+			expect:  true,
+		},
+		{
+			name:    "with not",
+			matcher: Not(FooEquals("foo")),
+			expect:  false,
+		},
+		{
+			name:    "with is zero",
+			matcher: IsZero[*TestSpec, string](BarAccessor),
+			expect:  true,
+		},
+		{
+			name:    "with is zero set value",
+			matcher: IsZero[*TestSpec, time.Time](UpdatedAtAccessor),
+			expect:  false,
+		},
+		{
+			name:    "with has prefix",
+			matcher: HasPrefix[*TestSpec](FooAccessor, "fo"),
+			expect:  true,
+		},
+		{
+			name:    "with has prefix not matching",
+			matcher: HasPrefix[*TestSpec](FooAccessor, "oo"),
+			expect:  false,
+		},
+		{
+			name:    "with contains",
+			matcher: Contains[*TestSpec](FooAccessor, "oo"),
+			expect:  true,
+		},
+		{
+			name:    "with contains not matching",
+			matcher: Contains[*TestSpec](FooAccessor, "fi"),
+			expect:  false,
+		},
+		{
+			name: "with regexp",
+			matcher: MatchRegexp[*TestSpec](
+				FooAccessor,
+				regexp.MustCompile(`^f.o$`),
+			),
+			expect: true,
+		},
+		{
+			name: "with regexp not matching",
+			matcher: MatchRegexp[*TestSpec](
+				FooAccessor,
+				regexp.MustCompile(`^b`),
+			),
+			expect: false,
+		},
+		{
+			name:    "with before",
+			matcher: Before[*TestSpec](UpdatedAtAccessor, now.Add(time.Second)),
+			expect:  true,
+		},
+		{
+			name:    "with before same time",
+			matcher: Before[*TestSpec](UpdatedAtAccessor, now),
+			expect:  false,
+		},
+		{
+			name:    "with after",
+			matcher: After[*TestSpec](UpdatedAtAccessor, now.Add(-time.Second)),
+			expect:  true,
+		},
+		{
+			name:    "with after same time",
+			matcher: After[*TestSpec](UpdatedAtAccessor, now),
+			expect:  false,
+		},
+		{
+			name: "with match func",
+			matcher: MatchFunc[*TestSpec](func(s *TestSpec) bool {
+				return s.Id%5 == 0
+			}),
+			expect: true,
+		},
+		{
+			name: "with composition",
+			matcher: And(
+				Or(FooEquals("fiz"), HasPrefix[*TestSpec](FooAccessor, "f")),
+				Not(In[*TestSpec, int](IdAccessor, 1, 2, 3)),
+			),
+			expect: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.matcher.Match(spec)
+
+			if got != tc.expect {
+				t.Errorf("expected match to be %t but got %t", tc.expect, got)
+			}
+		})
+	}
+}
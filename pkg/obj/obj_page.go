@@ -0,0 +1,380 @@
+package obj
+
+import (
+	"container/heap"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// Cursor marks the last record of a page so that the next page can start
+// right after it. It carries the values the order is by and the id of that
+// record, so only orders made of OrderBy and OrderByDesc can be paged with
+// cursors. It is opaque to callers and only valid with the same order.
+type Cursor string
+
+// page is where a select starts and how many records it returns. cursor is set
+// when the caller wants the cursor of the next page.
+type page struct {
+	after  Cursor
+	cursor bool
+	limit  int
+	offset int
+}
+
+func (stg *storage[I, S]) selectPage(
+	ctx context.Context,
+	filters Matcher[S],
+	orderBys []Lesser[S],
+	page page,
+	fields []string,
+) (results []S, next Cursor, err error) {
+	stg.lock.Lock()
+	defer stg.lock.Unlock()
+
+	return stg.page(ctx, filters, orderBys, page, fields)
+}
+
+// page selects one page of the records matching filters. Paged results are
+// ordered by orderBys and then by the string form of their id so that every
+// record has a single place in the order and a cursor can pick up after it.
+// When there is a limit only the best offset plus limit records are kept
+// while the data file is read. When fields are given only those, the id and
+// the fields the filters and orderBys read are decoded, if the marshaller
+// supports it.
+func (stg *storage[I, S]) page(
+	ctx context.Context,
+	filters Matcher[S],
+	orderBys []Lesser[S],
+	page page,
+	fields []string,
+) (results []S, next Cursor, err error) {
+	var (
+		after   S
+		keyers  []cursorKeyer[S]
+		ch      = make(chan specMsg[S], stg.concurrency)
+		compare = stg.compare(orderBys)
+		errCh   = make(chan error, stg.concurrency)
+		top     *topK[S]
+	)
+
+	if page.limit < 0 {
+		return nil, "", fmt.Errorf(
+			"%w, limit must be at least 0 but got %d",
+			illegalArgumentError,
+			page.limit,
+		)
+	}
+
+	if page.offset < 0 {
+		return nil, "", fmt.Errorf(
+			"%w, offset must be at least 0 but got %d",
+			illegalArgumentError,
+			page.offset,
+		)
+	}
+
+	if page.cursor || page.after != "" {
+		if keyers, err = cursorKeyers(orderBys); err != nil {
+			return nil, "", err
+		}
+	}
+
+	filters = stg.live(filters)
+
+	opts := []readControllerOpt{}
+	if len(fields) > 0 {
+		if projected, ok := stg.projection(filters, orderBys, fields); ok {
+			opts = append(opts, optFields{projected})
+		}
+	}
+
+	if page.after != "" {
+		if after, err = stg.decodeCursor(page.after, keyers); err != nil {
+			return nil, "", err
+		}
+
+		filters = And[S](filters, MatchFunc[S](func(s S) bool {
+			return compare(s, after) > 0
+		}))
+	}
+
+	controller := stg.newReadController(
+		ctx,
+		ch,
+		errCh,
+		filters,
+		opNoop,
+		opts...,
+	)
+
+	go controller.Start()
+
+	if page.limit > 0 {
+		top = &topK[S]{
+			compare: compare,
+			k:       page.offset + page.limit,
+			specs:   make([]S, 0, page.offset+page.limit),
+		}
+		err = stg.gatherEach(ch, errCh, func(msg specMsg[S]) {
+			top.add(msg.spec)
+		})
+		results = top.specs
+	} else {
+		results = make([]S, 0, 100)
+		err = stg.gatherEach(ch, errCh, func(msg specMsg[S]) {
+			results = append(results, msg.spec)
+		})
+	}
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	slices.SortFunc(results, func(a, b S) bool {
+		return compare(a, b) < 0
+	})
+
+	if page.offset >= len(results) {
+		return []S{}, "", nil
+	}
+	results = results[page.offset:]
+
+	if !page.cursor || page.limit == 0 || len(results) < page.limit {
+		return results, "", nil
+	}
+
+	if next, err = stg.encodeCursor(
+		results[len(results)-1],
+		keyers,
+	); err != nil {
+		return nil, "", err
+	}
+
+	return results, next, nil
+}
+
+// fieldReader is implemented by the filters and orders that read a single
+// field of a record.
+type fieldReader interface {
+	accessorName() string
+}
+
+// projection adds to fields the id and every field that filters and orderBys
+// read, since they are applied to the partly decoded records. It is not ok
+// when one of them reads fields it does not name, a MatchFunc for instance,
+// and the records must then be decoded whole.
+func (stg *storage[I, S]) projection(
+	filters Matcher[S],
+	orderBys []Lesser[S],
+	fields []string,
+) (projected []string, ok bool) {
+	projected = append([]string{stg.idAccessor.Name()}, fields...)
+
+	if stg.deletedAtAccessor != nil {
+		projected = append(projected, stg.deletedAtAccessor.Name())
+	}
+
+	if stg.expiresAtAccessor != nil {
+		projected = append(projected, stg.expiresAtAccessor.Name())
+	}
+
+	if projected, ok = matcherFields(filters, projected); !ok {
+		return nil, false
+	}
+
+	for _, orderBy := range orderBys {
+		reader, ok := orderBy.(fieldReader)
+		if !ok {
+			return nil, false
+		}
+		projected = append(projected, reader.accessorName())
+	}
+
+	slices.Sort(projected)
+	return slices.Compact(projected), true
+}
+
+// matcherFields appends the fields that matcher reads to fields.
+func matcherFields[S any](
+	matcher Matcher[S],
+	fields []string,
+) (_ []string, ok bool) {
+	var matchers []Matcher[S]
+
+	switch matcher := matcher.(type) {
+	case nil, *noopMatcher[S]:
+		return fields, true
+	case *and[S]:
+		matchers = matcher.matchers
+	case *or[S]:
+		matchers = matcher.matchers
+	case *not[S]:
+		matchers = []Matcher[S]{matcher.matcher}
+	case *unfiltered[S]:
+		matchers = []Matcher[S]{matcher.matcher}
+	case *withDeleted[S]:
+		matchers = []Matcher[S]{matcher.matcher}
+	case fieldReader:
+		return append(fields, matcher.accessorName()), true
+	default:
+		return nil, false
+	}
+
+	for _, m := range matchers {
+		if fields, ok = matcherFields(m, fields); !ok {
+			return nil, false
+		}
+	}
+
+	return fields, true
+}
+
+func (stg *storage[I, S]) compare(orderBys []Lesser[S]) func(a, b S) int {
+	return func(a, b S) int {
+		for _, lesser := range orderBys {
+			if res := lesser.Less(a, b); res != 0 {
+				return res
+			}
+		}
+
+		return strings.Compare(
+			fmt.Sprint(stg.idAccessor.Get(a)),
+			fmt.Sprint(stg.idAccessor.Get(b)),
+		)
+	}
+}
+
+// cursorKeyer is implemented by the orders whose value a cursor can carry.
+type cursorKeyer[S any] interface {
+	cursorKey(s S) any
+	setCursorKey(s S, data json.RawMessage) error
+}
+
+// cursorKeyers reports the orderBys as cursorKeyers. It fails when one of
+// them compares records by something it does not name, as a cursor would
+// then have to carry the whole record.
+func cursorKeyers[S any](
+	orderBys []Lesser[S],
+) (keyers []cursorKeyer[S], err error) {
+	keyers = make([]cursorKeyer[S], 0, len(orderBys))
+
+	for _, orderBy := range orderBys {
+		keyer, ok := orderBy.(cursorKeyer[S])
+		if !ok {
+			return nil, fmt.Errorf(
+				"%w, cursors need an order of OrderBy and OrderByDesc",
+				illegalArgumentError,
+			)
+		}
+		keyers = append(keyers, keyer)
+	}
+
+	return keyers, nil
+}
+
+// decodeCursor rebuilds a record holding only the values that cursor carries,
+// which are all that comparing it in the order of keyers reads.
+func (stg *storage[I, S]) decodeCursor(
+	cursor Cursor,
+	keyers []cursorKeyer[S],
+) (s S, err error) {
+	var (
+		data     []byte
+		encoding = base64.RawURLEncoding
+		id       I
+		keys     []json.RawMessage
+	)
+
+	if data, err = encoding.DecodeString(string(cursor)); err != nil {
+		return s, fmt.Errorf("%w, invalid cursor", illegalArgumentError)
+	}
+
+	if err = json.Unmarshal(data, &keys); err != nil ||
+		len(keys) != len(keyers)+1 {
+		return s, fmt.Errorf("%w, invalid cursor", illegalArgumentError)
+	}
+
+	s = stg.factory.New()
+
+	for i, keyer := range keyers {
+		if err = keyer.setCursorKey(s, keys[i]); err != nil {
+			return s, fmt.Errorf("%w, invalid cursor", illegalArgumentError)
+		}
+	}
+
+	if err = json.Unmarshal(keys[len(keyers)], &id); err != nil {
+		return s, fmt.Errorf("%w, invalid cursor", illegalArgumentError)
+	}
+	stg.idAccessor.Set(s, id)
+
+	return s, nil
+}
+
+// encodeCursor encodes the values of s that keyers compare, followed by its
+// id.
+func (stg *storage[I, S]) encodeCursor(
+	s S,
+	keyers []cursorKeyer[S],
+) (cursor Cursor, err error) {
+	var data []byte
+
+	keys := make([]any, 0, len(keyers)+1)
+	for _, keyer := range keyers {
+		keys = append(keys, keyer.cursorKey(s))
+	}
+	keys = append(keys, stg.idAccessor.Get(s))
+
+	if data, err = json.Marshal(keys); err != nil {
+		return "", err
+	}
+
+	return Cursor(base64.RawURLEncoding.EncodeToString(data)), nil
+}
+
+// topK keeps the k first records of an order. It is a heap with the last of
+// them on top so that it can be replaced when a record earlier in the order
+// comes along.
+type topK[S any] struct {
+	compare func(a, b S) int
+	k       int
+	specs   []S
+}
+
+func (top *topK[S]) add(s S) {
+	if len(top.specs) < top.k {
+		heap.Push(top, s)
+		return
+	}
+
+	if top.compare(s, top.specs[0]) < 0 {
+		top.specs[0] = s
+		heap.Fix(top, 0)
+	}
+}
+
+func (top *topK[S]) Len() int {
+	return len(top.specs)
+}
+
+func (top *topK[S]) Less(i, j int) bool {
+	return top.compare(top.specs[i], top.specs[j]) > 0
+}
+
+func (top *topK[S]) Pop() any {
+	last := top.specs[len(top.specs)-1]
+	top.specs = top.specs[:len(top.specs)-1]
+	return last
+}
+
+func (top *topK[S]) Push(s any) {
+	top.specs = append(top.specs, s.(S))
+}
+
+func (top *topK[S]) Swap(i, j int) {
+	top.specs[i], top.specs[j] = top.specs[j], top.specs[i]
+}
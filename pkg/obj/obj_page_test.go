@@ -0,0 +1,228 @@
+package obj
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+// lesserFunc orders records by a function that no cursor can carry.
+type lesserFunc func(i, j *TestSpec) int
+
+func (less lesserFunc) Less(i, j *TestSpec) int {
+	return less(i, j)
+}
+
+func TestPage(t *testing.T) {
+	type test struct {
+		name         string
+		filters      Matcher[*TestSpec]
+		orderBys     []Lesser[*TestSpec]
+		limit        int
+		offset       int
+		pages        int
+		after        Cursor
+		expectError  string
+		expect       []*TestSpec
+		expectNext   bool
+		expectCursor string
+	}
+
+	tests := []test{
+		{
+			name:     "with limit",
+			orderBys: []Lesser[*TestSpec]{OrderByFoo},
+			limit:    2,
+			expect: []*TestSpec{
+				{Id: 3, Foo: "a", Bar: "bar"},
+				{Id: 1, Foo: "b", Bar: "bar"},
+			},
+			expectNext:   true,
+			expectCursor: `["b",1]`,
+		},
+		{
+			name:     "with offset",
+			orderBys: []Lesser[*TestSpec]{OrderByFoo},
+			limit:    2,
+			offset:   1,
+			expect: []*TestSpec{
+				{Id: 1, Foo: "b", Bar: "bar"},
+				{Id: 4, Foo: "b", Bar: "buz"},
+			},
+			expectNext: true,
+		},
+		{
+			name:     "with offset without limit",
+			orderBys: []Lesser[*TestSpec]{OrderByFooDesc},
+			offset:   3,
+			expect: []*TestSpec{
+				{Id: 4, Foo: "b", Bar: "buz"},
+				{Id: 3, Foo: "a", Bar: "bar"},
+			},
+		},
+		{
+			name:     "with offset past the end",
+			orderBys: []Lesser[*TestSpec]{OrderByFoo},
+			limit:    2,
+			offset:   5,
+			expect:   []*TestSpec{},
+		},
+		{
+			name:     "with second page",
+			orderBys: []Lesser[*TestSpec]{OrderByFoo},
+			limit:    2,
+			pages:    2,
+			expect: []*TestSpec{
+				{Id: 4, Foo: "b", Bar: "buz"},
+				{Id: 2, Foo: "c", Bar: "buz"},
+			},
+			expectNext: true,
+		},
+		{
+			name:     "with last page",
+			orderBys: []Lesser[*TestSpec]{OrderByFoo},
+			limit:    2,
+			pages:    3,
+			expect: []*TestSpec{
+				{Id: 5, Foo: "d", Bar: "bar"},
+			},
+		},
+		{
+			name:     "with filters",
+			filters:  BarEquals("buz"),
+			orderBys: []Lesser[*TestSpec]{OrderByFooDesc},
+			limit:    1,
+			pages:    2,
+			expect: []*TestSpec{
+				{Id: 4, Foo: "b", Bar: "buz"},
+			},
+			expectNext:   true,
+			expectCursor: `["b",4]`,
+		},
+		{
+			name: "with custom order",
+			orderBys: []Lesser[*TestSpec]{
+				lesserFunc(func(i, j *TestSpec) int {
+					return strings.Compare(i.Bar+i.Foo, j.Bar+j.Foo)
+				}),
+			},
+			limit: 2,
+			expectError: "illegal argument error, cursors need an order of " +
+				"OrderBy and OrderByDesc",
+		},
+		{
+			name:  "without order",
+			limit: 3,
+			expect: []*TestSpec{
+				{Id: 1, Foo: "b", Bar: "bar"},
+				{Id: 2, Foo: "c", Bar: "buz"},
+				{Id: 3, Foo: "a", Bar: "bar"},
+			},
+			expectNext:   true,
+			expectCursor: `[3]`,
+		},
+		{
+			name:        "with invalid cursor",
+			limit:       2,
+			after:       "!",
+			expectError: "illegal argument error, invalid cursor",
+		},
+		{
+			name:     "with cursor of another order",
+			orderBys: []Lesser[*TestSpec]{OrderByFoo},
+			limit:    2,
+			after: Cursor(
+				base64.RawURLEncoding.EncodeToString([]byte(`[1]`)),
+			),
+			expectError: "illegal argument error, invalid cursor",
+		},
+		{
+			name:        "with invalid limit",
+			limit:       -1,
+			expectError: "illegal argument error, limit must be at least 0 but got -1",
+		},
+		{
+			name:        "with invalid offset",
+			offset:      -1,
+			expectError: "illegal argument error, offset must be at least 0 but got -1",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				err     error
+				next    Cursor
+				results []*TestSpec
+			)
+
+			util := &testUtil{
+				test: t,
+				lines: []string{
+					`{"id":1,"foo":"b","bar":"bar"}`,
+					`{"id":2,"foo":"c","bar":"buz"}`,
+					`{"id":3,"foo":"a","bar":"bar"}`,
+					`{"id":4,"foo":"b","bar":"buz"}`,
+					`{"id":5,"foo":"d","bar":"bar"}`,
+				},
+				expectError: tc.expectError,
+				expect:      tc.expect,
+			}
+
+			err = util.setup()
+			defer util.teardown()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.filters == nil {
+				tc.filters = Noop[*TestSpec]()
+			}
+
+			next = tc.after
+			for i := 0; i < tc.pages || i == 0; i++ {
+				results, next, err = util.stg.NewSelectBuilder().
+					Where(tc.filters).
+					OrderBy(tc.orderBys...).
+					Limit(tc.limit).
+					Offset(tc.offset).
+					After(next).
+					Page()
+				if err != nil {
+					break
+				}
+			}
+
+			if done := util.handleExpectError(err); done {
+				return
+			}
+
+			util.expectSpecs(results...)
+
+			if (next != "") != tc.expectNext {
+				t.Errorf(
+					"expected a next cursor to be %t but got %q",
+					tc.expectNext,
+					next,
+				)
+			}
+
+			if tc.expectCursor == "" {
+				return
+			}
+
+			got, err := base64.RawURLEncoding.DecodeString(string(next))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.expectCursor {
+				t.Errorf(
+					"expected cursor %s but got %s",
+					tc.expectCursor,
+					string(got),
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package obj
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/yo3jones/stg/pkg/fstln"
+	"github.com/yo3jones/stg/pkg/objbinlog"
+)
+
+// Recover replays the bin log for this object type against the data file.
+// Every id found in the log is brought to the image of its last committed
+// operation, or to the image it had before an aborted or incomplete
+// transaction touched it: missing records are inserted, stale or duplicate
+// lines left by an interrupted write are rewritten or blanked, and torn lines,
+// the start of an image the log holds, are blanked so that the logged image
+// can take their place. Any other line that can no longer be unmarshalled is
+// not the log's to repair; Recover then changes nothing and fails with
+// ErrIllegalState naming its offset. Indexes are rebuilt afterwards.
+func (stg *storage[I, S]) Recover() (recovered int, err error) {
+	return stg.RecoverContext(context.Background())
+}
+
+// RecoverContext is Recover that stops between ids once ctx is done. The ids
+// recovered so far stay recovered, so it can simply be run again.
+func (stg *storage[I, S]) RecoverContext(
+	ctx context.Context,
+) (recovered int, err error) {
+	stg.lock.Lock()
+	defer stg.lock.Unlock()
+
+	var (
+		current  map[I][]recoverLine
+		expected map[I][]byte
+		ids      []I
+		images   [][]byte
+		invalid  []recoverLine
+		ok       bool
+	)
+
+	if expected, ids, images, err = stg.expectedImages(); err != nil {
+		return 0, err
+	}
+
+	if current, invalid, err = stg.currentImages(ctx); err != nil {
+		return 0, err
+	}
+
+	for _, line := range invalid {
+		if !torn(line.raw, images) {
+			return 0, fmt.Errorf(
+				"%w, line at offset %d cannot be unmarshalled and is not in "+
+					"the bin log",
+				ErrIllegalState,
+				line.pos.Offset,
+			)
+		}
+	}
+
+	for _, line := range invalid {
+		if err = stg.stg.DeleteContext(ctx, line.pos); err != nil {
+			return recovered, stg.endRecover(err)
+		}
+		recovered++
+	}
+
+	for _, id := range ids {
+		if err = ctx.Err(); err != nil {
+			return recovered, stg.endRecover(err)
+		}
+
+		ok, err = stg.recoverImage(expected[id], current[id])
+		if err != nil {
+			return recovered, stg.endRecover(err)
+		}
+
+		if ok {
+			recovered++
+		}
+	}
+
+	return recovered, stg.buildIndexes()
+}
+
+// endRecover rebuilds the indexes after Recover stopped part way since the
+// lines it already rewrote moved.
+func (stg *storage[I, S]) endRecover(err error) error {
+	stg.buildIndexes()
+	return err
+}
+
+type recoverLine struct {
+	pos fstln.Position
+	raw []byte
+}
+
+// torn reports whether raw, less the padding of a blanked tail, is the start
+// of one of the logged images, as an interrupted write leaves it.
+func torn(raw []byte, images [][]byte) bool {
+	raw = bytes.TrimRight(raw, " ")
+
+	for _, image := range images {
+		if bytes.HasPrefix(image, raw) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// expectedImages returns the image each id in the bin log is to be recovered
+// to, the ids in the order they were first logged and every image logged.
+func (stg *storage[I, S]) expectedImages() (
+	expected map[I][]byte,
+	ids []I,
+	images [][]byte,
+	err error,
+) {
+	expected = map[I][]byte{}
+	ids = make([]I, 0, 100)
+	images = make([][]byte, 0, 100)
+
+	err = stg.binLogStg.Scan(stg.objType, func(entry objbinlog.Entry) error {
+		var (
+			err   error
+			image = entry.To
+			s     = stg.factory.New()
+		)
+
+		if image == nil {
+			image = entry.From
+		}
+
+		for _, logged := range [][]byte{entry.From, entry.To} {
+			if logged != nil {
+				images = append(images, logged)
+			}
+		}
+
+		if err = stg.marshalUnmarshaller.Unmarshal(image, s); err != nil {
+			return err
+		}
+
+		id := stg.idAccessor.Get(s)
+		_, exists := expected[id]
+		if !exists {
+			ids = append(ids, id)
+		}
+
+		if entry.Status == objbinlog.StatusCommitted {
+			expected[id] = entry.To
+		} else if !exists {
+			expected[id] = entry.From
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return expected, ids, images, nil
+}
+
+func (stg *storage[I, S]) currentImages(ctx context.Context) (
+	current map[I][]recoverLine,
+	invalid []recoverLine,
+	err error,
+) {
+	var (
+		data       []byte
+		pos        fstln.Position
+		controller = stg.newReadController(ctx, nil, nil, Noop[S](), opNoop)
+	)
+
+	current = map[I][]recoverLine{}
+	invalid = make([]recoverLine, 0)
+
+	if err = stg.stg.ResetScanContext(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	for {
+		if pos, data, err = controller.read(); err != nil && err != io.EOF {
+			return nil, nil, err
+		} else if pos == fstln.EOF {
+			break
+		}
+
+		s := stg.factory.New()
+		if err = stg.marshalUnmarshaller.Unmarshal(data, s); err != nil {
+			invalid = append(invalid, recoverLine{pos, data})
+			continue
+		}
+
+		id := stg.idAccessor.Get(s)
+		current[id] = append(current[id], recoverLine{pos, data})
+	}
+
+	return current, invalid, nil
+}
+
+func (stg *storage[I, S]) recoverImage(
+	expected []byte,
+	lines []recoverLine,
+) (recovered bool, err error) {
+	var (
+		keep  = -1
+		same  bool
+		stale []recoverLine
+	)
+
+	if expected != nil {
+		for i, line := range lines {
+			if same, err = stg.sameImage(expected, line.raw); err != nil {
+				return false, err
+			} else if same {
+				keep = i
+				break
+			}
+		}
+	}
+
+	for i, line := range lines {
+		if i != keep {
+			stale = append(stale, line)
+		}
+	}
+
+	if expected != nil && keep < 0 && len(stale) > 0 {
+		if _, err = stg.stg.Update(stale[0].pos, expected); err != nil {
+			return false, err
+		}
+		stale = stale[1:]
+		recovered = true
+	} else if expected != nil && keep < 0 {
+		if _, err = stg.stg.Insert(expected); err != nil {
+			return false, err
+		}
+		recovered = true
+	}
+
+	for _, line := range stale {
+		if err = stg.stg.Delete(line.pos); err != nil {
+			return recovered, err
+		}
+		recovered = true
+	}
+
+	return recovered, nil
+}
+
+func (stg *storage[I, S]) sameImage(a, b []byte) (same bool, err error) {
+	if bytes.Equal(a, b) {
+		return true, nil
+	}
+
+	var (
+		aData []byte
+		bData []byte
+		aSpec = stg.factory.New()
+		bSpec = stg.factory.New()
+	)
+
+	if err = stg.marshalUnmarshaller.Unmarshal(a, aSpec); err != nil {
+		return false, err
+	}
+
+	if err = stg.marshalUnmarshaller.Unmarshal(b, bSpec); err != nil {
+		return false, err
+	}
+
+	if aData, err = stg.marshalUnmarshaller.Marshal(aSpec); err != nil {
+		return false, err
+	}
+
+	if bData, err = stg.marshalUnmarshaller.Marshal(bSpec); err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(aData, bData), nil
+}
@@ -0,0 +1,278 @@
+package obj
+
+import "testing"
+
+func TestRecover(t *testing.T) {
+	type test struct {
+		name        string
+		lines       []string
+		binLogLines []string
+		mockErr     *mockErr
+		expectError string
+		expectCount int
+		expectLines [][]string
+	}
+
+	tests := []test{
+		{
+			name: "with consistent data",
+			lines: []string{
+				`{"id":1,"foo":"foo","bar":"bar"}`,
+				`{"id":2,"foo":"fiz","bar":"bar"}`,
+			},
+			binLogLines: []string{
+				`{"transaction":200,"type":"test","id":2,"ts":"2022-07-06T16:18:00-04:00","from":null,"to":{"id":2,"foo":"fiz","bar":"bar"}}`,
+			},
+			expectCount: 0,
+			expectLines: [][]string{
+				{
+					`{"id":1,"foo":"foo","bar":"bar"}`,
+					`{"id":2,"foo":"fiz","bar":"bar"}`,
+				},
+			},
+		},
+		{
+			name: "with equivalent image",
+			lines: []string{
+				`{"id":1,"foo":"foo","bar":"bar"}`,
+			},
+			binLogLines: []string{
+				`{"transaction":200,"type":"test","id":1,"ts":"2022-07-06T16:18:00-04:00","from":null,"to":{"bar":"bar","foo":"foo","id":1}}`,
+			},
+			expectCount: 0,
+			expectLines: [][]string{
+				{
+					`{"id":1,"foo":"foo","bar":"bar"}`,
+				},
+			},
+		},
+		{
+			name: "with insert not applied",
+			lines: []string{
+				`{"id":1,"foo":"foo","bar":"bar"}`,
+			},
+			binLogLines: []string{
+				`{"transaction":200,"type":"test","id":2,"ts":"2022-07-06T16:18:00-04:00","from":null,"to":{"id":2,"foo":"fiz","bar":"bar"}}`,
+			},
+			expectCount: 1,
+			expectLines: [][]string{
+				{
+					`{"id":1,"foo":"foo","bar":"bar"}`,
+					`{"id":2,"foo":"fiz","bar":"bar"}`,
+				},
+			},
+		},
+		{
+			name: "with update not applied",
+			lines: []string{
+				`{"id":1,"foo":"foo","bar":"bar"}`,
+				`{"id":2,"foo":"fiz","bar":"bar"}`,
+			},
+			binLogLines: []string{
+				`{"transaction":200,"type":"test","id":1,"ts":"2022-07-06T16:18:00-04:00","from":{"id":1,"foo":"foo","bar":"bar"},"to":{"id":1,"foo":"FOO","bar":"bar"}}`,
+			},
+			expectCount: 1,
+			expectLines: [][]string{
+				{
+					`{"id":1,"foo":"FOO","bar":"bar"}`,
+					`{"id":2,"foo":"fiz","bar":"bar"}`,
+				},
+			},
+		},
+		{
+			name: "with out of place update partially applied",
+			lines: []string{
+				`{"id":1,"foo":"foo","bar":"bar"}`,
+				`{"id":2,"foo":"fiz","bar":"bar"}`,
+				`{"id":1,"foo":"foofoo","bar":"bar"}`,
+			},
+			binLogLines: []string{
+				`{"transaction":200,"type":"test","id":1,"ts":"2022-07-06T16:18:00-04:00","from":{"id":1,"foo":"foo","bar":"bar"},"to":{"id":1,"foo":"foofoo","bar":"bar"}}`,
+			},
+			expectCount: 1,
+			expectLines: [][]string{
+				{
+					`                                `,
+					`{"id":2,"foo":"fiz","bar":"bar"}`,
+					`{"id":1,"foo":"foofoo","bar":"bar"}`,
+				},
+			},
+		},
+		{
+			name: "with delete not applied",
+			lines: []string{
+				`{"id":1,"foo":"foo","bar":"bar"}`,
+				`{"id":2,"foo":"fiz","bar":"bar"}`,
+			},
+			binLogLines: []string{
+				`{"transaction":200,"type":"test","id":1,"ts":"2022-07-06T16:18:00-04:00","from":null,"to":{"id":1,"foo":"foo","bar":"bar"}}`,
+				`{"transaction":201,"type":"test","id":1,"ts":"2022-07-06T16:18:00-04:00","from":{"id":1,"foo":"foo","bar":"bar"},"to":null}`,
+			},
+			expectCount: 1,
+			expectLines: [][]string{
+				{
+					`                                `,
+					`{"id":2,"foo":"fiz","bar":"bar"}`,
+				},
+			},
+		},
+		{
+			name: "with aborted update applied",
+			lines: []string{
+				`{"id":1,"foo":"FOO","bar":"bar"}`,
+				`{"id":2,"foo":"fiz","bar":"bar"}`,
+			},
+			binLogLines: []string{
+				`{"transaction":200,"type":"test","op":"begin","ts":"2022-07-06T16:18:00-04:00"}`,
+				`{"transaction":200,"type":"test","id":1,"ts":"2022-07-06T16:18:00-04:00","from":{"id":1,"foo":"foo","bar":"bar"},"to":{"id":1,"foo":"FOO","bar":"bar"}}`,
+				`{"transaction":200,"type":"test","id":2,"ts":"2022-07-06T16:18:00-04:00","from":{"id":2,"foo":"fiz","bar":"bar"},"to":{"id":2,"foo":"FOO","bar":"bar"}}`,
+				`{"transaction":200,"type":"test","op":"abort","ts":"2022-07-06T16:18:00-04:00"}`,
+			},
+			expectCount: 1,
+			expectLines: [][]string{
+				{
+					`{"id":1,"foo":"foo","bar":"bar"}`,
+					`{"id":2,"foo":"fiz","bar":"bar"}`,
+				},
+			},
+		},
+		{
+			name: "with incomplete insert applied",
+			lines: []string{
+				`{"id":1,"foo":"foo","bar":"bar"}`,
+				`{"id":2,"foo":"fiz","bar":"bar"}`,
+			},
+			binLogLines: []string{
+				`{"transaction":200,"type":"test","op":"begin","ts":"2022-07-06T16:18:00-04:00"}`,
+				`{"transaction":200,"type":"test","id":2,"ts":"2022-07-06T16:18:00-04:00","from":null,"to":{"id":2,"foo":"fiz","bar":"bar"}}`,
+			},
+			expectCount: 1,
+			expectLines: [][]string{
+				{
+					`{"id":1,"foo":"foo","bar":"bar"}`,
+					`                                `,
+				},
+			},
+		},
+		{
+			name: "with torn line",
+			lines: []string{
+				`{"id":1,"foo":"foo","bar":"bar"}`,
+				`{"id":2,"foo":"fiz"`,
+			},
+			binLogLines: []string{
+				`{"transaction":200,"type":"test","id":2,"ts":"2022-07-06T16:18:00-04:00","from":null,"to":{"id":2,"foo":"fiz","bar":"bar"}}`,
+			},
+			expectCount: 2,
+			expectLines: [][]string{
+				{
+					`{"id":1,"foo":"foo","bar":"bar"}`,
+					`                   `,
+					`{"id":2,"foo":"fiz","bar":"bar"}`,
+				},
+			},
+		},
+		{
+			name: "with corrupt line not in the log",
+			lines: []string{
+				`{"id":1,"foo":"foo","bar":"bar"}`,
+				`{"id":3,"foo":"corrupt`,
+			},
+			binLogLines: []string{
+				`{"transaction":200,"type":"test","id":2,"ts":"2022-07-06T16:18:00-04:00","from":null,"to":{"id":2,"foo":"fiz","bar":"bar"}}`,
+			},
+			expectError: "illegal state error, line at offset 33 cannot be " +
+				"unmarshalled and is not in the bin log",
+			expectLines: [][]string{
+				{
+					`{"id":1,"foo":"foo","bar":"bar"}`,
+					`{"id":3,"foo":"corrupt`,
+				},
+			},
+		},
+		{
+			name: "with other object type",
+			lines: []string{
+				`{"id":1,"foo":"foo","bar":"bar"}`,
+			},
+			binLogLines: []string{
+				`{"transaction":200,"type":"other","id":1,"ts":"2022-07-06T16:18:00-04:00","from":{"id":1,"foo":"foo","bar":"bar"},"to":null}`,
+			},
+			expectCount: 0,
+			expectLines: [][]string{
+				{
+					`{"id":1,"foo":"foo","bar":"bar"}`,
+				},
+			},
+		},
+		{
+			name: "with scan error",
+			lines: []string{
+				`{"id":1,"foo":"foo","bar":"bar"}`,
+			},
+			mockErr: &mockErr{
+				mockErrType: mockErrTypeScan,
+				errorOn:     0,
+				msg:         "with scan error",
+			},
+			expectError: "with scan error",
+		},
+		{
+			name: "with reset scan error",
+			lines: []string{
+				`{"id":1,"foo":"foo","bar":"bar"}`,
+			},
+			mockErr: &mockErr{
+				mockErrType: mockErrTypeResetScan,
+				errorOn:     0,
+				msg:         "with reset scan error",
+			},
+			expectError: "with reset scan error",
+		},
+		{
+			name: "with insert error",
+			lines: []string{
+				`{"id":1,"foo":"foo","bar":"bar"}`,
+			},
+			binLogLines: []string{
+				`{"transaction":200,"type":"test","id":2,"ts":"2022-07-06T16:18:00-04:00","from":null,"to":{"id":2,"foo":"fiz","bar":"bar"}}`,
+			},
+			mockErr: &mockErr{
+				mockErrType: mockErrTypeInsert,
+				errorOn:     0,
+				msg:         "with insert error",
+			},
+			expectError: "with insert error",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+
+			util := &testUtil{
+				test:        t,
+				lines:       tc.lines,
+				binLogLines: tc.binLogLines,
+				mockError:   tc.mockErr,
+				expectError: tc.expectError,
+				expectCount: tc.expectCount,
+				expectLines: tc.expectLines,
+			}
+
+			err = util.setup()
+			defer util.teardown()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			util.expectRecover()
+
+			// Lines are left as they were when recovery fails.
+			if tc.expectError != "" && tc.expectLines != nil {
+				util.handleExpectLines()
+			}
+		})
+	}
+}
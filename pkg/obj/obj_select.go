@@ -1,17 +1,42 @@
 package obj
 
+import "context"
+
 func (stg *storage[I, S]) Select(
 	filters Matcher[S],
 	orderBys []Lesser[S],
+) (results []S, err error) {
+	return stg.SelectContext(context.Background(), filters, orderBys)
+}
+
+func (stg *storage[I, S]) SelectContext(
+	ctx context.Context,
+	filters Matcher[S],
+	orderBys []Lesser[S],
 ) (results []S, err error) {
 	stg.lock.Lock()
 	defer stg.lock.Unlock()
+
+	return stg.selectSpecs(ctx, filters, orderBys)
+}
+
+func (stg *storage[I, S]) selectSpecs(
+	ctx context.Context,
+	filters Matcher[S],
+	orderBys []Lesser[S],
+) (results []S, err error) {
 	var (
 		ch    = make(chan specMsg[S], stg.concurrency)
 		errCh = make(chan error, stg.concurrency)
 	)
 
-	controller := stg.newReadController(ch, errCh, filters, opNoop)
+	controller := stg.newReadController(
+		ctx,
+		ch,
+		errCh,
+		stg.live(filters),
+		opNoop,
+	)
 
 	go controller.Start()
 
@@ -29,13 +54,30 @@ func (stg *storage[I, S]) NewSelectBuilder() SelectBuilder[S] {
 type SelectBuilder[S any] interface {
 	Where(filters ...Matcher[S]) SelectBuilder[S]
 	OrderBy(orderBys ...Lesser[S]) SelectBuilder[S]
+	After(cursor Cursor) SelectBuilder[S]
+	Limit(limit int) SelectBuilder[S]
+	Offset(offset int) SelectBuilder[S]
+	Project(fields ...Field) SelectBuilder[S]
+	IncludeDeleted() SelectBuilder[S]
+	Iterate(ctx context.Context, fn func(s S) error) (err error)
+	Page() (results []S, next Cursor, err error)
+	PageContext(ctx context.Context) (results []S, next Cursor, err error)
 	Run() (results []S, err error)
+	RunContext(ctx context.Context) (results []S, err error)
 }
 
 type selectBuilder[S any] struct {
-	where    Matcher[S]
-	orderBys []Lesser[S]
-	stg      Storage[S]
+	fields         []string
+	includeDeleted bool
+	where          Matcher[S]
+	orderBys       []Lesser[S]
+	page           page
+	stg            operator[S]
+}
+
+// Field names a field of a record. Every Accessor is a Field.
+type Field interface {
+	Name() string
 }
 
 func (builder *selectBuilder[S]) Where(
@@ -52,6 +94,115 @@ func (builder *selectBuilder[S]) OrderBy(
 	return builder
 }
 
+// After starts the results right after the last record of the page cursor
+// was returned with.
+func (builder *selectBuilder[S]) After(cursor Cursor) SelectBuilder[S] {
+	builder.page.after = cursor
+	return builder
+}
+
+func (builder *selectBuilder[S]) Limit(limit int) SelectBuilder[S] {
+	builder.page.limit = limit
+	return builder
+}
+
+func (builder *selectBuilder[S]) Offset(offset int) SelectBuilder[S] {
+	builder.page.offset = offset
+	return builder
+}
+
+// Project decodes only the given fields of each record, plus its id and the
+// fields the filters and the order read, when the storage's marshaller is a
+// stg.FieldUnmarshaller; every other field is left at its zero value. Records
+// are decoded whole when a filter or order does not name the fields it reads,
+// as with MatchFunc.
+func (builder *selectBuilder[S]) Project(fields ...Field) SelectBuilder[S] {
+	builder.fields = make([]string, 0, len(fields))
+	for _, field := range fields {
+		builder.fields = append(builder.fields, field.Name())
+	}
+	return builder
+}
+
+// IncludeDeleted also selects the records that were soft deleted, see
+// OptDeletedAt.
+func (builder *selectBuilder[S]) IncludeDeleted() SelectBuilder[S] {
+	builder.includeDeleted = true
+	return builder
+}
+
+// Iterate streams the records matching the filters to fn as they are read.
+// Records are not ordered or paged and fn must not call the storage, see
+// Storage.Iterate.
+func (builder *selectBuilder[S]) Iterate(
+	ctx context.Context,
+	fn func(s S) error,
+) (err error) {
+	return builder.stg.Iterate(ctx, builder.filters(), fn)
+}
+
+// Page runs the select and also returns the cursor of the page's last record
+// when the page is full, or an empty cursor when there are no more records.
+// It fails when the order cannot be carried by a cursor, see Cursor.
+func (builder *selectBuilder[S]) Page() (
+	results []S,
+	next Cursor,
+	err error,
+) {
+	return builder.PageContext(context.Background())
+}
+
+func (builder *selectBuilder[S]) PageContext(ctx context.Context) (
+	results []S,
+	next Cursor,
+	err error,
+) {
+	page := builder.page
+	page.cursor = true
+
+	return builder.stg.selectPage(
+		ctx,
+		builder.filters(),
+		builder.orderBys,
+		page,
+		builder.fields,
+	)
+}
+
 func (builder *selectBuilder[S]) Run() (results []S, err error) {
-	return builder.stg.Select(builder.where, builder.orderBys)
+	return builder.RunContext(context.Background())
+}
+
+func (builder *selectBuilder[S]) RunContext(
+	ctx context.Context,
+) (results []S, err error) {
+	if builder.page == (page{}) && len(builder.fields) == 0 {
+		return builder.stg.SelectContext(
+			ctx,
+			builder.filters(),
+			builder.orderBys,
+		)
+	}
+
+	results, _, err = builder.stg.selectPage(
+		ctx,
+		builder.filters(),
+		builder.orderBys,
+		builder.page,
+		builder.fields,
+	)
+	return results, err
+}
+
+func (builder *selectBuilder[S]) filters() Matcher[S] {
+	if !builder.includeDeleted {
+		return builder.where
+	}
+
+	where := builder.where
+	if where == nil {
+		where = Noop[S]()
+	}
+
+	return &withDeleted[S]{where}
 }
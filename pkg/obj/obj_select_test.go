@@ -1,6 +1,7 @@
 package obj
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -139,3 +140,97 @@ func TestSelect(t *testing.T) {
 		})
 	}
 }
+
+func TestSelectProject(t *testing.T) {
+	type test struct {
+		name     string
+		fields   []Field
+		filters  Matcher[*TestSpec]
+		orderBys []Lesser[*TestSpec]
+		limit    int
+		expect   []*TestSpec
+	}
+
+	tests := []test{
+		{
+			name:    "with one field",
+			fields:  []Field{FooAccessor},
+			filters: Noop[*TestSpec](),
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo"},
+				{Id: 2, Foo: "fiz"},
+				{Id: 3, Foo: "buz"},
+			},
+		},
+		{
+			name:     "with filter and order fields",
+			fields:   []Field{FooAccessor, BarAccessor},
+			filters:  Not(BarEquals("bar")),
+			orderBys: []Lesser[*TestSpec]{OrderByFoo},
+			expect: []*TestSpec{
+				{Id: 3, Foo: "buz", Bar: "baz"},
+				{Id: 2, Foo: "fiz", Bar: "buz"},
+			},
+		},
+		{
+			name:     "with limit",
+			fields:   []Field{BarAccessor},
+			filters:  Noop[*TestSpec](),
+			orderBys: []Lesser[*TestSpec]{OrderByBarDesc},
+			limit:    2,
+			expect: []*TestSpec{
+				{Id: 2, Bar: "buz"},
+				{Id: 3, Bar: "baz"},
+			},
+		},
+		{
+			name:    "with limit and custom order",
+			filters: Noop[*TestSpec](),
+			orderBys: []Lesser[*TestSpec]{
+				lesserFunc(func(i, j *TestSpec) int {
+					return strings.Compare(j.Bar, i.Bar)
+				}),
+			},
+			limit: 2,
+			expect: []*TestSpec{
+				{Id: 2, Type: "test", Foo: "fiz", Bar: "buz"},
+				{Id: 3, Type: "test", Foo: "buz", Bar: "baz"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+
+			util := &testUtil{
+				test: t,
+				lines: []string{
+					`{"id":1,"type":"test","foo":"foo","bar":"bar"}`,
+					`{"id":2,"type":"test","foo":"fiz","bar":"buz"}`,
+					`{"id":3,"type":"test","foo":"buz","bar":"baz"}`,
+				},
+				expect: tc.expect,
+			}
+
+			err = util.setup()
+			defer util.teardown()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := util.stg.NewSelectBuilder().
+				Where(tc.filters).
+				OrderBy(tc.orderBys...).
+				Limit(tc.limit).
+				Project(tc.fields...).
+				Run()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			util.expectSpecs(got...)
+		})
+	}
+}
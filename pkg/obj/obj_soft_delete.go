@@ -0,0 +1,173 @@
+package obj
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// OptDeletedAt turns on soft deletes. Deletes then set the deletedAt of the
+// records they match to the time of the delete instead of removing them, and
+// the records left behind, the tombstones, are skipped by every select, get,
+// aggregate, update and delete unless a select asks for them with
+// IncludeDeleted. Undelete restores tombstones and Purge removes them for
+// good. Tombstones keep their values in the indexes, so a unique index still
+// counts them until they are purged. Watch reports a soft delete as a delete
+// and an undelete as an insert, see Event.
+type OptDeletedAt[S any] struct {
+	Value Accessor[S, time.Time]
+}
+
+func (opt OptDeletedAt[S]) isStorageOpt() bool {
+	return true
+}
+
+// withDeleted marks filters that also match tombstones. It is unwrapped by
+// live before the filters reach the read controller.
+type withDeleted[S any] struct {
+	matcher Matcher[S]
+}
+
+func (matcher *withDeleted[S]) Match(s S) bool {
+	return matcher.matcher.Match(s)
+}
+
+// deleteOp is how a delete writes the records it matches: it removes them, or
+// tombstones them when soft deletes are on.
+func (stg *storage[I, S]) deleteOp() (op op, mutators []Mutator[S]) {
+	if stg.deletedAtAccessor == nil {
+		return opDelete, []Mutator[S]{}
+	}
+
+	return opUpdate, []Mutator[S]{
+		NewMutator(stg.deletedAtAccessor, stg.nower.Now()),
+	}
+}
+
+func (stg *storage[I, S]) isDeleted(s S) bool {
+	return stg.deletedAtAccessor != nil &&
+		!IsZero(stg.deletedAtAccessor).Match(s)
+}
+
+func (stg *storage[I, S]) checkSoftDelete() (err error) {
+	if stg.deletedAtAccessor == nil {
+		return fmt.Errorf(
+			"%w, a deletedAt accessor is required to restore or purge records",
+			illegalArgumentError,
+		)
+	}
+
+	return nil
+}
+
+// Undelete restores the tombstones matching filters and returns them as they
+// are once restored.
+func (stg *storage[I, S]) Undelete(
+	filters Matcher[S],
+) (restored []S, err error) {
+	return stg.UndeleteContext(context.Background(), filters)
+}
+
+func (stg *storage[I, S]) UndeleteContext(
+	ctx context.Context,
+	filters Matcher[S],
+) (restored []S, err error) {
+	if err = stg.checkSoftDelete(); err != nil {
+		return nil, err
+	}
+
+	tx := stg.begin()
+	defer func() { err = tx.end(err) }()
+
+	return stg.undelete(ctx, tx, filters)
+}
+
+func (stg *storage[I, S]) undelete(
+	ctx context.Context,
+	tx *transaction[I, S],
+	filters Matcher[S],
+) (restored []S, err error) {
+	return stg.runReadWrite(
+		ctx,
+		tx,
+		opUpdate,
+		&withDeleted[S]{
+			And(filters, Not(IsZero(stg.deletedAtAccessor))),
+		},
+		[]Mutator[S]{NewMutator(stg.deletedAtAccessor, time.Time{})},
+		nil,
+	)
+}
+
+// Purge removes the tombstones that were deleted at least retention ago and
+// returns how many it removed.
+func (stg *storage[I, S]) Purge(
+	retention time.Duration,
+) (purged int, err error) {
+	return stg.PurgeContext(context.Background(), retention)
+}
+
+func (stg *storage[I, S]) PurgeContext(
+	ctx context.Context,
+	retention time.Duration,
+) (purged int, err error) {
+	var results []S
+
+	if err = stg.checkSoftDelete(); err != nil {
+		return 0, err
+	}
+
+	if retention < 0 {
+		return 0, fmt.Errorf(
+			"%w, retention must be at least 0 but got %s",
+			illegalArgumentError,
+			retention,
+		)
+	}
+
+	cutoff := stg.nower.Now().Add(-retention)
+
+	tx := stg.begin()
+	defer func() { err = tx.end(err) }()
+
+	results, err = stg.runReadWrite(
+		ctx,
+		tx,
+		opDelete,
+		&withDeleted[S]{And(
+			Not(IsZero(stg.deletedAtAccessor)),
+			Not(After(stg.deletedAtAccessor, cutoff)),
+		)},
+		[]Mutator[S]{},
+		nil,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(results), nil
+}
+
+func (tx *transaction[I, S]) Undelete(
+	filters Matcher[S],
+) (restored []S, err error) {
+	return tx.UndeleteContext(context.Background(), filters)
+}
+
+func (tx *transaction[I, S]) UndeleteContext(
+	ctx context.Context,
+	filters Matcher[S],
+) (restored []S, err error) {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
+	if tx.ended {
+		return nil, fmt.Errorf("%w", transactionEndedError)
+	}
+
+	if err = tx.stg.checkSoftDelete(); err != nil {
+		return nil, err
+	}
+
+	return tx.stg.undelete(ctx, tx, filters)
+}
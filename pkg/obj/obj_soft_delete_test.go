@@ -0,0 +1,347 @@
+package obj
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSoftDelete(t *testing.T) {
+	type test struct {
+		name                  string
+		hardDelete            bool
+		run                   func(stg *storage[int, *TestSpec]) (int, error)
+		expectIllegalArgument bool
+		expectCount           int
+		expect                []*TestSpec
+		expectAll             []*TestSpec
+	}
+
+	var (
+		now        = GetTestNow()
+		lastWeek   = parseTestTime(t, "2022-07-01T16:18:00-04:00")
+		anHourAgo  = parseTestTime(t, "2022-07-06T15:18:00-04:00")
+		foo        = &TestSpec{Id: 1, Foo: "foo"}
+		fiz        = &TestSpec{Id: 2, Foo: "fiz"}
+		bar        = &TestSpec{Id: 3, Foo: "bar", DeletedAt: &lastWeek}
+		baz        = &TestSpec{Id: 4, Foo: "baz", DeletedAt: &anHourAgo}
+		deletedFoo = &TestSpec{
+			Id:        1,
+			Foo:       "foo",
+			UpdatedAt: now,
+			DeletedAt: &now,
+		}
+	)
+
+	tests := []test{
+		{
+			name: "with select",
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				results, err := stg.NewSelectBuilder().
+					Where(Noop[*TestSpec]()).
+					Run()
+				return len(results), err
+			},
+			expectCount: 2,
+			expect:      []*TestSpec{foo, fiz},
+			expectAll:   []*TestSpec{foo, fiz, bar, baz},
+		},
+		{
+			name: "with projected select",
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				results, err := stg.NewSelectBuilder().
+					Where(Noop[*TestSpec]()).
+					Project(FooAccessor).
+					Run()
+				return len(results), err
+			},
+			expectCount: 2,
+			expect:      []*TestSpec{foo, fiz},
+			expectAll:   []*TestSpec{foo, fiz, bar, baz},
+		},
+		{
+			name: "with page including deleted",
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				results, _, err := stg.NewSelectBuilder().
+					IncludeDeleted().
+					OrderBy(OrderById).
+					Limit(3).
+					Page()
+				return len(results), err
+			},
+			expectCount: 3,
+			expect:      []*TestSpec{foo, fiz},
+			expectAll:   []*TestSpec{foo, fiz, bar, baz},
+		},
+		{
+			name: "with iterate including deleted",
+			run: func(stg *storage[int, *TestSpec]) (count int, err error) {
+				err = stg.NewSelectBuilder().
+					Where(FooEquals("bar")).
+					IncludeDeleted().
+					Iterate(context.Background(), func(*TestSpec) error {
+						count++
+						return nil
+					})
+				return count, err
+			},
+			expectCount: 1,
+			expect:      []*TestSpec{foo, fiz},
+			expectAll:   []*TestSpec{foo, fiz, bar, baz},
+		},
+		{
+			name: "with get",
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				results, err := stg.GetMany([]int{1, 3})
+				return len(results), err
+			},
+			expectCount: 1,
+			expect:      []*TestSpec{foo, fiz},
+			expectAll:   []*TestSpec{foo, fiz, bar, baz},
+		},
+		{
+			name: "with aggregate",
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				groups, err := stg.NewAggregateBuilder().
+					Aggregate(Count[*TestSpec]()).
+					Run()
+				if err != nil {
+					return 0, err
+				}
+				return groups[0].Values[0].(int), nil
+			},
+			expectCount: 2,
+			expect:      []*TestSpec{foo, fiz},
+			expectAll:   []*TestSpec{foo, fiz, bar, baz},
+		},
+		{
+			name: "with delete",
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				deleted, err := stg.Delete(Noop[*TestSpec]())
+				return len(deleted), err
+			},
+			expectCount: 2,
+			expect:      []*TestSpec{},
+			expectAll: []*TestSpec{
+				deletedFoo,
+				{Id: 2, Foo: "fiz", UpdatedAt: now, DeletedAt: &now},
+				bar,
+				baz,
+			},
+		},
+		{
+			name: "with delete changes",
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				changes, err := stg.NewDeleteBuilder().
+					Where(FooEquals("foo")).
+					RunChanges()
+				if err == nil && !reflect.DeepEqual(
+					changes,
+					[]Change[*TestSpec]{{Before: foo, After: deletedFoo}},
+				) {
+					t.Errorf("expected foo to be tombstoned but got %v", changes)
+				}
+				return len(changes), err
+			},
+			expectCount: 1,
+			expect:      []*TestSpec{fiz},
+			expectAll:   []*TestSpec{deletedFoo, fiz, bar, baz},
+		},
+		{
+			name:       "with hard delete",
+			hardDelete: true,
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				deleted, err := stg.Delete(FooEquals("foo"))
+				return len(deleted), err
+			},
+			expectCount: 1,
+			expect:      []*TestSpec{fiz, bar, baz},
+			expectAll:   []*TestSpec{fiz, bar, baz},
+		},
+		{
+			name: "with update",
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				updated, err := stg.Update(
+					FooEquals("bar"),
+					[]Mutator[*TestSpec]{MutateBar("BAR")},
+					nil,
+				)
+				return len(updated), err
+			},
+			expectCount: 0,
+			expect:      []*TestSpec{foo, fiz},
+			expectAll:   []*TestSpec{foo, fiz, bar, baz},
+		},
+		{
+			name: "with undelete",
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				restored, err := stg.Undelete(Noop[*TestSpec]())
+				return len(restored), err
+			},
+			expectCount: 2,
+			expect: []*TestSpec{
+				foo,
+				fiz,
+				{Id: 3, Foo: "bar", UpdatedAt: now},
+				{Id: 4, Foo: "baz", UpdatedAt: now},
+			},
+			expectAll: []*TestSpec{
+				foo,
+				fiz,
+				{Id: 3, Foo: "bar", UpdatedAt: now},
+				{Id: 4, Foo: "baz", UpdatedAt: now},
+			},
+		},
+		{
+			name: "with undelete in a transaction",
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				tx := stg.Begin()
+
+				restored, err := tx.Undelete(FooEquals("baz"))
+				if err != nil {
+					tx.Rollback()
+					return 0, err
+				}
+
+				return len(restored), tx.Commit()
+			},
+			expectCount: 1,
+			expect: []*TestSpec{
+				foo,
+				fiz,
+				{Id: 4, Foo: "baz", UpdatedAt: now},
+			},
+			expectAll: []*TestSpec{
+				foo,
+				fiz,
+				bar,
+				{Id: 4, Foo: "baz", UpdatedAt: now},
+			},
+		},
+		{
+			name:       "with undelete without a deletedAt accessor",
+			hardDelete: true,
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				restored, err := stg.Undelete(Noop[*TestSpec]())
+				return len(restored), err
+			},
+			expectIllegalArgument: true,
+			expect:                []*TestSpec{foo, fiz, bar, baz},
+			expectAll:             []*TestSpec{foo, fiz, bar, baz},
+		},
+		{
+			name: "with purge",
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				return stg.Purge(24 * time.Hour)
+			},
+			expectCount: 1,
+			expect:      []*TestSpec{foo, fiz},
+			expectAll:   []*TestSpec{foo, fiz, baz},
+		},
+		{
+			name: "with purge of every tombstone",
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				return stg.Purge(0)
+			},
+			expectCount: 2,
+			expect:      []*TestSpec{foo, fiz},
+			expectAll:   []*TestSpec{foo, fiz},
+		},
+		{
+			name: "with purge of a negative retention",
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				return stg.Purge(-time.Hour)
+			},
+			expectIllegalArgument: true,
+			expect:                []*TestSpec{foo, fiz},
+			expectAll:             []*TestSpec{foo, fiz, bar, baz},
+		},
+		{
+			name:       "with purge without a deletedAt accessor",
+			hardDelete: true,
+			run: func(stg *storage[int, *TestSpec]) (int, error) {
+				return stg.Purge(0)
+			},
+			expectIllegalArgument: true,
+			expect:                []*TestSpec{foo, fiz, bar, baz},
+			expectAll:             []*TestSpec{foo, fiz, bar, baz},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				all     []*TestSpec
+				count   int
+				err     error
+				results []*TestSpec
+			)
+
+			util := &testUtil{
+				test: t,
+				lines: []string{
+					`{"id":1,"foo":"foo"}`,
+					`{"id":2,"foo":"fiz"}`,
+					`{"id":3,"foo":"bar","deletedAt":"2022-07-01T16:18:00-04:00"}`,
+					`{"id":4,"foo":"baz","deletedAt":"2022-07-06T15:18:00-04:00"}`,
+				},
+			}
+
+			err = util.setup()
+			defer util.teardown()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !tc.hardDelete {
+				util.stg.deletedAtAccessor = DeletedAtAccessor
+			}
+
+			count, err = tc.run(util.stg)
+
+			switch {
+			case tc.expectIllegalArgument:
+				if !errors.Is(err, illegalArgumentError) {
+					t.Errorf("expected an illegal argument error but got %v", err)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case count != tc.expectCount:
+				t.Errorf("expected count %d but got %d", tc.expectCount, count)
+			}
+
+			if results, err = util.stg.NewSelectBuilder().
+				Where(Noop[*TestSpec]()).
+				OrderBy(OrderById).
+				Run(); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(results, tc.expect) {
+				t.Errorf(
+					"expected select result to be \n%s\n but got \n%s\n",
+					testSpecSliceString(tc.expect),
+					testSpecSliceString(results),
+				)
+			}
+
+			if all, err = util.stg.NewSelectBuilder().
+				IncludeDeleted().
+				OrderBy(OrderById).
+				Run(); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(all, tc.expectAll) {
+				t.Errorf(
+					"expected all records to be \n%s\n but got \n%s\n",
+					testSpecSliceString(tc.expectAll),
+					testSpecSliceString(all),
+				)
+			}
+		})
+	}
+}
@@ -1,10 +1,200 @@
 package obj
 
 import (
+	"os"
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/yo3jones/stg/pkg/objbinlog"
+	"github.com/yo3jones/stg/pkg/stg"
 )
 
+func TestNew(t *testing.T) {
+	type test struct {
+		name                string
+		nilHandle           bool
+		factory             SpecFactory[*TestSpec]
+		idAccessor          Accessor[*TestSpec, int]
+		createdAtAccessor   Accessor[*TestSpec, time.Time]
+		updatedAtAccessor   Accessor[*TestSpec, time.Time]
+		idFactory           stg.IdFactory[int]
+		marshalUnmarshaller stg.MarshalUnmarshaller[*TestSpec]
+		nilBinLogStg        bool
+		opts                []OptStorage
+		expectError         string
+		expectObjType       string
+	}
+
+	defaults := func(tc test) test {
+		if tc.factory == nil {
+			tc.factory = &TestSpecFactory{}
+		}
+		if tc.idAccessor == nil {
+			tc.idAccessor = IdAccessor
+		}
+		if tc.createdAtAccessor == nil {
+			tc.createdAtAccessor = CreatedAtAccessor
+		}
+		if tc.updatedAtAccessor == nil {
+			tc.updatedAtAccessor = UpdatedAtAccessor
+		}
+		if tc.idFactory == nil {
+			tc.idFactory = &testIdFactory{100}
+		}
+		if tc.marshalUnmarshaller == nil {
+			tc.marshalUnmarshaller = &testMarshalUnmarshaller[*TestSpec]{}
+		}
+		return tc
+	}
+
+	tests := []test{
+		{
+			name: "with success",
+			opts: []OptStorage{
+				OptBufferLen{10},
+				OptConcurrency{1},
+				OptNower{&TestNower{}},
+				OptObjType{"test"},
+			},
+			expectObjType: "test",
+		},
+		{
+			name:        "with missing handle",
+			nilHandle:   true,
+			expectError: "illegal argument error, handle is required",
+		},
+		{
+			name:         "with missing bin log storage",
+			nilBinLogStg: true,
+			expectError:  "illegal argument error, bin log storage is required",
+		},
+		{
+			name:        "with invalid concurrency",
+			opts:        []OptStorage{OptConcurrency{0}},
+			expectError: "illegal argument error, concurrency must be at least 1 but got 0",
+		},
+		{
+			name:        "with invalid buffer length",
+			opts:        []OptStorage{OptBufferLen{-1}},
+			expectError: "illegal argument error, buffer length must be at least 1 but got -1",
+		},
+		{
+			name:        "with invalid watch buffer length",
+			opts:        []OptStorage{OptWatchBufferLen{0}},
+			expectError: "illegal argument error, watch buffer length must be at least 1 but got 0",
+		},
+		{
+			name:        "with missing nower",
+			opts:        []OptStorage{OptNower{}},
+			expectError: "illegal argument error, nower is required",
+		},
+		{
+			name: "with indexes",
+			opts: []OptStorage{
+				OptIndex[*TestSpec]{NewHashIndex[*TestSpec, string](FooAccessor)},
+				OptIndex[*TestSpec]{
+					NewOrderedIndex[*TestSpec, string](BarAccessor),
+				},
+			},
+		},
+		{
+			name:        "with missing index",
+			opts:        []OptStorage{OptIndex[*TestSpec]{}},
+			expectError: "illegal argument error, index is required",
+		},
+		{
+			name: "with duplicate index",
+			opts: []OptStorage{
+				OptIndex[*TestSpec]{NewHashIndex[*TestSpec, string](FooAccessor)},
+				OptIndex[*TestSpec]{
+					NewOrderedIndex[*TestSpec, string](FooAccessor),
+				},
+			},
+			expectError: "illegal argument error, index foo is declared more than once",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				binLogStg objbinlog.BinLogStorage
+				err       error
+				file      *os.File
+				handle    stg.Handle
+				logFile   *os.File
+				got       Storage[*TestSpec]
+			)
+
+			tc = defaults(tc)
+
+			os.Remove("test.jsonl")
+			if file, err = os.Create("test.jsonl"); err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove("test.jsonl")
+			defer file.Close()
+
+			os.Remove("test_log.jsonl")
+			if logFile, err = os.Create("test_log.jsonl"); err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove("test_log.jsonl")
+			defer logFile.Close()
+
+			if !tc.nilHandle {
+				handle = file
+			}
+
+			if !tc.nilBinLogStg {
+				binLogStg = objbinlog.New[int](
+					logFile,
+					&testIdFactory{200},
+					&testMarshalUnmarshaller[any]{},
+				)
+			}
+
+			got, err = New(
+				handle,
+				tc.factory,
+				tc.idAccessor,
+				tc.createdAtAccessor,
+				tc.updatedAtAccessor,
+				tc.idFactory,
+				tc.marshalUnmarshaller,
+				binLogStg,
+				tc.opts...,
+			)
+
+			if tc.expectError == "" && err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.expectError != "" {
+				if err == nil {
+					t.Fatalf("expected an error but got nil")
+				}
+				if err.Error() != tc.expectError {
+					t.Errorf(
+						"expected an error with message \n%s\n but got \n%s\n",
+						tc.expectError,
+						err.Error(),
+					)
+				}
+				return
+			}
+
+			if objType := got.(*storage[int, *TestSpec]).objType; objType != tc.expectObjType {
+				t.Errorf(
+					"expected obj type to be %s but got %s",
+					tc.expectObjType,
+					objType,
+				)
+			}
+		})
+	}
+}
+
 func TestAnd(t *testing.T) {
 	type test struct {
 		name   string
@@ -0,0 +1,497 @@
+package obj
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/yo3jones/stg/pkg/fstln"
+	"github.com/yo3jones/stg/pkg/objbinlog"
+)
+
+// Transaction groups Insert, Update and Delete calls into a single unit. The
+// storage stays locked from Begin until Commit or Rollback; writes go to the
+// data file as they are made and Rollback puts back the image each touched
+// record had before the transaction. Commit and Rollback take no context
+// since a rollback that stopped half way would leave the data file neither
+// before nor after the transaction.
+type Transaction[S any] interface {
+	Commit() (err error)
+	Delete(filters Matcher[S]) (deleted []S, err error)
+	DeleteContext(
+		ctx context.Context,
+		filters Matcher[S],
+	) (deleted []S, err error)
+	Insert(mutators []Mutator[S]) (inserted S, err error)
+	InsertContext(
+		ctx context.Context,
+		mutators []Mutator[S],
+	) (inserted S, err error)
+	InsertMany(mutators [][]Mutator[S]) (inserted []S, err error)
+	InsertManyContext(
+		ctx context.Context,
+		mutators [][]Mutator[S],
+	) (inserted []S, err error)
+	Iterate(
+		ctx context.Context,
+		filters Matcher[S],
+		fn func(s S) error,
+	) (err error)
+	NewAggregateBuilder() AggregateBuilder[S]
+	NewDeleteBuilder() DeleteBuilder[S]
+	NewInsertBuilder() InsertBuilder[S]
+	NewSelectBuilder() SelectBuilder[S]
+	NewUpdateBuilder() UpdateBuilder[S]
+	NewUpsertBuilder() UpsertBuilder[S]
+	Rollback() (err error)
+	Select(
+		filters Matcher[S],
+		orderBys []Lesser[S],
+	) (results []S, err error)
+	SelectContext(
+		ctx context.Context,
+		filters Matcher[S],
+		orderBys []Lesser[S],
+	) (results []S, err error)
+	Undelete(filters Matcher[S]) (restored []S, err error)
+	UndeleteContext(
+		ctx context.Context,
+		filters Matcher[S],
+	) (restored []S, err error)
+	Update(
+		filters Matcher[S],
+		mutators []Mutator[S],
+		orderBys []Lesser[S],
+	) (updated []S, err error)
+	UpdateContext(
+		ctx context.Context,
+		filters Matcher[S],
+		mutators []Mutator[S],
+		orderBys []Lesser[S],
+	) (updated []S, err error)
+	Upsert(
+		key Matcher[S],
+		mutators []Mutator[S],
+	) (upserted S, inserted bool, err error)
+	UpsertContext(
+		ctx context.Context,
+		key Matcher[S],
+		mutators []Mutator[S],
+	) (upserted S, inserted bool, err error)
+}
+
+type transaction[I comparable, S any] struct {
+	binLogTrans objbinlog.Transaction
+	ended       bool
+	events      []Event[S]
+	ids         []I
+	lock        sync.Mutex
+	records     map[I]*transactionRecord
+	stg         *storage[I, S]
+}
+
+type transactionRecord struct {
+	exists bool
+	from   []byte
+	pos    fstln.Position
+}
+
+func (stg *storage[I, S]) Begin() Transaction[S] {
+	return stg.begin()
+}
+
+func (stg *storage[I, S]) begin() *transaction[I, S] {
+	stg.lock.Lock()
+
+	return &transaction[I, S]{
+		binLogTrans: stg.binLogStg.StartTransaction(stg.objType),
+		ids:         make([]I, 0),
+		records:     map[I]*transactionRecord{},
+		stg:         stg,
+	}
+}
+
+func (tx *transaction[I, S]) Commit() (err error) {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
+	if tx.ended {
+		return fmt.Errorf("%w", transactionEndedError)
+	}
+
+	tx.ended = true
+	defer tx.stg.lock.Unlock()
+
+	if err = tx.binLogTrans.End(); err != nil {
+		tx.rollback()
+		return err
+	}
+
+	tx.stg.publish(tx.events)
+
+	return nil
+}
+
+func (tx *transaction[I, S]) Delete(
+	filters Matcher[S],
+) (deleted []S, err error) {
+	return tx.DeleteContext(context.Background(), filters)
+}
+
+func (tx *transaction[I, S]) DeleteContext(
+	ctx context.Context,
+	filters Matcher[S],
+) (deleted []S, err error) {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
+	if tx.ended {
+		return nil, fmt.Errorf("%w", transactionEndedError)
+	}
+
+	op, mutators := tx.stg.deleteOp()
+
+	return tx.stg.runReadWrite(ctx, tx, op, filters, mutators, nil)
+}
+
+func (tx *transaction[I, S]) deleteChanges(
+	ctx context.Context,
+	filters Matcher[S],
+) (changes []Change[S], err error) {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
+	if tx.ended {
+		return nil, fmt.Errorf("%w", transactionEndedError)
+	}
+
+	op, mutators := tx.stg.deleteOp()
+
+	return tx.stg.runReadWriteChanges(ctx, tx, op, filters, mutators, nil)
+}
+
+func (tx *transaction[I, S]) Insert(
+	mutators []Mutator[S],
+) (inserted S, err error) {
+	return tx.InsertContext(context.Background(), mutators)
+}
+
+func (tx *transaction[I, S]) InsertContext(
+	ctx context.Context,
+	mutators []Mutator[S],
+) (inserted S, err error) {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
+	if tx.ended {
+		return inserted, fmt.Errorf("%w", transactionEndedError)
+	}
+
+	return tx.stg.insert(ctx, tx, mutators)
+}
+
+func (tx *transaction[I, S]) InsertMany(
+	mutators [][]Mutator[S],
+) (inserted []S, err error) {
+	return tx.InsertManyContext(context.Background(), mutators)
+}
+
+func (tx *transaction[I, S]) InsertManyContext(
+	ctx context.Context,
+	mutators [][]Mutator[S],
+) (inserted []S, err error) {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
+	if tx.ended {
+		return nil, fmt.Errorf("%w", transactionEndedError)
+	}
+
+	return tx.stg.insertMany(ctx, tx, mutators)
+}
+
+func (tx *transaction[I, S]) NewAggregateBuilder() AggregateBuilder[S] {
+	return &aggregateBuilder[S]{filters: Noop[S](), stg: tx}
+}
+
+func (tx *transaction[I, S]) NewDeleteBuilder() DeleteBuilder[S] {
+	return &deleteBuilder[S]{stg: tx}
+}
+
+func (tx *transaction[I, S]) NewInsertBuilder() InsertBuilder[S] {
+	return &insertBuilder[S]{stg: tx}
+}
+
+func (tx *transaction[I, S]) NewSelectBuilder() SelectBuilder[S] {
+	return &selectBuilder[S]{stg: tx}
+}
+
+func (tx *transaction[I, S]) NewUpdateBuilder() UpdateBuilder[S] {
+	return &updateBuilder[S]{stg: tx}
+}
+
+func (tx *transaction[I, S]) NewUpsertBuilder() UpsertBuilder[S] {
+	return &upsertBuilder[S]{stg: tx}
+}
+
+func (tx *transaction[I, S]) Rollback() (err error) {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
+	if tx.ended {
+		return fmt.Errorf("%w", transactionEndedError)
+	}
+
+	tx.ended = true
+	defer tx.stg.lock.Unlock()
+
+	if err = tx.rollback(); err != nil {
+		tx.binLogTrans.Abort()
+		return err
+	}
+
+	return tx.binLogTrans.Abort()
+}
+
+func (tx *transaction[I, S]) Select(
+	filters Matcher[S],
+	orderBys []Lesser[S],
+) (results []S, err error) {
+	return tx.SelectContext(context.Background(), filters, orderBys)
+}
+
+func (tx *transaction[I, S]) SelectContext(
+	ctx context.Context,
+	filters Matcher[S],
+	orderBys []Lesser[S],
+) (results []S, err error) {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
+	if tx.ended {
+		return nil, fmt.Errorf("%w", transactionEndedError)
+	}
+
+	return tx.stg.selectSpecs(ctx, filters, orderBys)
+}
+
+func (tx *transaction[I, S]) aggregate(
+	ctx context.Context,
+	filters Matcher[S],
+	grouper Grouper[S],
+	aggregates []Aggregate[S],
+) (groups []Group, err error) {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
+	if tx.ended {
+		return nil, fmt.Errorf("%w", transactionEndedError)
+	}
+
+	return tx.stg.aggregateSpecs(ctx, filters, grouper, aggregates)
+}
+
+func (tx *transaction[I, S]) Iterate(
+	ctx context.Context,
+	filters Matcher[S],
+	fn func(s S) error,
+) (err error) {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
+	if tx.ended {
+		return fmt.Errorf("%w", transactionEndedError)
+	}
+
+	return tx.stg.iterate(ctx, filters, fn)
+}
+
+func (tx *transaction[I, S]) selectPage(
+	ctx context.Context,
+	filters Matcher[S],
+	orderBys []Lesser[S],
+	page page,
+	fields []string,
+) (results []S, next Cursor, err error) {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
+	if tx.ended {
+		return nil, "", fmt.Errorf("%w", transactionEndedError)
+	}
+
+	return tx.stg.page(ctx, filters, orderBys, page, fields)
+}
+
+func (tx *transaction[I, S]) Update(
+	filters Matcher[S],
+	mutators []Mutator[S],
+	orderBys []Lesser[S],
+) (updated []S, err error) {
+	return tx.UpdateContext(context.Background(), filters, mutators, orderBys)
+}
+
+func (tx *transaction[I, S]) UpdateContext(
+	ctx context.Context,
+	filters Matcher[S],
+	mutators []Mutator[S],
+	orderBys []Lesser[S],
+) (updated []S, err error) {
+	return tx.update(ctx, filters, mutators, orderBys)
+}
+
+func (tx *transaction[I, S]) update(
+	ctx context.Context,
+	filters Matcher[S],
+	mutators []Mutator[S],
+	orderBys []Lesser[S],
+	opts ...writeControllerOpt,
+) (updated []S, err error) {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
+	if tx.ended {
+		return nil, fmt.Errorf("%w", transactionEndedError)
+	}
+
+	return tx.stg.runReadWrite(
+		ctx,
+		tx,
+		opUpdate,
+		filters,
+		mutators,
+		orderBys,
+		opts...,
+	)
+}
+
+func (tx *transaction[I, S]) updateChanges(
+	ctx context.Context,
+	filters Matcher[S],
+	mutators []Mutator[S],
+	orderBys []Lesser[S],
+	opts ...writeControllerOpt,
+) (changes []Change[S], err error) {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
+	if tx.ended {
+		return nil, fmt.Errorf("%w", transactionEndedError)
+	}
+
+	return tx.stg.runReadWriteChanges(
+		ctx,
+		tx,
+		opUpdate,
+		filters,
+		mutators,
+		orderBys,
+		opts...,
+	)
+}
+
+func (tx *transaction[I, S]) Upsert(
+	key Matcher[S],
+	mutators []Mutator[S],
+) (upserted S, inserted bool, err error) {
+	return tx.UpsertContext(context.Background(), key, mutators)
+}
+
+func (tx *transaction[I, S]) UpsertContext(
+	ctx context.Context,
+	key Matcher[S],
+	mutators []Mutator[S],
+) (upserted S, inserted bool, err error) {
+	tx.lock.Lock()
+	defer tx.lock.Unlock()
+
+	if tx.ended {
+		return upserted, false, fmt.Errorf("%w", transactionEndedError)
+	}
+
+	return tx.stg.upsert(ctx, tx, key, mutators)
+}
+
+// end finishes an implicit transaction, committing it when err is nil and
+// rolling it back otherwise. A failed rollback is not reported over the error
+// that caused it.
+func (tx *transaction[I, S]) end(err error) error {
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// record notes where a record written by the transaction now lives. Only the
+// first image seen for an id is kept since that is the one to restore.
+func (tx *transaction[I, S]) record(
+	id I,
+	from []byte,
+	pos fstln.Position,
+	exists bool,
+) {
+	record, found := tx.records[id]
+	if !found {
+		record = &transactionRecord{from: from}
+		tx.records[id] = record
+		tx.ids = append(tx.ids, id)
+	}
+
+	record.exists = exists
+	record.pos = pos
+}
+
+// rollback restores every record the transaction wrote and moves its index
+// entries along with it, leaving the records it did not touch alone.
+func (tx *transaction[I, S]) rollback() (err error) {
+	var (
+		from     S
+		fstlnStg = tx.stg.stg
+		id       I
+		pos      fstln.Position
+		record   *transactionRecord
+		stg      = tx.stg
+	)
+
+	for i := len(tx.ids) - 1; i >= 0; i-- {
+		id = tx.ids[i]
+		record = tx.records[id]
+
+		if record.from != nil {
+			from = stg.factory.New()
+			err = stg.marshalUnmarshaller.Unmarshal(record.from, from)
+			if err != nil {
+				return err
+			}
+		}
+
+		switch {
+		case record.from == nil && record.exists:
+			err = fstlnStg.Delete(record.pos)
+		case record.from != nil && record.exists:
+			pos, err = fstlnStg.Update(record.pos, record.from)
+		case record.from != nil && !record.exists:
+			pos, err = fstlnStg.Insert(record.from)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if record.exists {
+			stg.unindexId(id, record.pos)
+		}
+
+		if record.from != nil {
+			stg.index(from, pos)
+		}
+	}
+
+	return nil
+}
+
+var transactionEndedError = fmt.Errorf(
+	"illegal state error, transaction has ended",
+)
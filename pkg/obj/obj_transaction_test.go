@@ -0,0 +1,179 @@
+package obj
+
+import "testing"
+
+func TestTransaction(t *testing.T) {
+	type test struct {
+		name         string
+		lines        []string
+		run          func(tx Transaction[*TestSpec]) error
+		rollback     bool
+		mockErr      *mockErr
+		expectError  string
+		expect       []*TestSpec
+		expectLines  [][]string
+		expectBinLog [][]string
+	}
+
+	tests := []test{
+		{
+			name: "with commit",
+			lines: []string{
+				`{"id":1,"foo":"foo","bar":"bar"}`,
+				`{"id":2,"foo":"fiz","bar":"buz"}`,
+			},
+			run: func(tx Transaction[*TestSpec]) (err error) {
+				if _, err = tx.NewInsertBuilder().
+					Set(MutateFoo("new")).
+					Run(); err != nil {
+					return err
+				}
+				_, err = tx.NewDeleteBuilder().Where(BarEquals("buz")).Run()
+				return err
+			},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{
+					Id:        100,
+					Foo:       "new",
+					UpdatedAt: GetTestNow(),
+					CreatedAt: GetTestNow(),
+				},
+			},
+			expectLines: [][]string{
+				{
+					`{"id":1,"foo":"foo","bar":"bar"}`,
+					`                                `,
+					`{"id":100,"type":"","foo":"new","bar":"","updatedAt":"2022-07-06T16:18:00-04:00","createdAt":"2022-07-06T16:18:00-04:00"}`,
+				},
+			},
+			expectBinLog: [][]string{
+				{
+					`{"transaction":200,"type":"test","op":"begin","ts":"2022-07-06T16:18:00-04:00"}`,
+					`{"transaction":200,"type":"test","id":100,"ts":"2022-07-06T16:18:00-04:00","from":null,"to":{"id":100,"type":"","foo":"new","bar":"","updatedAt":"2022-07-06T16:18:00-04:00","createdAt":"2022-07-06T16:18:00-04:00"}}`,
+					`{"transaction":200,"type":"test","id":2,"ts":"2022-07-06T16:18:00-04:00","from":{"id":2,"foo":"fiz","bar":"buz"},"to":null}`,
+					`{"transaction":200,"type":"test","op":"commit","ts":"2022-07-06T16:18:00-04:00"}`,
+				},
+			},
+		},
+		{
+			name: "with rollback",
+			lines: []string{
+				`{"id":1,"foo":"foo","bar":"bar"}`,
+				`{"id":2,"foo":"fiz","bar":"buz"}`,
+			},
+			run: func(tx Transaction[*TestSpec]) (err error) {
+				if _, err = tx.NewInsertBuilder().
+					Set(MutateFoo("new")).
+					Run(); err != nil {
+					return err
+				}
+				if _, err = tx.NewUpdateBuilder().
+					Where(FooEquals("foo")).
+					Set(MutateBar("BAR")).
+					Run(); err != nil {
+					return err
+				}
+				_, err = tx.NewDeleteBuilder().Where(BarEquals("buz")).Run()
+				return err
+			},
+			rollback: true,
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "fiz", Bar: "buz"},
+			},
+			expectLines: [][]string{
+				{
+					`{"id":2,"foo":"fiz","bar":"buz"}`,
+					`                                `,
+					`                                                                                                                         `,
+					`{"id":1,"foo":"foo","bar":"bar"}`,
+					`                                                                                    `,
+				},
+			},
+			expectBinLog: [][]string{
+				{
+					`{"transaction":200,"type":"test","op":"begin","ts":"2022-07-06T16:18:00-04:00"}`,
+					`{"transaction":200,"type":"test","id":100,"ts":"2022-07-06T16:18:00-04:00","from":null,"to":{"id":100,"type":"","foo":"new","bar":"","updatedAt":"2022-07-06T16:18:00-04:00","createdAt":"2022-07-06T16:18:00-04:00"}}`,
+					`{"transaction":200,"type":"test","id":1,"ts":"2022-07-06T16:18:00-04:00","from":{"id":1,"foo":"foo","bar":"bar"},"to":{"id":1,"type":"","foo":"foo","bar":"BAR","updatedAt":"2022-07-06T16:18:00-04:00","createdAt":"0001-01-01T00:00:00Z"}}`,
+					`{"transaction":200,"type":"test","id":2,"ts":"2022-07-06T16:18:00-04:00","from":{"id":2,"foo":"fiz","bar":"buz"},"to":null}`,
+					`{"transaction":200,"type":"test","op":"abort","ts":"2022-07-06T16:18:00-04:00"}`,
+				},
+			},
+		},
+		{
+			name: "with error",
+			lines: []string{
+				`{"id":1,"foo":"foo","bar":"bar"}`,
+			},
+			run: func(tx Transaction[*TestSpec]) (err error) {
+				_, err = tx.NewInsertBuilder().Set(MutateFoo("new")).Run()
+				return err
+			},
+			mockErr: &mockErr{
+				mockErrType: mockErrTypeInsert,
+				errorOn:     0,
+				msg:         "with insert error",
+			},
+			expectError: "with insert error",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				err     error
+				results []*TestSpec
+			)
+
+			util := &testUtil{
+				test:         t,
+				lines:        tc.lines,
+				mockError:    tc.mockErr,
+				expectError:  tc.expectError,
+				expect:       tc.expect,
+				expectLines:  tc.expectLines,
+				expectBinLog: tc.expectBinLog,
+			}
+
+			err = util.setup()
+			defer util.teardown()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tx := util.stg.Begin()
+
+			if err = tc.run(tx); err != nil {
+				tx.Rollback()
+			} else if tc.rollback {
+				err = tx.Rollback()
+			} else {
+				err = tx.Commit()
+			}
+
+			if done := util.handleExpectError(err); done {
+				return
+			}
+
+			if err = tx.Commit(); err == nil {
+				t.Errorf("expected an error committing an ended transaction")
+			}
+
+			results, err = util.stg.NewSelectBuilder().
+				Where(Noop[*TestSpec]()).
+				OrderBy(OrderById).
+				Run()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			util.expectSpecs(results...)
+
+			util.handleExpectLines()
+
+			util.handleExpectBinLog()
+		})
+	}
+}
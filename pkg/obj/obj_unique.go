@@ -0,0 +1,150 @@
+package obj
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/yo3jones/stg/pkg/fstln"
+)
+
+// ErrUniqueViolation is returned by Insert and Update when a record would take
+// a value a unique index already holds for another record. Field is the name
+// of the index's accessor and Id the id of the record holding the value.
+// Tombstones and expired records keep their values until they are purged or
+// swept, and Deleted or Expired tell that the record holding the value is one
+// of them, which no read returns.
+type ErrUniqueViolation struct {
+	Field   string
+	Id      any
+	Deleted bool
+	Expired bool
+}
+
+func (err *ErrUniqueViolation) Error() string {
+	msg := fmt.Sprintf(
+		"unique violation error, %s is already taken by %v",
+		err.Field,
+		err.Id,
+	)
+
+	switch {
+	case err.Deleted:
+		msg += ", a tombstone until it is purged"
+	case err.Expired:
+		msg += ", an expired record until it is swept"
+	}
+
+	return msg
+}
+
+// NewUniqueIndex creates an index that answers Equals filters on accessor like
+// NewHashIndex and also requires that no two records share a value,
+// including the zero value.
+func NewUniqueIndex[S any, T comparable](accessor Accessor[S, T]) Index[S] {
+	index := newHashIndex(accessor)
+	index.unique = true
+	return index
+}
+
+// checkUnique returns an ErrUniqueViolation when a record other than the one
+// at pos holds a value of s that a unique index requires to be distinct.
+func (stg *storage[I, S]) checkUnique(s S, pos fstln.Position) (err error) {
+	for _, index := range stg.indexes {
+		if conflicting, found := index.conflict(s, pos); found {
+			return stg.uniqueViolation(index.Name(), conflicting)
+		}
+	}
+
+	return nil
+}
+
+// uniqueViolation reports the value of field taken by the record at pos,
+// telling whether that record is hidden from reads. The violation stands even
+// when the record cannot be read back to tell.
+func (stg *storage[I, S]) uniqueViolation(
+	field string,
+	pos fstln.Position,
+) *ErrUniqueViolation {
+	violation := &ErrUniqueViolation{Field: field, Id: stg.ids[pos]}
+
+	if stg.deletedAtAccessor == nil && stg.expiresAtAccessor == nil {
+		return violation
+	}
+
+	if holder, err := stg.readSpec(pos); err == nil {
+		violation.Deleted = stg.isDeleted(holder)
+		violation.Expired = stg.isExpired(holder)
+	}
+
+	return violation
+}
+
+// claimUnique checks s like checkUnique and then records its values against
+// pos in the unique indexes so that the other records of the same update are
+// checked against them. The write controller calls it from several goroutines.
+func (stg *storage[I, S]) claimUnique(s S, pos fstln.Position) (err error) {
+	stg.claimLock.Lock()
+	defer stg.claimLock.Unlock()
+
+	if err = stg.checkUnique(s, pos); err != nil {
+		return err
+	}
+
+	for _, index := range stg.indexes {
+		if index.isUnique() {
+			index.remove(pos)
+			index.add(s, pos)
+		}
+	}
+
+	return nil
+}
+
+// claims keeps the positions a single write claimed unique values at so that
+// the claims of the records it did not get to write can be released again.
+type claims[I comparable, S any] struct {
+	lock      sync.Mutex
+	positions []fstln.Position
+	stg       *storage[I, S]
+}
+
+func (claims *claims[I, S]) claim(s S, pos fstln.Position) (err error) {
+	if err = claims.stg.claimUnique(s, pos); err != nil {
+		return err
+	}
+
+	claims.lock.Lock()
+	defer claims.lock.Unlock()
+
+	claims.positions = append(claims.positions, pos)
+
+	return nil
+}
+
+// release puts back the values of the records that were claimed but not
+// written, which are still on the lines they were read from. written holds
+// the positions the written records were read from.
+func (claims *claims[I, S]) release(
+	written map[fstln.Position]struct{},
+) (err error) {
+	var s S
+
+	for _, pos := range claims.positions {
+		if _, found := written[pos]; found {
+			continue
+		}
+
+		if s, err = claims.stg.readSpec(pos); err != nil {
+			return err
+		}
+
+		for _, index := range claims.stg.indexes {
+			if index.isUnique() {
+				index.remove(pos)
+				index.add(s, pos)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,266 @@
+package obj
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestUnique(t *testing.T) {
+	type test struct {
+		name        string
+		run         func(stg *storage[int, *TestSpec]) error
+		mockError   *mockErr
+		hidden      bool
+		expectError *ErrUniqueViolation
+		expect      []*TestSpec
+	}
+
+	tests := []test{
+		{
+			name: "with insert",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewInsertBuilder().Set(MutateFoo("new")).Run()
+				return err
+			},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "fiz", Bar: "buz"},
+				{
+					Id:        100,
+					Foo:       "new",
+					UpdatedAt: GetTestNow(),
+					CreatedAt: GetTestNow(),
+				},
+			},
+		},
+		{
+			name: "with insert violation",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewInsertBuilder().Set(MutateFoo("fiz")).Run()
+				return err
+			},
+			expectError: &ErrUniqueViolation{Field: "foo", Id: 2},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "fiz", Bar: "buz"},
+			},
+		},
+		{
+			name: "with insert after failed update",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				if _, err = stg.NewUpdateBuilder().
+					Where(FooEquals("foo")).
+					Set(MutateFoo("new")).
+					Run(); err == nil {
+					return errors.New("expected the update to fail")
+				}
+				_, err = stg.NewInsertBuilder().Set(MutateFoo("new")).Run()
+				return err
+			},
+			mockError: &mockErr{
+				mockErrType: mockErrTypeUpdate,
+				errorOn:     0,
+				msg:         "with update error",
+			},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "fiz", Bar: "buz"},
+				{
+					Id:        100,
+					Foo:       "new",
+					UpdatedAt: GetTestNow(),
+					CreatedAt: GetTestNow(),
+				},
+			},
+		},
+		{
+			name: "with insert violation after rollback",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				tx := stg.Begin()
+				if _, err = tx.NewDeleteBuilder().
+					Where(FooEquals("fiz")).
+					Run(); err != nil {
+					return err
+				}
+				if _, err = tx.NewUpdateBuilder().
+					Where(FooEquals("foo")).
+					Set(MutateFoo("fiz")).
+					Run(); err != nil {
+					return err
+				}
+				if err = tx.Rollback(); err != nil {
+					return err
+				}
+				_, err = stg.NewInsertBuilder().Set(MutateFoo("fiz")).Run()
+				return err
+			},
+			expectError: &ErrUniqueViolation{Field: "foo", Id: 2},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "fiz", Bar: "buz"},
+			},
+		},
+		{
+			name:   "with insert violation by a tombstone",
+			hidden: true,
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewInsertBuilder().Set(MutateFoo("gone")).Run()
+				return err
+			},
+			expectError: &ErrUniqueViolation{Field: "foo", Id: 3, Deleted: true},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "fiz", Bar: "buz"},
+			},
+		},
+		{
+			name:   "with insert violation by an expired record",
+			hidden: true,
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewInsertBuilder().Set(MutateFoo("old")).Run()
+				return err
+			},
+			expectError: &ErrUniqueViolation{Field: "foo", Id: 4, Expired: true},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "fiz", Bar: "buz"},
+			},
+		},
+		{
+			name: "with insert after delete",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				if _, err = stg.NewDeleteBuilder().
+					Where(FooEquals("fiz")).
+					Run(); err != nil {
+					return err
+				}
+				_, err = stg.NewInsertBuilder().Set(MutateFoo("fiz")).Run()
+				return err
+			},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{
+					Id:        100,
+					Foo:       "fiz",
+					UpdatedAt: GetTestNow(),
+					CreatedAt: GetTestNow(),
+				},
+			},
+		},
+		{
+			name: "with update keeping value",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewUpdateBuilder().
+					Where(FooEquals("foo")).
+					Set(MutateBar("BAR")).
+					Run()
+				return err
+			},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "BAR", UpdatedAt: GetTestNow()},
+				{Id: 2, Foo: "fiz", Bar: "buz"},
+			},
+		},
+		{
+			name: "with update violation",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewUpdateBuilder().
+					Where(FooEquals("foo")).
+					Set(MutateFoo("fiz")).
+					Run()
+				return err
+			},
+			expectError: &ErrUniqueViolation{Field: "foo", Id: 2},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "fiz", Bar: "buz"},
+			},
+		},
+		{
+			name: "with update violation within the update",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewUpdateBuilder().
+					Where(Noop[*TestSpec]()).
+					Set(MutateFoo("new")).
+					Run()
+				return err
+			},
+			expectError: &ErrUniqueViolation{Field: "foo"},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "fiz", Bar: "buz"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				err       error
+				violation *ErrUniqueViolation
+			)
+
+			util := &testUtil{
+				test: t,
+				lines: []string{
+					`{"id":1,"foo":"foo","bar":"bar"}`,
+					`{"id":2,"foo":"fiz","bar":"buz"}`,
+				},
+				indexes: []Index[*TestSpec]{
+					NewUniqueIndex[*TestSpec, string](FooAccessor),
+				},
+				mockError: tc.mockError,
+				filters:   Noop[*TestSpec](),
+				orderBys:  []Lesser[*TestSpec]{OrderById},
+				expect:    tc.expect,
+			}
+
+			if tc.hidden {
+				util.lines = append(
+					util.lines,
+					`{"id":3,"foo":"gone","deletedAt":"2022-07-01T00:00:00Z"}`,
+					`{"id":4,"foo":"old","expiresAt":"2022-07-06T15:18:00-04:00"}`,
+				)
+			}
+
+			err = util.setup()
+			defer util.teardown()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.hidden {
+				util.stg.deletedAtAccessor = DeletedAtAccessor
+				util.stg.expiresAtAccessor = ExpiresAtAccessor
+			}
+
+			err = tc.run(util.stg)
+
+			if tc.expectError == nil && err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.expectError != nil {
+				if !errors.As(err, &violation) {
+					t.Fatalf("expected a unique violation but got %v", err)
+				}
+
+				if tc.expectError.Id == nil {
+					violation.Id = nil
+				}
+
+				if !reflect.DeepEqual(violation, tc.expectError) {
+					t.Errorf(
+						"expected violation %+v but got %+v",
+						tc.expectError,
+						violation,
+					)
+				}
+			}
+
+			util.expectSelect()
+		})
+	}
+}
@@ -1,13 +1,64 @@
 package obj
 
+import "context"
+
 func (stg *storage[I, S]) Update(
 	filters Matcher[S],
 	mutators []Mutator[S],
 	orderBys []Lesser[S],
 ) (updated []S, err error) {
-	stg.lock.Lock()
-	defer stg.lock.Unlock()
-	return stg.runReadWrite(opUpdate, filters, mutators, orderBys...)
+	return stg.UpdateContext(context.Background(), filters, mutators, orderBys)
+}
+
+func (stg *storage[I, S]) UpdateContext(
+	ctx context.Context,
+	filters Matcher[S],
+	mutators []Mutator[S],
+	orderBys []Lesser[S],
+) (updated []S, err error) {
+	return stg.update(ctx, filters, mutators, orderBys)
+}
+
+func (stg *storage[I, S]) update(
+	ctx context.Context,
+	filters Matcher[S],
+	mutators []Mutator[S],
+	orderBys []Lesser[S],
+	opts ...writeControllerOpt,
+) (updated []S, err error) {
+	tx := stg.begin()
+	defer func() { err = tx.end(err) }()
+
+	return stg.runReadWrite(
+		ctx,
+		tx,
+		opUpdate,
+		filters,
+		mutators,
+		orderBys,
+		opts...,
+	)
+}
+
+func (stg *storage[I, S]) updateChanges(
+	ctx context.Context,
+	filters Matcher[S],
+	mutators []Mutator[S],
+	orderBys []Lesser[S],
+	opts ...writeControllerOpt,
+) (changes []Change[S], err error) {
+	tx := stg.begin()
+	defer func() { err = tx.end(err) }()
+
+	return stg.runReadWriteChanges(
+		ctx,
+		tx,
+		opUpdate,
+		filters,
+		mutators,
+		orderBys,
+		opts...,
+	)
 }
 
 func (stg *storage[I, S]) NewUpdateBuilder() UpdateBuilder[S] {
@@ -19,15 +70,20 @@ func (stg *storage[I, S]) NewUpdateBuilder() UpdateBuilder[S] {
 type UpdateBuilder[S any] interface {
 	OrderBy(orderBys ...Lesser[S]) UpdateBuilder[S]
 	Run() (updated []S, err error)
+	RunContext(ctx context.Context) (updated []S, err error)
+	RunChanges() (changes []Change[S], err error)
+	RunChangesContext(ctx context.Context) (changes []Change[S], err error)
 	Set(mutators ...Mutator[S]) UpdateBuilder[S]
 	Where(filters ...Matcher[S]) UpdateBuilder[S]
+	WhereVersion(version int64) UpdateBuilder[S]
 }
 
 type updateBuilder[S any] struct {
 	filters  Matcher[S]
 	orderBys []Lesser[S]
 	mutators []Mutator[S]
-	stg      Storage[S]
+	stg      operator[S]
+	version  *int64
 }
 
 func (builder *updateBuilder[S]) OrderBy(
@@ -38,10 +94,36 @@ func (builder *updateBuilder[S]) OrderBy(
 }
 
 func (builder *updateBuilder[S]) Run() (updated []S, err error) {
-	return builder.stg.Update(
+	return builder.RunContext(context.Background())
+}
+
+func (builder *updateBuilder[S]) RunContext(
+	ctx context.Context,
+) (updated []S, err error) {
+	return builder.stg.update(
+		ctx,
+		builder.filters,
+		builder.mutators,
+		builder.orderBys,
+		builder.writeOpts()...,
+	)
+}
+
+// RunChanges updates like Run but returns the image each record had before
+// the update along with the one it has after it.
+func (builder *updateBuilder[S]) RunChanges() (changes []Change[S], err error) {
+	return builder.RunChangesContext(context.Background())
+}
+
+func (builder *updateBuilder[S]) RunChangesContext(
+	ctx context.Context,
+) (changes []Change[S], err error) {
+	return builder.stg.updateChanges(
+		ctx,
 		builder.filters,
 		builder.mutators,
 		builder.orderBys,
+		builder.writeOpts()...,
 	)
 }
 
@@ -54,3 +136,18 @@ func (builder *updateBuilder[S]) Where(filters ...Matcher[S]) UpdateBuilder[S] {
 	builder.filters = And(filters...)
 	return builder
 }
+
+// WhereVersion fails the update with an ErrVersionConflict when a record it
+// matches is not at version. It requires a storage with OptVersion.
+func (builder *updateBuilder[S]) WhereVersion(version int64) UpdateBuilder[S] {
+	builder.version = &version
+	return builder
+}
+
+func (builder *updateBuilder[S]) writeOpts() []writeControllerOpt {
+	if builder.version == nil {
+		return nil
+	}
+
+	return []writeControllerOpt{optExpectVersion{*builder.version}}
+}
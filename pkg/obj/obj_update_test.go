@@ -53,12 +53,16 @@ func TestUpdate(t *testing.T) {
 			},
 			expectBinLog: [][]string{
 				{
+					`{"transaction":200,"type":"test","op":"begin","ts":"2022-07-06T16:18:00-04:00"}`,
 					`{"transaction":200,"type":"test","id":1,"ts":"2022-07-06T16:18:00-04:00","from":{"id":1,"foo":"foo","bar":"bar"},"to":{"id":1,"type":"","foo":"FOO","bar":"bar","updatedAt":"2022-07-06T16:18:00-04:00","createdAt":"0001-01-01T00:00:00Z"}}`,
 					`{"transaction":200,"type":"test","id":2,"ts":"2022-07-06T16:18:00-04:00","from":{"id":2,"foo":"fiz","bar":"bar"},"to":{"id":2,"type":"","foo":"FOO","bar":"bar","updatedAt":"2022-07-06T16:18:00-04:00","createdAt":"0001-01-01T00:00:00Z"}}`,
+					`{"transaction":200,"type":"test","op":"commit","ts":"2022-07-06T16:18:00-04:00"}`,
 				},
 				{
+					`{"transaction":200,"type":"test","op":"begin","ts":"2022-07-06T16:18:00-04:00"}`,
 					`{"transaction":200,"type":"test","id":2,"ts":"2022-07-06T16:18:00-04:00","from":{"id":2,"foo":"fiz","bar":"bar"},"to":{"id":2,"type":"","foo":"FOO","bar":"bar","updatedAt":"2022-07-06T16:18:00-04:00","createdAt":"0001-01-01T00:00:00Z"}}`,
 					`{"transaction":200,"type":"test","id":1,"ts":"2022-07-06T16:18:00-04:00","from":{"id":1,"foo":"foo","bar":"bar"},"to":{"id":1,"type":"","foo":"FOO","bar":"bar","updatedAt":"2022-07-06T16:18:00-04:00","createdAt":"0001-01-01T00:00:00Z"}}`,
+					`{"transaction":200,"type":"test","op":"commit","ts":"2022-07-06T16:18:00-04:00"}`,
 				},
 			},
 		},
@@ -0,0 +1,191 @@
+package obj
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// keyMatcher is an Equals filter. Besides finding the record with the key it
+// gives the mutator that sets the key on a record that is about to be inserted.
+type keyMatcher[S any] interface {
+	Matcher[S]
+	accessorName() string
+	keyMutator() Mutator[S]
+}
+
+func (stg *storage[I, S]) Upsert(
+	key Matcher[S],
+	mutators []Mutator[S],
+) (upserted S, inserted bool, err error) {
+	return stg.UpsertContext(context.Background(), key, mutators)
+}
+
+// UpsertContext updates the record matching key with mutators, or inserts a
+// new one with mutators and key's value when there is none, in a single
+// transaction. key must be an Equals filter on the id accessor or on the
+// accessor of a unique index. A tombstone of a soft delete matching key is
+// restored and updated, and an expired record matching key is swept and
+// replaced by a new one; both are reported as inserted since neither was
+// visible before.
+func (stg *storage[I, S]) UpsertContext(
+	ctx context.Context,
+	key Matcher[S],
+	mutators []Mutator[S],
+) (upserted S, inserted bool, err error) {
+	tx := stg.begin()
+	defer func() { err = tx.end(err) }()
+
+	return stg.upsert(ctx, tx, key, mutators)
+}
+
+func (stg *storage[I, S]) upsert(
+	ctx context.Context,
+	tx *transaction[I, S],
+	key Matcher[S],
+	mutators []Mutator[S],
+) (upserted S, inserted bool, err error) {
+	var (
+		keyMutator Mutator[S]
+		matched    []S
+		updated    []S
+	)
+
+	if keyMutator, err = stg.upsertKey(key); err != nil {
+		return upserted, false, err
+	}
+
+	// Tombstones and expired records are looked up too since they still hold
+	// their key.
+	if matched, err = stg.selectSpecs(ctx, &unfiltered[S]{key}, nil); err != nil {
+		return upserted, false, err
+	}
+
+	switch {
+	case len(matched) == 0:
+		upserted, err = stg.insert(
+			ctx,
+			tx,
+			append(append([]Mutator[S]{}, mutators...), keyMutator),
+		)
+		return upserted, true, err
+	case len(matched) == 1 && stg.isExpired(matched[0]):
+		// An expired record only waits for a sweep, so it is swept now and a
+		// new record takes its key.
+		_, err = stg.runReadWrite(
+			ctx,
+			tx,
+			opDelete,
+			&unfiltered[S]{key},
+			[]Mutator[S]{},
+			nil,
+		)
+		if err != nil {
+			return upserted, false, err
+		}
+		upserted, err = stg.insert(
+			ctx,
+			tx,
+			append(append([]Mutator[S]{}, mutators...), keyMutator),
+		)
+		return upserted, true, err
+	case len(matched) == 1 && stg.isDeleted(matched[0]):
+		// A tombstone is restored and updated, keeping its id and history.
+		updated, err = stg.runReadWrite(
+			ctx,
+			tx,
+			opUpdate,
+			&withDeleted[S]{key},
+			append(
+				append([]Mutator[S]{}, mutators...),
+				NewMutator(stg.deletedAtAccessor, time.Time{}),
+			),
+			nil,
+		)
+		if err != nil {
+			return upserted, false, err
+		}
+		return updated[0], true, nil
+	case len(matched) == 1:
+		updated, err = stg.runReadWrite(
+			ctx,
+			tx,
+			opUpdate,
+			key,
+			mutators,
+			nil,
+		)
+		if err != nil {
+			return upserted, false, err
+		}
+		return updated[0], false, nil
+	default:
+		return upserted, false, fmt.Errorf(
+			"%w, upsert key matched %d records",
+			illegalArgumentError,
+			len(matched),
+		)
+	}
+}
+
+func (stg *storage[I, S]) upsertKey(key Matcher[S]) (Mutator[S], error) {
+	if key, ok := key.(keyMatcher[S]); ok {
+		if key.accessorName() == stg.idAccessor.Name() {
+			return key.keyMutator(), nil
+		}
+
+		for _, index := range stg.indexes {
+			if index.isUnique() && index.Name() == key.accessorName() {
+				return key.keyMutator(), nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf(
+		"%w, upsert key must be an Equals filter on the id or a unique index",
+		illegalArgumentError,
+	)
+}
+
+func (stg *storage[I, S]) NewUpsertBuilder() UpsertBuilder[S] {
+	return &upsertBuilder[S]{stg: stg}
+}
+
+type UpsertBuilder[S any] interface {
+	On(key Matcher[S]) UpsertBuilder[S]
+	Run() (upserted S, inserted bool, err error)
+	RunContext(ctx context.Context) (upserted S, inserted bool, err error)
+	Set(mutators ...Mutator[S]) UpsertBuilder[S]
+}
+
+type upsertBuilder[S any] struct {
+	key      Matcher[S]
+	mutators []Mutator[S]
+	stg      operator[S]
+}
+
+func (builder *upsertBuilder[S]) On(key Matcher[S]) UpsertBuilder[S] {
+	builder.key = key
+	return builder
+}
+
+func (builder *upsertBuilder[S]) Run() (
+	upserted S,
+	inserted bool,
+	err error,
+) {
+	return builder.RunContext(context.Background())
+}
+
+func (builder *upsertBuilder[S]) RunContext(ctx context.Context) (
+	upserted S,
+	inserted bool,
+	err error,
+) {
+	return builder.stg.UpsertContext(ctx, builder.key, builder.mutators)
+}
+
+func (builder *upsertBuilder[S]) Set(mutators ...Mutator[S]) UpsertBuilder[S] {
+	builder.mutators = mutators
+	return builder
+}
@@ -0,0 +1,288 @@
+package obj
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUpsert(t *testing.T) {
+	type test struct {
+		name           string
+		key            Matcher[*TestSpec]
+		mutators       []Mutator[*TestSpec]
+		mockError      *mockErr
+		expectError    string
+		expectInserted bool
+		expectUpserted *TestSpec
+		expect         []*TestSpec
+		expectBinLog   [][]string
+	}
+
+	tests := []test{
+		{
+			name:     "with update by id",
+			key:      Equals[*TestSpec, int](IdAccessor, 2),
+			mutators: []Mutator[*TestSpec]{MutateBar("BAR")},
+			expectUpserted: &TestSpec{
+				Id:        2,
+				Foo:       "fiz",
+				Bar:       "BAR",
+				UpdatedAt: GetTestNow(),
+			},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "fiz", Bar: "BAR", UpdatedAt: GetTestNow()},
+			},
+			expectBinLog: [][]string{
+				{
+					`{"transaction":200,"type":"test","op":"begin","ts":"2022-07-06T16:18:00-04:00"}`,
+					`{"transaction":200,"type":"test","id":2,"ts":"2022-07-06T16:18:00-04:00","from":{"id":2,"foo":"fiz","bar":"buz"},"to":{"id":2,"type":"","foo":"fiz","bar":"BAR","updatedAt":"2022-07-06T16:18:00-04:00","createdAt":"0001-01-01T00:00:00Z"}}`,
+					`{"transaction":200,"type":"test","op":"commit","ts":"2022-07-06T16:18:00-04:00"}`,
+				},
+			},
+		},
+		{
+			name:           "with insert by id",
+			key:            Equals[*TestSpec, int](IdAccessor, 7),
+			mutators:       []Mutator[*TestSpec]{MutateFoo("new")},
+			expectInserted: true,
+			expectUpserted: &TestSpec{
+				Id:        7,
+				Foo:       "new",
+				UpdatedAt: GetTestNow(),
+				CreatedAt: GetTestNow(),
+			},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "fiz", Bar: "buz"},
+				{
+					Id:        7,
+					Foo:       "new",
+					UpdatedAt: GetTestNow(),
+					CreatedAt: GetTestNow(),
+				},
+			},
+			expectBinLog: [][]string{
+				{
+					`{"transaction":200,"type":"test","op":"begin","ts":"2022-07-06T16:18:00-04:00"}`,
+					`{"transaction":200,"type":"test","id":7,"ts":"2022-07-06T16:18:00-04:00","from":null,"to":{"id":7,"type":"","foo":"new","bar":"","updatedAt":"2022-07-06T16:18:00-04:00","createdAt":"2022-07-06T16:18:00-04:00"}}`,
+					`{"transaction":200,"type":"test","op":"commit","ts":"2022-07-06T16:18:00-04:00"}`,
+				},
+			},
+		},
+		{
+			name:     "with update by unique key",
+			key:      FooEquals("foo"),
+			mutators: []Mutator[*TestSpec]{MutateBar("BAR")},
+			expectUpserted: &TestSpec{
+				Id:        1,
+				Foo:       "foo",
+				Bar:       "BAR",
+				UpdatedAt: GetTestNow(),
+			},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "BAR", UpdatedAt: GetTestNow()},
+				{Id: 2, Foo: "fiz", Bar: "buz"},
+			},
+		},
+		{
+			name:           "with insert by unique key",
+			key:            FooEquals("new"),
+			mutators:       []Mutator[*TestSpec]{MutateBar("bar")},
+			expectInserted: true,
+			expectUpserted: &TestSpec{
+				Id:        100,
+				Foo:       "new",
+				Bar:       "bar",
+				UpdatedAt: GetTestNow(),
+				CreatedAt: GetTestNow(),
+			},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "fiz", Bar: "buz"},
+				{
+					Id:        100,
+					Foo:       "new",
+					Bar:       "bar",
+					UpdatedAt: GetTestNow(),
+					CreatedAt: GetTestNow(),
+				},
+			},
+		},
+		{
+			name:           "with tombstone by id",
+			key:            Equals[*TestSpec, int](IdAccessor, 3),
+			mutators:       []Mutator[*TestSpec]{MutateBar("BAR")},
+			expectInserted: true,
+			expectUpserted: &TestSpec{
+				Id:        3,
+				Foo:       "bar",
+				Bar:       "BAR",
+				UpdatedAt: GetTestNow(),
+			},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "fiz", Bar: "buz"},
+				{Id: 3, Foo: "bar", Bar: "BAR", UpdatedAt: GetTestNow()},
+			},
+		},
+		{
+			name:           "with tombstone by unique key",
+			key:            FooEquals("bar"),
+			mutators:       []Mutator[*TestSpec]{MutateBar("BAR")},
+			expectInserted: true,
+			expectUpserted: &TestSpec{
+				Id:        3,
+				Foo:       "bar",
+				Bar:       "BAR",
+				UpdatedAt: GetTestNow(),
+			},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "fiz", Bar: "buz"},
+				{Id: 3, Foo: "bar", Bar: "BAR", UpdatedAt: GetTestNow()},
+			},
+		},
+		{
+			name:           "with expired by id",
+			key:            Equals[*TestSpec, int](IdAccessor, 4),
+			mutators:       []Mutator[*TestSpec]{MutateFoo("new")},
+			expectInserted: true,
+			expectUpserted: &TestSpec{
+				Id:        4,
+				Foo:       "new",
+				UpdatedAt: GetTestNow(),
+				CreatedAt: GetTestNow(),
+			},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "fiz", Bar: "buz"},
+				{
+					Id:        4,
+					Foo:       "new",
+					UpdatedAt: GetTestNow(),
+					CreatedAt: GetTestNow(),
+				},
+			},
+			expectBinLog: [][]string{
+				{
+					`{"transaction":200,"type":"test","op":"begin","ts":"2022-07-06T16:18:00-04:00"}`,
+					`{"transaction":200,"type":"test","id":4,"ts":"2022-07-06T16:18:00-04:00","from":{"id":4,"foo":"baz","bar":"biz","expiresAt":"2022-07-06T15:18:00-04:00"},"to":null}`,
+					`{"transaction":200,"type":"test","id":4,"ts":"2022-07-06T16:18:00-04:00","from":null,"to":{"id":4,"type":"","foo":"new","bar":"","updatedAt":"2022-07-06T16:18:00-04:00","createdAt":"2022-07-06T16:18:00-04:00"}}`,
+					`{"transaction":200,"type":"test","op":"commit","ts":"2022-07-06T16:18:00-04:00"}`,
+				},
+			},
+		},
+		{
+			name:           "with expired by unique key",
+			key:            FooEquals("baz"),
+			mutators:       []Mutator[*TestSpec]{MutateBar("bar")},
+			expectInserted: true,
+			expectUpserted: &TestSpec{
+				Id:        100,
+				Foo:       "baz",
+				Bar:       "bar",
+				UpdatedAt: GetTestNow(),
+				CreatedAt: GetTestNow(),
+			},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "bar"},
+				{Id: 2, Foo: "fiz", Bar: "buz"},
+				{
+					Id:        100,
+					Foo:       "baz",
+					Bar:       "bar",
+					UpdatedAt: GetTestNow(),
+					CreatedAt: GetTestNow(),
+				},
+			},
+		},
+		{
+			name:        "with key not unique",
+			key:         BarEquals("bar"),
+			expectError: "illegal argument error, upsert key must be an Equals filter on the id or a unique index",
+		},
+		{
+			name:        "with key not an equals filter",
+			key:         In[*TestSpec, int](IdAccessor, 1, 2),
+			expectError: "illegal argument error, upsert key must be an Equals filter on the id or a unique index",
+		},
+		{
+			name:     "with insert error",
+			key:      Equals[*TestSpec, int](IdAccessor, 7),
+			mutators: []Mutator[*TestSpec]{MutateFoo("new")},
+			mockError: &mockErr{
+				mockErrType: mockErrTypeInsert,
+				errorOn:     0,
+				msg:         "with insert error",
+			},
+			expectError: "with insert error",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+
+			util := &testUtil{
+				test: t,
+				lines: []string{
+					`{"id":1,"foo":"foo","bar":"bar"}`,
+					`{"id":2,"foo":"fiz","bar":"buz"}`,
+					`{"id":3,"foo":"bar","bar":"baz","deletedAt":"2022-07-01T16:18:00-04:00"}`,
+					`{"id":4,"foo":"baz","bar":"biz","expiresAt":"2022-07-06T15:18:00-04:00"}`,
+				},
+				indexes: []Index[*TestSpec]{
+					NewUniqueIndex[*TestSpec, string](FooAccessor),
+				},
+				mockError:    tc.mockError,
+				expectError:  tc.expectError,
+				filters:      Noop[*TestSpec](),
+				orderBys:     []Lesser[*TestSpec]{OrderById},
+				expect:       tc.expect,
+				expectBinLog: tc.expectBinLog,
+			}
+
+			err = util.setup()
+			defer util.teardown()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			util.stg.deletedAtAccessor = DeletedAtAccessor
+			util.stg.expiresAtAccessor = ExpiresAtAccessor
+
+			got, inserted, err := util.stg.NewUpsertBuilder().
+				On(tc.key).
+				Set(tc.mutators...).
+				Run()
+
+			if done := util.handleExpectError(err); done {
+				return
+			}
+
+			if inserted != tc.expectInserted {
+				t.Errorf(
+					"expected inserted to be %t but got %t",
+					tc.expectInserted,
+					inserted,
+				)
+			}
+
+			if !reflect.DeepEqual(got, tc.expectUpserted) {
+				t.Errorf(
+					"expected upserted to be \n%s\n but got \n%s\n",
+					tc.expectUpserted,
+					got,
+				)
+			}
+
+			util.expectSelect()
+
+			if tc.expectBinLog != nil {
+				util.handleExpectBinLog()
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package obj
+
+import (
+	"context"
+	"fmt"
+)
+
+// OptVersion gives the records a version that inserts start at 1 and every
+// update increments, so that an update can require the version it last read.
+type OptVersion[S any] struct {
+	Value Accessor[S, int64]
+}
+
+func (opt OptVersion[S]) isStorageOpt() bool {
+	return true
+}
+
+// ErrVersionConflict is returned by UpdateIf and by updates with WhereVersion
+// when a record is not at the expected version. Id is the id of the record
+// and Actual the version it is at. Nothing is written when it is returned.
+type ErrVersionConflict struct {
+	Id       any
+	Expected int64
+	Actual   int64
+}
+
+func (err *ErrVersionConflict) Error() string {
+	return fmt.Sprintf(
+		"version conflict error, %v is at version %d but %d was expected",
+		err.Id,
+		err.Actual,
+		err.Expected,
+	)
+}
+
+// UpdateIf updates the record with id when it is still at expectedVersion.
+// found is false when there is no record with id.
+func (stg *storage[I, S]) UpdateIf(
+	id I,
+	expectedVersion int64,
+	mutators []Mutator[S],
+) (updated S, found bool, err error) {
+	return stg.UpdateIfContext(
+		context.Background(),
+		id,
+		expectedVersion,
+		mutators,
+	)
+}
+
+func (stg *storage[I, S]) UpdateIfContext(
+	ctx context.Context,
+	id I,
+	expectedVersion int64,
+	mutators []Mutator[S],
+) (updated S, found bool, err error) {
+	var results []S
+
+	results, err = stg.update(
+		ctx,
+		Equals(stg.idAccessor, id),
+		mutators,
+		nil,
+		optExpectVersion{expectedVersion},
+	)
+	if err != nil || len(results) == 0 {
+		return updated, false, err
+	}
+
+	return results[0], true, nil
+}
+
+// checkVersionSupported fails an update asking for a version when the
+// storage has none.
+func (stg *storage[I, S]) checkVersionSupported(
+	opts []writeControllerOpt,
+) (err error) {
+	if stg.versionAccessor != nil {
+		return nil
+	}
+
+	for _, opt := range opts {
+		if _, ok := opt.(optExpectVersion); ok {
+			return fmt.Errorf(
+				"%w, a version accessor is required to expect a version",
+				illegalArgumentError,
+			)
+		}
+	}
+
+	return nil
+}
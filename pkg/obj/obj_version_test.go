@@ -0,0 +1,230 @@
+package obj
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	type test struct {
+		name                  string
+		noVersion             bool
+		run                   func(stg *storage[int, *TestSpec]) error
+		expectError           *ErrVersionConflict
+		expectIllegalArgument bool
+		expect                []*TestSpec
+	}
+
+	tests := []test{
+		{
+			name: "with insert",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewInsertBuilder().Set(MutateFoo("new")).Run()
+				return err
+			},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Version: 3},
+				{Id: 2, Foo: "fiz", Version: 1},
+				{
+					Id:        100,
+					Foo:       "new",
+					UpdatedAt: GetTestNow(),
+					CreatedAt: GetTestNow(),
+					Version:   1,
+				},
+			},
+		},
+		{
+			name: "with update",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewUpdateBuilder().
+					Where(Noop[*TestSpec]()).
+					Set(MutateBar("BAR")).
+					Run()
+				return err
+			},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "BAR", UpdatedAt: GetTestNow(), Version: 4},
+				{Id: 2, Foo: "fiz", Bar: "BAR", UpdatedAt: GetTestNow(), Version: 2},
+			},
+		},
+		{
+			name: "with update setting the version",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewUpdateBuilder().
+					Where(FooEquals("foo")).
+					Set(NewMutator[*TestSpec, int64](VersionAccessor, 10)).
+					Run()
+				return err
+			},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", UpdatedAt: GetTestNow(), Version: 4},
+				{Id: 2, Foo: "fiz", Version: 1},
+			},
+		},
+		{
+			name: "with where version",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewUpdateBuilder().
+					Where(FooEquals("foo")).
+					WhereVersion(3).
+					Set(MutateBar("BAR")).
+					Run()
+				return err
+			},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Bar: "BAR", UpdatedAt: GetTestNow(), Version: 4},
+				{Id: 2, Foo: "fiz", Version: 1},
+			},
+		},
+		{
+			name: "with where version conflict",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewUpdateBuilder().
+					Where(Noop[*TestSpec]()).
+					WhereVersion(1).
+					Set(MutateBar("BAR")).
+					Run()
+				return err
+			},
+			expectError: &ErrVersionConflict{Id: 1, Expected: 1, Actual: 3},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Version: 3},
+				{Id: 2, Foo: "fiz", Version: 1},
+			},
+		},
+		{
+			name: "with update if",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				var (
+					found   bool
+					updated *TestSpec
+				)
+
+				updated, found, err = stg.UpdateIf(
+					2,
+					1,
+					[]Mutator[*TestSpec]{MutateBar("BAR")},
+				)
+				if err == nil && (!found || updated.Version != 2) {
+					t.Errorf("expected version 2 to be found but got %v", updated)
+				}
+				return err
+			},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Version: 3},
+				{Id: 2, Foo: "fiz", Bar: "BAR", UpdatedAt: GetTestNow(), Version: 2},
+			},
+		},
+		{
+			name: "with update if conflict",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, _, err = stg.UpdateIf(
+					1,
+					2,
+					[]Mutator[*TestSpec]{MutateBar("BAR")},
+				)
+				return err
+			},
+			expectError: &ErrVersionConflict{Id: 1, Expected: 2, Actual: 3},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Version: 3},
+				{Id: 2, Foo: "fiz", Version: 1},
+			},
+		},
+		{
+			name: "with update if not found",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				var found bool
+
+				_, found, err = stg.UpdateIf(
+					7,
+					1,
+					[]Mutator[*TestSpec]{MutateBar("BAR")},
+				)
+				if found {
+					t.Errorf("expected no record to be found")
+				}
+				return err
+			},
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Version: 3},
+				{Id: 2, Foo: "fiz", Version: 1},
+			},
+		},
+		{
+			name:      "with where version without a version accessor",
+			noVersion: true,
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewUpdateBuilder().
+					Where(Noop[*TestSpec]()).
+					WhereVersion(1).
+					Set(MutateBar("BAR")).
+					Run()
+				return err
+			},
+			expectIllegalArgument: true,
+			expect: []*TestSpec{
+				{Id: 1, Foo: "foo", Version: 3},
+				{Id: 2, Foo: "fiz", Version: 1},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				err      error
+				conflict *ErrVersionConflict
+			)
+
+			util := &testUtil{
+				test: t,
+				lines: []string{
+					`{"id":1,"foo":"foo","version":3}`,
+					`{"id":2,"foo":"fiz","version":1}`,
+				},
+				filters:  Noop[*TestSpec](),
+				orderBys: []Lesser[*TestSpec]{OrderById},
+				expect:   tc.expect,
+			}
+
+			err = util.setup()
+			defer util.teardown()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !tc.noVersion {
+				util.stg.versionAccessor = VersionAccessor
+			}
+
+			err = tc.run(util.stg)
+
+			switch {
+			case tc.expectIllegalArgument:
+				if !errors.Is(err, illegalArgumentError) {
+					t.Errorf("expected an illegal argument error but got %v", err)
+				}
+			case tc.expectError != nil:
+				if !errors.As(err, &conflict) {
+					t.Fatalf("expected a version conflict but got %v", err)
+				}
+
+				if !reflect.DeepEqual(conflict, tc.expectError) {
+					t.Errorf(
+						"expected conflict %+v but got %+v",
+						tc.expectError,
+						conflict,
+					)
+				}
+			case err != nil:
+				t.Fatal(err)
+			}
+
+			util.expectSelect()
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package obj
+
+import (
+	"fmt"
+	"sync"
+)
+
+// EventType is the kind of write an Event reports.
+type EventType int
+
+const (
+	EventInsert EventType = iota
+	EventUpdate
+	EventDelete
+)
+
+func (eventType EventType) String() string {
+	switch eventType {
+	case EventInsert:
+		return "insert"
+	case EventUpdate:
+		return "update"
+	case EventDelete:
+		return "delete"
+	}
+
+	return fmt.Sprintf("EventType(%d)", int(eventType))
+}
+
+// Event reports the write of one record by a committed transaction. Before is
+// the zero value for inserts and After for deletes. With OptDeletedAt a soft
+// delete is an EventDelete whose After is the tombstone, and restoring a
+// tombstone is an EventInsert whose Before is the tombstone. The records may
+// be shared with the results of the write and with other subscriptions, so
+// they must not be modified.
+type Event[S any] struct {
+	After         S
+	Before        S
+	TransactionId any
+	Type          EventType
+}
+
+// Subscription delivers the events of a Watch. Events is closed once the
+// subscription is, after which Err tells why.
+type Subscription[S any] interface {
+	Close()
+	Err() error
+	Events() <-chan Event[S]
+}
+
+// ErrSlowConsumer is the Err of a subscription that was closed because it
+// fell a whole buffer behind.
+var ErrSlowConsumer = fmt.Errorf(
+	"slow consumer error, the subscription fell a whole buffer behind",
+)
+
+type OptWatchBufferLen struct {
+	Value int
+}
+
+func (opt OptWatchBufferLen) isStorageOpt() bool {
+	return true
+}
+
+type subscription[S any] struct {
+	ch      chan Event[S]
+	closed  bool
+	err     error
+	filters Matcher[S]
+	lock    sync.Mutex
+	unwatch func(sub *subscription[S])
+}
+
+// Watch subscribes to the writes of every transaction that commits after it
+// returns. Inserts and deletes are delivered when their record matches
+// filters, and updates when the record matches them before or after the
+// update, so that a record leaving the filters is seen. Nil filters match
+// every record. The events of a transaction are delivered together, in the
+// order they were written, once it has committed; nothing is delivered for a
+// transaction that rolls back.
+//
+// Every subscription buffers up to OptWatchBufferLen events. Writes never
+// wait for a subscriber: when an event does not fit in the buffer, the
+// subscription is closed with ErrSlowConsumer and the caller may Watch again
+// and Select to catch up.
+func (stg *storage[I, S]) Watch(filters Matcher[S]) Subscription[S] {
+	// Waiting for the storage lock keeps the subscription from seeing part of
+	// a transaction that is under way.
+	stg.lock.Lock()
+	defer stg.lock.Unlock()
+
+	if filters == nil {
+		filters = Noop[S]()
+	}
+
+	sub := &subscription[S]{
+		ch:      make(chan Event[S], stg.watchBufferLen),
+		filters: filters,
+		unwatch: stg.unwatch,
+	}
+
+	stg.watchLock.Lock()
+	defer stg.watchLock.Unlock()
+
+	stg.watchers[sub] = struct{}{}
+
+	return sub
+}
+
+func (stg *storage[I, S]) unwatch(sub *subscription[S]) {
+	stg.watchLock.Lock()
+	defer stg.watchLock.Unlock()
+
+	delete(stg.watchers, sub)
+}
+
+func (stg *storage[I, S]) watched() bool {
+	stg.watchLock.Lock()
+	defer stg.watchLock.Unlock()
+
+	return len(stg.watchers) > 0
+}
+
+func (stg *storage[I, S]) publish(events []Event[S]) {
+	if len(events) == 0 {
+		return
+	}
+
+	stg.watchLock.Lock()
+	defer stg.watchLock.Unlock()
+
+	for sub := range stg.watchers {
+		if !sub.deliver(events) {
+			delete(stg.watchers, sub)
+		}
+	}
+}
+
+// Close stops the subscription and closes Events. Events already buffered
+// can still be received.
+func (sub *subscription[S]) Close() {
+	sub.unwatch(sub)
+
+	sub.lock.Lock()
+	defer sub.lock.Unlock()
+
+	sub.close(nil)
+}
+
+func (sub *subscription[S]) Err() error {
+	sub.lock.Lock()
+	defer sub.lock.Unlock()
+
+	return sub.err
+}
+
+func (sub *subscription[S]) Events() <-chan Event[S] {
+	return sub.ch
+}
+
+func (sub *subscription[S]) close(err error) {
+	if sub.closed {
+		return
+	}
+
+	sub.closed = true
+	sub.err = err
+	close(sub.ch)
+}
+
+func (sub *subscription[S]) deliver(events []Event[S]) (ok bool) {
+	sub.lock.Lock()
+	defer sub.lock.Unlock()
+
+	if sub.closed {
+		return false
+	}
+
+	for _, event := range events {
+		if !sub.matches(event) {
+			continue
+		}
+
+		select {
+		case sub.ch <- event:
+		default:
+			sub.close(ErrSlowConsumer)
+			return false
+		}
+	}
+
+	return true
+}
+
+func (sub *subscription[S]) matches(event Event[S]) bool {
+	return event.Type != EventInsert && sub.filters.Match(event.Before) ||
+		event.Type != EventDelete && sub.filters.Match(event.After)
+}
+
+// queueEvent keeps an event to publish when the transaction commits. Nothing
+// is kept while no one is watching.
+func (tx *transaction[I, S]) queueEvent(event Event[S]) {
+	if !tx.stg.watched() {
+		return
+	}
+
+	event.TransactionId = tx.binLogTrans.Id()
+	tx.events = append(tx.events, event)
+}
+
+func (tx *transaction[I, S]) queueChanges(
+	msgs []specMsg[S],
+	op op,
+) (err error) {
+	var changes []Change[S]
+
+	if !tx.stg.watched() {
+		return nil
+	}
+
+	if changes, err = tx.stg.changes(msgs, op); err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		tx.queueEvent(Event[S]{
+			After:  change.After,
+			Before: change.Before,
+			Type:   tx.stg.eventType(change, op),
+		})
+	}
+
+	return nil
+}
+
+// eventType tells what a write of change is to a watcher, to whom a record is
+// inserted when it is restored from a tombstone and deleted when it becomes
+// one.
+func (stg *storage[I, S]) eventType(change Change[S], op op) EventType {
+	switch {
+	case op == opDelete:
+		return EventDelete
+	case stg.isDeleted(change.After) && !stg.isDeleted(change.Before):
+		return EventDelete
+	case stg.isDeleted(change.Before) && !stg.isDeleted(change.After):
+		return EventInsert
+	}
+
+	return EventUpdate
+}
@@ -0,0 +1,289 @@
+package obj
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestWatch(t *testing.T) {
+	type test struct {
+		name        string
+		filters     Matcher[*TestSpec]
+		bufferLen   int
+		softDelete  bool
+		run         func(stg *storage[int, *TestSpec]) error
+		expectError error
+		expect      []Event[*TestSpec]
+	}
+
+	var (
+		foo = &TestSpec{Id: 1, Foo: "foo", Bar: "bar"}
+		fiz = &TestSpec{Id: 2, Foo: "fiz", Bar: "buz"}
+		now = GetTestNow()
+
+		deletedFiz = &TestSpec{
+			Id:        2,
+			Foo:       "fiz",
+			Bar:       "buz",
+			UpdatedAt: now,
+			DeletedAt: &now,
+		}
+		restoredFiz = &TestSpec{Id: 2, Foo: "fiz", Bar: "buz", UpdatedAt: now}
+	)
+
+	tests := []test{
+		{
+			name:    "with insert",
+			filters: Noop[*TestSpec](),
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewInsertBuilder().Set(MutateFoo("new")).Run()
+				return err
+			},
+			expect: []Event[*TestSpec]{
+				{
+					After: &TestSpec{
+						Id:        100,
+						Foo:       "new",
+						UpdatedAt: GetTestNow(),
+						CreatedAt: GetTestNow(),
+					},
+					TransactionId: 200,
+					Type:          EventInsert,
+				},
+			},
+		},
+		{
+			name:    "with insert many",
+			filters: Noop[*TestSpec](),
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewInsertBuilder().
+					Add(MutateFoo("a")).
+					Add(MutateFoo("b")).
+					RunMany()
+				return err
+			},
+			expect: []Event[*TestSpec]{
+				{
+					After: &TestSpec{
+						Id:        100,
+						Foo:       "a",
+						UpdatedAt: GetTestNow(),
+						CreatedAt: GetTestNow(),
+					},
+					TransactionId: 200,
+					Type:          EventInsert,
+				},
+				{
+					After: &TestSpec{
+						Id:        101,
+						Foo:       "b",
+						UpdatedAt: GetTestNow(),
+						CreatedAt: GetTestNow(),
+					},
+					TransactionId: 200,
+					Type:          EventInsert,
+				},
+			},
+		},
+		{
+			name:    "with update leaving the filters",
+			filters: BarEquals("bar"),
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewUpdateBuilder().
+					Where(Noop[*TestSpec]()).
+					Set(MutateBar("BAR")).
+					Run()
+				return err
+			},
+			expect: []Event[*TestSpec]{
+				{
+					After: &TestSpec{
+						Id:        1,
+						Foo:       "foo",
+						Bar:       "BAR",
+						UpdatedAt: GetTestNow(),
+					},
+					Before:        foo,
+					TransactionId: 200,
+					Type:          EventUpdate,
+				},
+			},
+		},
+		{
+			name:    "with delete",
+			filters: FooEquals("fiz"),
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewDeleteBuilder().Where(Noop[*TestSpec]()).Run()
+				return err
+			},
+			expect: []Event[*TestSpec]{
+				{Before: fiz, TransactionId: 200, Type: EventDelete},
+			},
+		},
+		{
+			name:    "with transaction",
+			filters: Noop[*TestSpec](),
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				tx := stg.Begin()
+				if _, err = tx.Delete(FooEquals("foo")); err != nil {
+					return err
+				}
+				if _, err = tx.Insert(
+					[]Mutator[*TestSpec]{MutateFoo("foo")},
+				); err != nil {
+					return err
+				}
+				return tx.Commit()
+			},
+			expect: []Event[*TestSpec]{
+				{Before: foo, TransactionId: 200, Type: EventDelete},
+				{
+					After: &TestSpec{
+						Id:        100,
+						Foo:       "foo",
+						UpdatedAt: GetTestNow(),
+						CreatedAt: GetTestNow(),
+					},
+					TransactionId: 200,
+					Type:          EventInsert,
+				},
+			},
+		},
+		{
+			name:    "with rollback",
+			filters: Noop[*TestSpec](),
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				tx := stg.Begin()
+				if _, err = tx.Delete(FooEquals("foo")); err != nil {
+					return err
+				}
+				return tx.Rollback()
+			},
+			expect: []Event[*TestSpec]{},
+		},
+		{
+			name:    "with failed write",
+			filters: Noop[*TestSpec](),
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewInsertBuilder().
+					Add(MutateFoo("fiz")).
+					RunMany()
+				if err == nil {
+					t.Errorf("expected a unique violation")
+				}
+				return nil
+			},
+			expect: []Event[*TestSpec]{},
+		},
+		{
+			name:       "with soft delete and undelete",
+			filters:    BarEquals("buz"),
+			softDelete: true,
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				if _, err = stg.NewDeleteBuilder().
+					Where(FooEquals("fiz")).
+					Run(); err != nil {
+					return err
+				}
+				_, err = stg.Undelete(FooEquals("fiz"))
+				return err
+			},
+			expect: []Event[*TestSpec]{
+				{
+					After:         deletedFiz,
+					Before:        fiz,
+					TransactionId: 200,
+					Type:          EventDelete,
+				},
+				{
+					After:         restoredFiz,
+					Before:        deletedFiz,
+					TransactionId: 201,
+					Type:          EventInsert,
+				},
+			},
+		},
+		{
+			name: "with nil filters",
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewDeleteBuilder().Where(FooEquals("fiz")).Run()
+				return err
+			},
+			expect: []Event[*TestSpec]{
+				{Before: fiz, TransactionId: 200, Type: EventDelete},
+			},
+		},
+		{
+			name:      "with slow consumer",
+			filters:   Noop[*TestSpec](),
+			bufferLen: 1,
+			run: func(stg *storage[int, *TestSpec]) (err error) {
+				_, err = stg.NewDeleteBuilder().Where(Noop[*TestSpec]()).Run()
+				return err
+			},
+			expectError: ErrSlowConsumer,
+			expect: []Event[*TestSpec]{
+				{Before: foo, TransactionId: 200, Type: EventDelete},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+
+			util := &testUtil{
+				test: t,
+				lines: []string{
+					`{"id":1,"foo":"foo","bar":"bar"}`,
+					`{"id":2,"foo":"fiz","bar":"buz"}`,
+				},
+				indexes: []Index[*TestSpec]{
+					NewUniqueIndex[*TestSpec, string](FooAccessor),
+				},
+			}
+
+			err = util.setup()
+			defer util.teardown()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// A single worker writes the records in the order of the file so
+			// that it is known which of them the slow consumer gets.
+			if tc.softDelete {
+				util.stg.deletedAtAccessor = DeletedAtAccessor
+			}
+
+			if tc.bufferLen != 0 {
+				util.stg.concurrency = 1
+				util.stg.watchBufferLen = tc.bufferLen
+			}
+
+			sub := util.stg.Watch(tc.filters)
+
+			if err = tc.run(util.stg); err != nil {
+				t.Fatal(err)
+			}
+
+			if tc.expectError == nil {
+				sub.Close()
+			}
+
+			got := []Event[*TestSpec]{}
+			for event := range sub.Events() {
+				got = append(got, event)
+			}
+
+			if !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("expected events\n%v\nbut got\n%v", tc.expect, got)
+			}
+
+			if !errors.Is(sub.Err(), tc.expectError) {
+				t.Errorf("expected error %v but got %v", tc.expectError, sub.Err())
+			}
+		})
+	}
+}
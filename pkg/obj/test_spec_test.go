@@ -3,16 +3,22 @@ package obj
 import (
 	"encoding/json"
 	"fmt"
+	"testing"
 	"time"
+
+	"github.com/yo3jones/stg/pkg/jsonl"
 )
 
 type TestSpec struct {
-	Id        int       `json:"id"`
-	Type      string    `json:"type"`
-	Foo       string    `json:"foo"`
-	Bar       string    `json:"bar"`
-	UpdatedAt time.Time `json:"updatedAt"`
-	CreatedAt time.Time `json:"createdAt"`
+	Id        int        `json:"id"`
+	Type      string     `json:"type"`
+	Foo       string     `json:"foo"`
+	Bar       string     `json:"bar"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+	Version   int64      `json:"version,omitempty"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
 }
 
 func (spec *TestSpec) GetId() int {
@@ -52,12 +58,23 @@ func GetTestNow() time.Time {
 	return now
 }
 
+func parseTestTime(t *testing.T, value string) time.Time {
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
 var (
 	IdAccessor        = &idAccessor{}
 	FooAccessor       = &fooAccessor{}
 	BarAccessor       = &barAccessor{}
 	UpdatedAtAccessor = &updatedAtAccessor{}
 	CreatedAtAccessor = &createdAtAccessor{}
+	VersionAccessor   = &versionAccessor{}
+	DeletedAtAccessor = &deletedAtAccessor{}
+	ExpiresAtAccessor = &expiresAtAccessor{}
 )
 
 var (
@@ -110,6 +127,62 @@ func (*createdAtAccessor) Set(s *TestSpec, v time.Time) {
 	s.CreatedAt = v
 }
 
+type versionAccessor struct{}
+
+func (*versionAccessor) Get(s *TestSpec) int64 {
+	return s.Version
+}
+
+func (*versionAccessor) Name() string {
+	return "version"
+}
+
+func (*versionAccessor) Set(s *TestSpec, v int64) {
+	s.Version = v
+}
+
+type deletedAtAccessor struct{}
+
+func (*deletedAtAccessor) Get(s *TestSpec) time.Time {
+	if s.DeletedAt == nil {
+		return time.Time{}
+	}
+	return *s.DeletedAt
+}
+
+func (*deletedAtAccessor) Name() string {
+	return "deletedAt"
+}
+
+func (*deletedAtAccessor) Set(s *TestSpec, v time.Time) {
+	if v.IsZero() {
+		s.DeletedAt = nil
+		return
+	}
+	s.DeletedAt = &v
+}
+
+type expiresAtAccessor struct{}
+
+func (*expiresAtAccessor) Get(s *TestSpec) time.Time {
+	if s.ExpiresAt == nil {
+		return time.Time{}
+	}
+	return *s.ExpiresAt
+}
+
+func (*expiresAtAccessor) Name() string {
+	return "expiresAt"
+}
+
+func (*expiresAtAccessor) Set(s *TestSpec, v time.Time) {
+	if v.IsZero() {
+		s.ExpiresAt = nil
+		return
+	}
+	s.ExpiresAt = &v
+}
+
 type fooAccessor struct{}
 
 func (*fooAccessor) Get(s *TestSpec) string {
@@ -184,3 +257,15 @@ func (marshalUnmarshaller *testMarshalUnmarshaller[S]) Marshal(
 func (*testMarshalUnmarshaller[S]) Unmarshal(data []byte, v S) error {
 	return json.Unmarshal(data, v)
 }
+
+func (*testMarshalUnmarshaller[S]) UnmarshalFields(
+	data []byte,
+	v S,
+	fields []string,
+) error {
+	return (&jsonl.JsonlMarshalUnmarshaller[S]{}).UnmarshalFields(
+		data,
+		v,
+		fields,
+	)
+}
@@ -1,6 +1,7 @@
 package obj
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -21,7 +22,9 @@ type testUtil struct {
 	stg          *storage[int, *TestSpec]
 	test         *testing.T
 	lines        []string
+	binLogLines  []string
 	filters      Matcher[*TestSpec]
+	indexes      []Index[*TestSpec]
 	orderBys     []Lesser[*TestSpec]
 	mutators     []Mutator[*TestSpec]
 	bufferLen    int
@@ -30,6 +33,7 @@ type testUtil struct {
 	expect       []*TestSpec
 	expectLines  [][]string
 	expectBinLog [][]string
+	expectCount  int
 }
 
 func (util *testUtil) setup() (err error) {
@@ -73,6 +77,7 @@ func (util *testUtil) setup() (err error) {
 		factory:           &TestSpecFactory{},
 		idAccessor:        IdAccessor,
 		idFactory:         &testIdFactory{100},
+		indexes:           util.indexes,
 		nower:             &TestNower{},
 		objType:           "test",
 		stg:               util.fstlnstg,
@@ -80,16 +85,31 @@ func (util *testUtil) setup() (err error) {
 			mockErr: util.mockError,
 		},
 		updatedAtAccessor: UpdatedAtAccessor,
+		watchBufferLen:    100,
+		watchers:          map[*subscription[*TestSpec]]struct{}{},
 	}
 
 	if err = util.writeLines(util.lines); err != nil {
 		return err
 	}
 
+	for _, line := range util.binLogLines {
+		if _, err = fmt.Fprintf(util.logFile, "%s\n", line); err != nil {
+			return err
+		}
+	}
+
 	if err = fstlnstg.ResetScan(); err != nil {
 		return err
 	}
 
+	// Indexes are built around the mock so that its errors and call counts
+	// are left for the test. Lines that cannot be unmarshalled are left for
+	// the test to trip over as well.
+	util.stg.stg = fstlnstg
+	util.stg.buildIndexes()
+	util.stg.stg = util.fstlnstg
+
 	return nil
 }
 
@@ -225,6 +245,29 @@ func (util *testUtil) expectUpdate() {
 	util.handleExpectBinLog()
 }
 
+func (util *testUtil) expectRecover() {
+	var (
+		err       error
+		recovered int
+	)
+
+	recovered, err = util.stg.Recover()
+
+	if done := util.handleExpectError(err); done {
+		return
+	}
+
+	if recovered != util.expectCount {
+		util.test.Errorf(
+			"expected %d records to be recovered but got %d",
+			util.expectCount,
+			recovered,
+		)
+	}
+
+	util.handleExpectLines()
+}
+
 func (util *testUtil) expectSpecs(got ...*TestSpec) {
 	if !reflect.DeepEqual(got, util.expect) {
 		util.test.Errorf(
@@ -352,6 +395,13 @@ func (mock *mockStg) Read(
 	return mock.stg.Read(line)
 }
 
+func (mock *mockStg) ReadAt(pos fstln.Position) (line []byte, err error) {
+	if err = mock.handleMockError(mockErrTypeReadAt); err != nil {
+		return nil, err
+	}
+	return mock.stg.ReadAt(pos)
+}
+
 func (mock *mockStg) ResetScan() (err error) {
 	if err = mock.handleMockError(mockErrTypeResetScan); err != nil {
 		return err
@@ -369,6 +419,89 @@ func (mock *mockStg) Update(
 	return mock.stg.Update(pos, line)
 }
 
+func (mock *mockStg) DeleteContext(
+	ctx context.Context,
+	pos fstln.Position,
+) (err error) {
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	return mock.Delete(pos)
+}
+
+func (mock *mockStg) InsertContext(
+	ctx context.Context,
+	line []byte,
+) (pos fstln.Position, err error) {
+	if err = ctx.Err(); err != nil {
+		return pos, err
+	}
+	return mock.Insert(line)
+}
+
+func (mock *mockStg) InsertMany(
+	lines [][]byte,
+) (positions []fstln.Position, err error) {
+	if err = mock.handleMockError(mockErrTypeInsert); err != nil {
+		return nil, err
+	}
+	return mock.stg.InsertMany(lines)
+}
+
+func (mock *mockStg) InsertManyContext(
+	ctx context.Context,
+	lines [][]byte,
+) (positions []fstln.Position, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	return mock.InsertMany(lines)
+}
+
+func (mock *mockStg) MaintenanceContext(
+	ctx context.Context,
+) (freed int, err error) {
+	return mock.stg.MaintenanceContext(ctx)
+}
+
+func (mock *mockStg) ReadContext(
+	ctx context.Context,
+	line []byte,
+) (pos fstln.Position, n int, isPrefix bool, err error) {
+	if err = ctx.Err(); err != nil {
+		return pos, n, isPrefix, err
+	}
+	return mock.Read(line)
+}
+
+func (mock *mockStg) ReadAtContext(
+	ctx context.Context,
+	pos fstln.Position,
+) (line []byte, err error) {
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	return mock.ReadAt(pos)
+}
+
+func (mock *mockStg) ResetScanContext(ctx context.Context) (err error) {
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	return mock.ResetScan()
+}
+
+func (mock *mockStg) UpdateContext(
+	ctx context.Context,
+	pos fstln.Position,
+	line []byte,
+) (afterPos fstln.Position, err error) {
+	if err = ctx.Err(); err != nil {
+		return afterPos, err
+	}
+	return mock.Update(pos, line)
+}
+
 func (mock *mockStg) getCallCount(t mockErrType) int {
 	if mock.callCounts == nil {
 		mock.callCounts = map[mockErrType]int{}
@@ -416,6 +549,16 @@ type mockBinLogStroage struct {
 	mockErr *mockErr
 }
 
+func (mock *mockBinLogStroage) Scan(
+	objType string,
+	fn func(entry objbinlog.Entry) error,
+) (err error) {
+	if mock.mockErr != nil && mock.mockErr.mockErrType == mockErrTypeScan {
+		return fmt.Errorf("%s", mock.mockErr.msg)
+	}
+	return mock.stg.Scan(objType, fn)
+}
+
 func (mock *mockBinLogStroage) StartTransaction(
 	objType string,
 ) objbinlog.Transaction {
@@ -431,8 +574,16 @@ type mockTransaction struct {
 	callCount   int
 }
 
-func (mock *mockTransaction) End() {
-	mock.transaction.End()
+func (mock *mockTransaction) Abort() (err error) {
+	return mock.transaction.Abort()
+}
+
+func (mock *mockTransaction) End() (err error) {
+	return mock.transaction.End()
+}
+
+func (mock *mockTransaction) Id() any {
+	return mock.transaction.Id()
 }
 
 func (mock *mockTransaction) LogDelete(id any, from []byte) (err error) {
@@ -481,4 +632,6 @@ const (
 	mockErrTypeDelete
 	mockErrTypeUpdate
 	mockErrTypeBinLog
+	mockErrTypeScan
+	mockErrTypeReadAt
 )
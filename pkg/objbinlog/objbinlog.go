@@ -1,6 +1,7 @@
 package objbinlog
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
@@ -8,11 +9,14 @@ import (
 )
 
 type BinLogStorage interface {
+	Scan(objType string, fn func(entry Entry) error) (err error)
 	StartTransaction(objType string) Transaction
 }
 
 type Transaction interface {
-	End()
+	Abort() (err error)
+	End() (err error)
+	Id() any
 	LogDelete(id any, from []byte) (err error)
 	LogInsert(id any, to []byte) (err error)
 	LogUpdate(id any, from, to []byte) (err error)
@@ -23,11 +27,16 @@ type binLogStorage[T comparable] struct {
 	idFactory           stg.IdFactory[T]
 	lock                sync.Mutex
 	marshalUnmarshaller stg.MarshalUnmarshaller[any]
+	maxSegmentAge       time.Duration
+	maxSegmentSize      int64
 	nower               stg.Nower
+	retainSegments      int
+	segments            *segments[T]
 	writeLock           sync.Mutex
 }
 
 type transaction[T comparable] struct {
+	begun         bool
 	ended         bool
 	objType       string
 	stg           *binLogStorage[T]
@@ -41,18 +50,34 @@ func New[T comparable](
 	marshalUnmarshaller stg.MarshalUnmarshaller[any],
 	opts ...OptBinLogStorage,
 ) BinLogStorage {
+	return newBinLogStorage(handle, idFactory, marshalUnmarshaller, opts...)
+}
+
+func newBinLogStorage[T comparable](
+	handle stg.Handle,
+	idFactory stg.IdFactory[T],
+	marshalUnmarshaller stg.MarshalUnmarshaller[any],
+	opts ...OptBinLogStorage,
+) *binLogStorage[T] {
 	stg := &binLogStorage[T]{
 		handle:              handle,
 		idFactory:           idFactory,
 		marshalUnmarshaller: marshalUnmarshaller,
 		nower:               stg.NewNower(),
+		retainSegments:      1,
 	}
 
 	for _, opt := range opts {
 		opt.isBinLogStorageOpt()
 		switch opt := opt.(type) {
+		case OptMaxSegmentAge:
+			stg.maxSegmentAge = opt.Value
+		case OptMaxSegmentSize:
+			stg.maxSegmentSize = opt.Value
 		case OptNower:
 			stg.nower = opt.Value
+		case OptRetainSegments:
+			stg.retainSegments = opt.Value
 		}
 	}
 
@@ -80,3 +105,11 @@ type OptNower struct {
 func (opt OptNower) isBinLogStorageOpt() bool {
 	return true
 }
+
+// ErrIllegalArgument is wrapped by the errors reporting an option or argument
+// the bin log cannot work with.
+var ErrIllegalArgument = fmt.Errorf("illegal argument error")
+
+// ErrIllegalState is wrapped by the errors reporting a bin log, segment or
+// transaction that is not in a state the call can work with.
+var ErrIllegalState = fmt.Errorf("illegal state error")
@@ -0,0 +1,428 @@
+package objbinlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/yo3jones/stg/pkg/stg"
+)
+
+// Follower reads a bin log like tail -f, possibly from another process than
+// the one writing it. Next returns the logs and markers appended to the bin
+// log in order and waits for more once it has read them all; a Follower of a
+// segmented bin log moves on to the next segment of the manifest once it has
+// read the current one to the end. Checkpoint saves how far the logs returned
+// so far go, so that a Follower created over the same checkpoint after a
+// restart picks up right after them. Logs returned after the last Checkpoint
+// are returned again after a restart, so they should be applied idempotently.
+type Follower[T comparable] interface {
+	Checkpoint() (err error)
+	Next(ctx context.Context) (log *Log[T], err error)
+	Offset() int64
+	Segment() string
+}
+
+// CheckpointStore keeps the checkpoint of a Follower or a Replica. Save
+// replaces the checkpoint in a single step, so that a crash leaves either the
+// old or the new one, and Load returns no data until the first Save.
+type CheckpointStore interface {
+	Load() (data []byte, err error)
+	Save(data []byte) (err error)
+}
+
+// NewFileCheckpointStore keeps a checkpoint in the file at path. Save writes
+// a temporary file next to it and renames it over path.
+func NewFileCheckpointStore(path string) CheckpointStore {
+	return fileCheckpointStore(path)
+}
+
+type fileCheckpointStore string
+
+func (path fileCheckpointStore) Load() (data []byte, err error) {
+	if data, err = os.ReadFile(string(path)); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	return data, err
+}
+
+func (path fileCheckpointStore) Save(data []byte) (err error) {
+	return writeFileAtomic(string(path), data)
+}
+
+type follower[T comparable] struct {
+	checkpoint          CheckpointStore
+	factory             SegmentFactory
+	handle              stg.Handle
+	marshalUnmarshaller stg.MarshalUnmarshaller[any]
+	objType             string
+	pollInterval        time.Duration
+	reader              *reader[T]
+	segment             string
+}
+
+type followerCheckpoint struct {
+	Segment string `json:"segment,omitempty"`
+	Offset  int64  `json:"offset"`
+}
+
+// NewFollower follows the bin log in handle from the offset saved in
+// checkpoint, or from the start when checkpoint is empty. The follower only
+// reads handle.
+func NewFollower[T comparable](
+	handle stg.Handle,
+	checkpoint CheckpointStore,
+	marshalUnmarshaller stg.MarshalUnmarshaller[any],
+	opts ...OptFollower,
+) (Follower[T], error) {
+	var (
+		err      error
+		follower *follower[T]
+		saved    followerCheckpoint
+	)
+
+	if err = loadCheckpoint(checkpoint, marshalUnmarshaller, &saved); err != nil {
+		return nil, err
+	}
+
+	if follower, err = newFollower[T](marshalUnmarshaller, opts...); err != nil {
+		return nil, err
+	}
+	follower.checkpoint = checkpoint
+	follower.follow(handle, "", saved.Offset)
+
+	return follower, nil
+}
+
+// NewSegmentedFollower follows the segmented bin log of factory from the
+// segment and offset saved in checkpoint, or from the start of its oldest
+// segment when checkpoint is empty. The follower only reads the segments and
+// the manifest. A segment pruned before the follower has read it to the end
+// makes Next fail.
+func NewSegmentedFollower[T comparable](
+	factory SegmentFactory,
+	checkpoint CheckpointStore,
+	marshalUnmarshaller stg.MarshalUnmarshaller[any],
+	opts ...OptFollower,
+) (Follower[T], error) {
+	var (
+		err      error
+		follower *follower[T]
+		saved    followerCheckpoint
+	)
+
+	if err = loadCheckpoint(checkpoint, marshalUnmarshaller, &saved); err != nil {
+		return nil, err
+	}
+
+	follower, err = newSegmentedFollower[T](
+		factory,
+		saved,
+		marshalUnmarshaller,
+		opts...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	follower.checkpoint = checkpoint
+
+	return follower, nil
+}
+
+func newFollower[T comparable](
+	marshalUnmarshaller stg.MarshalUnmarshaller[any],
+	opts ...OptFollower,
+) (*follower[T], error) {
+	follower := &follower[T]{
+		marshalUnmarshaller: marshalUnmarshaller,
+		pollInterval:        100 * time.Millisecond,
+	}
+
+	for _, opt := range opts {
+		opt.isFollowerOpt()
+		switch opt := opt.(type) {
+		case OptObjType:
+			follower.objType = opt.Value
+		case OptPollInterval:
+			follower.pollInterval = opt.Value
+		}
+	}
+
+	if follower.pollInterval <= 0 {
+		return nil, fmt.Errorf(
+			"%w, poll interval must be positive but got %s",
+			ErrIllegalArgument,
+			follower.pollInterval,
+		)
+	}
+
+	return follower, nil
+}
+
+func newSegmentedFollower[T comparable](
+	factory SegmentFactory,
+	saved followerCheckpoint,
+	marshalUnmarshaller stg.MarshalUnmarshaller[any],
+	opts ...OptFollower,
+) (follower *follower[T], err error) {
+	var read manifest[T]
+
+	if follower, err = newFollower[T](marshalUnmarshaller, opts...); err != nil {
+		return nil, err
+	}
+	follower.factory = factory
+
+	if saved.Segment == "" {
+		return follower, nil
+	}
+
+	if read, err = readManifest[T](factory, marshalUnmarshaller); err != nil {
+		return nil, err
+	}
+
+	if segmentIndex(read.Segments, saved.Segment) < 0 {
+		return nil, fmt.Errorf(
+			"%w, checkpointed segment %s is no longer in the manifest",
+			ErrIllegalState,
+			saved.Segment,
+		)
+	}
+
+	if err = follower.openSegment(saved.Segment, saved.Offset); err != nil {
+		return nil, err
+	}
+
+	return follower, nil
+}
+
+// loadCheckpoint unmarshals the checkpoint held by store into v, leaving v
+// untouched when store holds none yet.
+func loadCheckpoint(
+	store CheckpointStore,
+	marshalUnmarshaller stg.MarshalUnmarshaller[any],
+	v any,
+) (err error) {
+	var data []byte
+
+	if data, err = store.Load(); err != nil {
+		return err
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	return marshalUnmarshaller.Unmarshal(data, v)
+}
+
+func (follower *follower[T]) Checkpoint() (err error) {
+	var data []byte
+
+	data, err = follower.marshalUnmarshaller.Marshal(follower.position())
+	if err != nil {
+		return err
+	}
+
+	return follower.checkpoint.Save(data)
+}
+
+// Next returns the next log or marker, waiting for one to be appended when
+// all of them have been read. It returns the error of ctx once ctx is done,
+// and an error wrapping ErrIllegalState when the bin log has become shorter
+// than what was already read, as it does when it is replaced, or when the
+// segment being read was pruned.
+func (follower *follower[T]) Next(
+	ctx context.Context,
+) (log *Log[T], err error) {
+	var next string
+
+	for {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if follower.handle != nil {
+			if log, err = follower.reader.Next(); err != io.EOF {
+				return log, err
+			}
+
+			if err = follower.checkSize(); err != nil {
+				return nil, err
+			}
+		}
+
+		if follower.factory != nil {
+			if next, err = follower.nextSegment(); err != nil {
+				return nil, err
+			}
+		}
+
+		if next != "" {
+			// A new segment is only started once the bin log is done writing
+			// the current one, but its last lines may have come in after it
+			// was read to the end above.
+			if follower.handle != nil {
+				if log, err = follower.reader.Next(); err != io.EOF {
+					return log, err
+				}
+			}
+
+			if err = follower.openSegment(next, 0); err != nil {
+				return nil, err
+			}
+			next = ""
+			continue
+		}
+
+		timer := time.NewTimer(follower.pollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Offset is where the log after the last one returned by Next starts in the
+// segment named by Segment.
+func (follower *follower[T]) Offset() int64 {
+	if follower.reader == nil {
+		return 0
+	}
+
+	return follower.reader.offset
+}
+
+// Segment is the name of the segment being read, or empty when the follower
+// reads a single handle or no segment has been started yet.
+func (follower *follower[T]) Segment() string {
+	return follower.segment
+}
+
+func (follower *follower[T]) position() followerCheckpoint {
+	return followerCheckpoint{
+		Segment: follower.segment,
+		Offset:  follower.Offset(),
+	}
+}
+
+func (follower *follower[T]) follow(
+	handle stg.Handle,
+	segment string,
+	offset int64,
+) {
+	follower.handle = handle
+	follower.segment = segment
+	follower.reader = &reader[T]{
+		handle:              handle,
+		marshalUnmarshaller: follower.marshalUnmarshaller,
+		objType:             follower.objType,
+		offset:              offset,
+	}
+}
+
+// openSegment moves on to the named segment, closing the one read so far.
+func (follower *follower[T]) openSegment(
+	name string,
+	offset int64,
+) (err error) {
+	var handle stg.Handle
+
+	if handle, err = follower.factory.Open(name); err != nil {
+		return err
+	}
+
+	closeHandles(follower.handle)
+	follower.follow(handle, name, offset)
+
+	return nil
+}
+
+// nextSegment returns the name of the segment after the one being read, or
+// of the oldest one when none is read yet, and an empty name when there is
+// none.
+func (follower *follower[T]) nextSegment() (next string, err error) {
+	read, err := readManifest[T](
+		follower.factory,
+		follower.marshalUnmarshaller,
+	)
+	if err != nil {
+		return "", err
+	}
+	list := read.Segments
+
+	if follower.segment == "" {
+		if len(list) == 0 {
+			return "", nil
+		}
+		return list[0].Name, nil
+	}
+
+	i := segmentIndex(list, follower.segment)
+	if i < 0 {
+		return "", fmt.Errorf(
+			"%w, segment %s was pruned before it was read to the end",
+			ErrIllegalState,
+			follower.segment,
+		)
+	}
+
+	if i == len(list)-1 {
+		return "", nil
+	}
+
+	return list[i+1].Name, nil
+}
+
+func (follower *follower[T]) checkSize() (err error) {
+	var size int64
+
+	if size, err = follower.handle.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+
+	if size < follower.reader.offset {
+		return fmt.Errorf(
+			"%w, bin log is %d bytes but %d were already read",
+			ErrIllegalState,
+			size,
+			follower.reader.offset,
+		)
+	}
+
+	return nil
+}
+
+func segmentIndex[T comparable](list []Segment[T], name string) int {
+	for i, segment := range list {
+		if segment.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+type OptFollower interface {
+	isFollowerOpt() bool
+}
+
+func (opt OptObjType) isFollowerOpt() bool {
+	return true
+}
+
+// OptPollInterval is how long a Follower waits before looking for new logs
+// once it has read them all.
+type OptPollInterval struct {
+	Value time.Duration
+}
+
+func (opt OptPollInterval) isFollowerOpt() bool {
+	return true
+}
@@ -0,0 +1,435 @@
+package objbinlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFollower(t *testing.T) {
+	type test struct {
+		name          string
+		opts          []OptFollower
+		expect        []string
+		expectWaited  []string
+		expectResumed []string
+	}
+
+	tests := []test{
+		{
+			name: "with every type",
+			expect: []string{
+				"0 test begin <nil>",
+				"0 test  1",
+				"0 test commit <nil>",
+			},
+			expectWaited: []string{
+				"1 other begin <nil>",
+				"1 other  2",
+				"1 other commit <nil>",
+			},
+			expectResumed: []string{
+				"2 test begin <nil>",
+				"2 test  3",
+				"2 test commit <nil>",
+			},
+		},
+		{
+			name: "with obj type",
+			opts: []OptFollower{OptObjType{"test"}},
+			expect: []string{
+				"0 test begin <nil>",
+				"0 test  1",
+				"0 test commit <nil>",
+			},
+			expectWaited: []string{},
+			expectResumed: []string{
+				"2 test begin <nil>",
+				"2 test  3",
+				"2 test commit <nil>",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				err      error
+				follower Follower[int]
+				opts     = append(
+					[]OptFollower{OptPollInterval{time.Millisecond}},
+					tc.opts...,
+				)
+			)
+
+			helper := &followerHelper{t: t}
+			defer helper.teardown()
+
+			stg := helper.setup()
+
+			helper.write(stg, "test", 1)
+
+			if follower, err = helper.follow(opts...); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := helper.next(follower, len(tc.expect)); !reflect.DeepEqual(
+				got,
+				tc.expect,
+			) {
+				t.Errorf("expected logs \n%q\n but got \n%q\n", tc.expect, got)
+			}
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				time.Sleep(10 * time.Millisecond)
+				helper.write(stg, "other", 2)
+			}()
+
+			if got := helper.next(
+				follower,
+				len(tc.expectWaited),
+			); !reflect.DeepEqual(got, tc.expectWaited) {
+				t.Errorf(
+					"expected waited logs \n%q\n but got \n%q\n",
+					tc.expectWaited,
+					got,
+				)
+			}
+			<-done
+
+			ctx, cancel := context.WithTimeout(
+				context.Background(),
+				10*time.Millisecond,
+			)
+			defer cancel()
+
+			if _, err = follower.Next(ctx); !errors.Is(
+				err,
+				context.DeadlineExceeded,
+			) {
+				t.Errorf("expected the deadline to be exceeded but got %v", err)
+			}
+
+			if err = follower.Checkpoint(); err != nil {
+				t.Fatal(err)
+			}
+
+			helper.write(stg, "test", 3)
+
+			// A new follower over the same checkpoint picks up after the logs
+			// the first one checkpointed.
+			if follower, err = helper.follow(opts...); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := helper.next(
+				follower,
+				len(tc.expectResumed),
+			); !reflect.DeepEqual(got, tc.expectResumed) {
+				t.Errorf(
+					"expected resumed logs \n%q\n but got \n%q\n",
+					tc.expectResumed,
+					got,
+				)
+			}
+		})
+	}
+}
+
+func TestFollowerTruncated(t *testing.T) {
+	var (
+		err      error
+		follower Follower[int]
+	)
+
+	helper := &followerHelper{t: t}
+	defer helper.teardown()
+
+	stg := helper.setup()
+
+	helper.write(stg, "test", 1)
+
+	if follower, err = helper.follow(OptPollInterval{time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+
+	helper.next(follower, 3)
+
+	if err = helper.file.Truncate(0); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := fmt.Sprintf(
+		"illegal state error, bin log is 0 bytes but %d were already read",
+		follower.Offset(),
+	)
+	if _, err = follower.Next(context.Background()); !errors.Is(
+		err,
+		ErrIllegalState,
+	) || err.Error() != expect {
+		t.Errorf("expected error %s but got %v", expect, err)
+	}
+}
+
+func TestFollowerInvalidPollInterval(t *testing.T) {
+	helper := &followerHelper{t: t}
+	defer helper.teardown()
+
+	helper.setup()
+
+	expect := "illegal argument error, poll interval must be positive but got 0s"
+	if _, err := helper.follow(OptPollInterval{0}); !errors.Is(
+		err,
+		ErrIllegalArgument,
+	) || err.Error() != expect {
+		t.Errorf("expected error %s but got %v", expect, err)
+	}
+}
+
+func TestSegmentedFollower(t *testing.T) {
+	var (
+		err      error
+		follower Follower[int]
+	)
+
+	os.RemoveAll("test_segments")
+	os.MkdirAll("test_segments", 0755)
+	defer os.RemoveAll("test_segments")
+	defer os.Remove("test_checkpoint.json")
+
+	factory := NewFileSegmentFactory("test_segments")
+	checkpoint := NewFileCheckpointStore("test_checkpoint.json")
+	opts := []OptFollower{OptPollInterval{time.Millisecond}}
+	helper := &followerHelper{t: t}
+
+	stg, err := NewSegmented[int](
+		factory,
+		&testIdFactory{},
+		&testMarshalUnmarshaller{},
+		OptNower{&testNower{}},
+		OptMaxSegmentSize{1},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if follower, err = NewSegmentedFollower[int](
+		factory,
+		checkpoint,
+		&testMarshalUnmarshaller{},
+		opts...,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every transaction lands in a segment of its own.
+	helper.write(stg, "test", 1)
+	helper.write(stg, "test", 2)
+
+	expect := []string{
+		"0 test begin <nil>",
+		"0 test  1",
+		"0 test commit <nil>",
+		"1 test begin <nil>",
+		"1 test  2",
+		"1 test commit <nil>",
+	}
+	if got := helper.next(follower, len(expect)); !reflect.DeepEqual(
+		got,
+		expect,
+	) {
+		t.Errorf("expected logs \n%q\n but got \n%q\n", expect, got)
+	}
+
+	if segment := follower.Segment(); segment != "segment-00000002.jsonl" {
+		t.Errorf("expected to follow segment-00000002.jsonl but got %s", segment)
+	}
+
+	if err = follower.Checkpoint(); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		time.Sleep(10 * time.Millisecond)
+		helper.write(stg, "test", 3)
+	}()
+
+	expect = []string{
+		"2 test begin <nil>",
+		"2 test  3",
+		"2 test commit <nil>",
+	}
+	if got := helper.next(follower, len(expect)); !reflect.DeepEqual(
+		got,
+		expect,
+	) {
+		t.Errorf("expected waited logs \n%q\n but got \n%q\n", expect, got)
+	}
+	<-done
+
+	// A new follower over the checkpoint resumes in the second segment.
+	if follower, err = NewSegmentedFollower[int](
+		factory,
+		checkpoint,
+		&testMarshalUnmarshaller{},
+		opts...,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := helper.next(follower, len(expect)); !reflect.DeepEqual(
+		got,
+		expect,
+	) {
+		t.Errorf("expected resumed logs \n%q\n but got \n%q\n", expect, got)
+	}
+
+	// Keeping the checkpointed segment only prunes the segments before it.
+	retired, err := stg.Prune(
+		GetTestNow().Add(time.Hour),
+		OptKeepSegment{"segment-00000002.jsonl"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(retired) != 1 || retired[0].Name != "segment-00000001.jsonl" {
+		t.Errorf(
+			"expected only segment-00000001.jsonl retired but got %v",
+			retired,
+		)
+	}
+
+	if follower, err = NewSegmentedFollower[int](
+		factory,
+		checkpoint,
+		&testMarshalUnmarshaller{},
+		opts...,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := helper.next(follower, len(expect)); !reflect.DeepEqual(
+		got,
+		expect,
+	) {
+		t.Errorf("expected kept logs \n%q\n but got \n%q\n", expect, got)
+	}
+
+	// Once the checkpointed segment is pruned the follower cannot resume.
+	if _, err = stg.Prune(
+		GetTestNow().Add(time.Hour),
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = NewSegmentedFollower[int](
+		factory,
+		checkpoint,
+		&testMarshalUnmarshaller{},
+		opts...,
+	); !errors.Is(err, ErrIllegalState) {
+		t.Errorf("expected an illegal state error but got %v", err)
+	}
+}
+
+type followerHelper struct {
+	t          *testing.T
+	file       *os.File
+	followFile *os.File
+}
+
+func (helper *followerHelper) setup() BinLogStorage {
+	var err error
+
+	helper.teardown()
+
+	if helper.file, err = os.Create("test_follow.jsonl"); err != nil {
+		helper.t.Fatal(err)
+	}
+
+	return New[int](
+		helper.file,
+		&testIdFactory{},
+		&testMarshalUnmarshaller{},
+		OptNower{&testNower{}},
+	)
+}
+
+// follow opens the bin log again as another process would.
+func (helper *followerHelper) follow(
+	opts ...OptFollower,
+) (follower Follower[int], err error) {
+	if helper.followFile != nil {
+		helper.followFile.Close()
+	}
+
+	if helper.followFile, err = os.OpenFile(
+		"test_follow.jsonl",
+		os.O_RDWR,
+		0644,
+	); err != nil {
+		return nil, err
+	}
+
+	return NewFollower[int](
+		helper.followFile,
+		NewFileCheckpointStore("test_checkpoint.json"),
+		&testMarshalUnmarshaller{},
+		opts...,
+	)
+}
+
+func (helper *followerHelper) write(stg BinLogStorage, objType string, id int) {
+	transaction := stg.StartTransaction(objType)
+
+	if err := transaction.LogInsert(id, []byte(`{}`)); err != nil {
+		helper.t.Fatal(err)
+	}
+
+	if err := transaction.End(); err != nil {
+		helper.t.Fatal(err)
+	}
+}
+
+func (helper *followerHelper) next(follower Follower[int], n int) []string {
+	got := make([]string, 0, n)
+
+	for i := 0; i < n; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		log, err := follower.Next(ctx)
+		cancel()
+
+		if err != nil {
+			helper.t.Fatal(err)
+		}
+
+		got = append(got, fmt.Sprintf(
+			"%d %s %s %v",
+			log.TransactionId,
+			log.Type,
+			log.Op,
+			log.Id,
+		))
+	}
+
+	return got
+}
+
+func (helper *followerHelper) teardown() {
+	for _, file := range []*os.File{helper.file, helper.followFile} {
+		if file != nil {
+			file.Close()
+		}
+	}
+
+	os.Remove("test_follow.jsonl")
+	os.Remove("test_checkpoint.json")
+}
@@ -0,0 +1,370 @@
+package objbinlog
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/yo3jones/stg/pkg/stg"
+)
+
+type Entry struct {
+	TransactionId any
+	Type          string
+	Id            any
+	Timestamp     time.Time
+	From          []byte
+	To            []byte
+	Status        Status
+}
+
+// Status tells whether the transaction an entry belongs to committed. Entries
+// written before transaction markers existed have no begin marker and are
+// reported as committed.
+type Status int
+
+const (
+	StatusCommitted Status = iota + 1
+	StatusAborted
+	StatusIncomplete
+)
+
+// Scan calls fn for every entry of objType in log order. Entries of a
+// transaction are held back until its commit or abort marker is read so that
+// they can be reported with their final status; transactions still open at
+// the end of the log are reported as incomplete.
+func (stg *binLogStorage[T]) Scan(
+	objType string,
+	fn func(entry Entry) error,
+) (err error) {
+	stg.lock.Lock()
+	defer stg.lock.Unlock()
+	stg.writeLock.Lock()
+	defer stg.writeLock.Unlock()
+
+	var (
+		log     *Log[T]
+		scanner = newScanner[T](objType, fn)
+	)
+
+	readers, opened, err := stg.readers(OptObjType{objType})
+	if err != nil {
+		return err
+	}
+	defer closeHandles(opened...)
+
+	for _, reader := range readers {
+		for {
+			if log, err = reader.Next(); err == io.EOF {
+				break
+			} else if err != nil {
+				return err
+			}
+
+			if err = scanner.scan(log); err != nil {
+				return err
+			}
+		}
+	}
+
+	return scanner.close()
+}
+
+// Reader iterates the logs and markers of a bin log in the order they were
+// written. Next returns io.EOF once no complete line is left; a trailing line
+// without its newline is either still being written or was torn by a crash,
+// so it is left unread and Next picks it up again once it is complete.
+type Reader[T comparable] interface {
+	Next() (log *Log[T], err error)
+	Reset()
+}
+
+type reader[T comparable] struct {
+	buffer              *bufio.Reader
+	entityId            any
+	entityIdData        []byte
+	handle              stg.Handle
+	marshalUnmarshaller stg.MarshalUnmarshaller[any]
+	objType             string
+	offset              int64
+	since               time.Time
+	transactionId       *T
+	until               time.Time
+}
+
+func NewReader[T comparable](
+	handle stg.Handle,
+	marshalUnmarshaller stg.MarshalUnmarshaller[any],
+	opts ...OptReader,
+) Reader[T] {
+	reader := &reader[T]{
+		handle:              handle,
+		marshalUnmarshaller: marshalUnmarshaller,
+	}
+
+	for _, opt := range opts {
+		opt.isReaderOpt()
+		switch opt := opt.(type) {
+		case OptEntityId:
+			reader.entityId = opt.Value
+		case OptObjType:
+			reader.objType = opt.Value
+		case OptSince:
+			reader.since = opt.Value
+		case OptTransactionId[T]:
+			reader.transactionId = &opt.Value
+		case OptUntil:
+			reader.until = opt.Value
+		}
+	}
+
+	return reader
+}
+
+func (reader *reader[T]) Next() (log *Log[T], err error) {
+	var match bool
+
+	for {
+		if log, err = reader.next(); err != nil {
+			return nil, err
+		}
+
+		if match, err = reader.match(log); err != nil {
+			return nil, err
+		} else if match {
+			return log, nil
+		}
+	}
+}
+
+func (reader *reader[T]) Reset() {
+	reader.buffer = nil
+	reader.offset = 0
+}
+
+func (reader *reader[T]) next() (log *Log[T], err error) {
+	var line []byte
+
+	if reader.buffer == nil {
+		if _, err = reader.handle.Seek(reader.offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		reader.buffer = bufio.NewReader(reader.handle)
+	}
+
+	for {
+		if line, err = reader.buffer.ReadBytes('\n'); err == io.EOF {
+			reader.buffer = nil
+			return nil, io.EOF
+		} else if err != nil {
+			return nil, err
+		}
+
+		reader.offset += int64(len(line))
+
+		if line = bytes.TrimSpace(line); len(line) == 0 {
+			continue
+		}
+
+		log = &Log[T]{}
+		if err = reader.marshalUnmarshaller.Unmarshal(line, log); err != nil {
+			return nil, err
+		}
+
+		return log, nil
+	}
+}
+
+func (reader *reader[T]) match(log *Log[T]) (match bool, err error) {
+	var data []byte
+
+	if reader.objType != "" && log.Type != reader.objType {
+		return false, nil
+	}
+
+	if reader.transactionId != nil && log.TransactionId != *reader.transactionId {
+		return false, nil
+	}
+
+	if !reader.since.IsZero() && log.Timestamp.Before(reader.since) {
+		return false, nil
+	}
+
+	if !reader.until.IsZero() && !log.Timestamp.Before(reader.until) {
+		return false, nil
+	}
+
+	if reader.entityId == nil {
+		return true, nil
+	}
+
+	if log.Op != "" {
+		return false, nil
+	}
+
+	if reader.entityIdData == nil {
+		reader.entityIdData, err = reader.marshalUnmarshaller.Marshal(
+			reader.entityId,
+		)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if data, err = reader.marshalUnmarshaller.Marshal(log.Id); err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(data, reader.entityIdData), nil
+}
+
+type OptReader interface {
+	isReaderOpt() bool
+}
+
+// OptEntityId keeps the logs of a single record. Ids are compared by their
+// marshalled form since decoding the log turns numeric ids into float64.
+// Markers carry no id and are skipped.
+type OptEntityId struct {
+	Value any
+}
+
+func (opt OptEntityId) isReaderOpt() bool {
+	return true
+}
+
+type OptObjType struct {
+	Value string
+}
+
+func (opt OptObjType) isReaderOpt() bool {
+	return true
+}
+
+// OptSince keeps the logs written at or after Value.
+type OptSince struct {
+	Value time.Time
+}
+
+func (opt OptSince) isReaderOpt() bool {
+	return true
+}
+
+type OptTransactionId[T comparable] struct {
+	Value T
+}
+
+func (opt OptTransactionId[T]) isReaderOpt() bool {
+	return true
+}
+
+// OptUntil keeps the logs written before Value.
+type OptUntil struct {
+	Value time.Time
+}
+
+func (opt OptUntil) isReaderOpt() bool {
+	return true
+}
+
+// Decode unmarshals the image held by wrapper into v, reporting false and
+// leaving v untouched when the image is null.
+func Decode[S any](
+	wrapper DataWrapper,
+	unmarshaller stg.Unmarshaller[S],
+	v S,
+) (ok bool, err error) {
+	if wrapper.data == nil {
+		return false, nil
+	}
+
+	if err = unmarshaller.Unmarshal(wrapper.data, v); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+type scanner[T comparable] struct {
+	fn      func(entry Entry) error
+	objType string
+	open    []T
+	pending map[T][]Entry
+}
+
+func newScanner[T comparable](
+	objType string,
+	fn func(entry Entry) error,
+) *scanner[T] {
+	return &scanner[T]{
+		fn:      fn,
+		objType: objType,
+		open:    make([]T, 0),
+		pending: map[T][]Entry{},
+	}
+}
+
+func (scanner *scanner[T]) scan(log *Log[T]) (err error) {
+	if log.Type != scanner.objType {
+		return nil
+	}
+
+	switch log.Op {
+	case OpBegin:
+		scanner.open = append(scanner.open, log.TransactionId)
+		scanner.pending[log.TransactionId] = make([]Entry, 0)
+		return nil
+	case OpCommit:
+		return scanner.flush(log.TransactionId, StatusCommitted)
+	case OpAbort:
+		return scanner.flush(log.TransactionId, StatusAborted)
+	}
+
+	entry := Entry{
+		TransactionId: log.TransactionId,
+		Type:          log.Type,
+		Id:            log.Id,
+		Timestamp:     log.Timestamp,
+		From:          log.From.data,
+		To:            log.To.data,
+	}
+
+	if entries, isOpen := scanner.pending[log.TransactionId]; isOpen {
+		scanner.pending[log.TransactionId] = append(entries, entry)
+		return nil
+	}
+
+	entry.Status = StatusCommitted
+
+	return scanner.fn(entry)
+}
+
+func (scanner *scanner[T]) flush(transactionId T, status Status) (err error) {
+	for _, entry := range scanner.pending[transactionId] {
+		entry.Status = status
+		if err = scanner.fn(entry); err != nil {
+			return err
+		}
+	}
+
+	delete(scanner.pending, transactionId)
+	for i, id := range scanner.open {
+		if id == transactionId {
+			scanner.open = append(scanner.open[:i], scanner.open[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+func (scanner *scanner[T]) close() (err error) {
+	for len(scanner.open) > 0 {
+		if err = scanner.flush(scanner.open[0], StatusIncomplete); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,332 @@
+package objbinlog
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScan(t *testing.T) {
+	type test struct {
+		name    string
+		objType string
+		expect  []Entry
+	}
+
+	lines := []string{
+		`{"transaction":0,"type":"test","id":1,"ts":"2022-07-07T13:51:57-04:00","from":null,"to":{"foo":"foo"}}`,
+		`{"transaction":1,"type":"test","op":"begin","ts":"2022-07-07T13:51:57-04:00"}`,
+		`{"transaction":1,"type":"test","id":1,"ts":"2022-07-07T13:51:57-04:00","from":{"foo":"foo"},"to":{"foo":"bar"}}`,
+		`{"transaction":1,"type":"test","op":"commit","ts":"2022-07-07T13:51:57-04:00"}`,
+		`{"transaction":2,"type":"other","op":"begin","ts":"2022-07-07T13:51:57-04:00"}`,
+		`{"transaction":2,"type":"other","id":2,"ts":"2022-07-07T13:51:57-04:00","from":null,"to":{"fiz":"buz"}}`,
+		`{"transaction":2,"type":"other","op":"commit","ts":"2022-07-07T13:51:57-04:00"}`,
+		`{"transaction":3,"type":"test","op":"begin","ts":"2022-07-07T13:51:57-04:00"}`,
+		`{"transaction":3,"type":"test","id":1,"ts":"2022-07-07T13:51:57-04:00","from":{"foo":"bar"},"to":{"foo":"baz"}}`,
+		`{"transaction":3,"type":"test","op":"abort","ts":"2022-07-07T13:51:57-04:00"}`,
+		``,
+		`{"transaction":4,"type":"test","op":"begin","ts":"2022-07-07T13:51:57-04:00"}`,
+		`{"transaction":4,"type":"test","id":1,"ts":"2022-07-07T13:51:57-04:00","from":{"foo":"bar"},"to":null}`,
+	}
+
+	tests := []test{
+		{
+			name:    "with matching type",
+			objType: "test",
+			expect: []Entry{
+				{
+					TransactionId: 0,
+					Type:          "test",
+					Id:            float64(1),
+					Timestamp:     GetTestNow().UTC(),
+					From:          nil,
+					To:            []byte(`{"foo":"foo"}`),
+					Status:        StatusCommitted,
+				},
+				{
+					TransactionId: 1,
+					Type:          "test",
+					Id:            float64(1),
+					Timestamp:     GetTestNow().UTC(),
+					From:          []byte(`{"foo":"foo"}`),
+					To:            []byte(`{"foo":"bar"}`),
+					Status:        StatusCommitted,
+				},
+				{
+					TransactionId: 3,
+					Type:          "test",
+					Id:            float64(1),
+					Timestamp:     GetTestNow().UTC(),
+					From:          []byte(`{"foo":"bar"}`),
+					To:            []byte(`{"foo":"baz"}`),
+					Status:        StatusAborted,
+				},
+				{
+					TransactionId: 4,
+					Type:          "test",
+					Id:            float64(1),
+					Timestamp:     GetTestNow().UTC(),
+					From:          []byte(`{"foo":"bar"}`),
+					To:            nil,
+					Status:        StatusIncomplete,
+				},
+			},
+		},
+		{
+			name:    "with other type",
+			objType: "other",
+			expect: []Entry{
+				{
+					TransactionId: 2,
+					Type:          "other",
+					Id:            float64(2),
+					Timestamp:     GetTestNow().UTC(),
+					From:          nil,
+					To:            []byte(`{"fiz":"buz"}`),
+					Status:        StatusCommitted,
+				},
+			},
+		},
+		{
+			name:    "with no matches",
+			objType: "none",
+			expect:  []Entry{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			helper := &testHelper{t: t}
+
+			stg := helper.setup()
+			defer helper.teardown()
+
+			for _, line := range lines {
+				if _, err := fmt.Fprintf(helper.file, "%s\n", line); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got := []Entry{}
+			err := stg.Scan(tc.objType, func(entry Entry) error {
+				entry.Timestamp = entry.Timestamp.UTC()
+				got = append(got, entry)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("expected entries \n%v\n but got \n%v\n", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestReader(t *testing.T) {
+	type test struct {
+		name   string
+		opts   []OptReader
+		expect []string
+	}
+
+	lines := []string{
+		`{"transaction":0,"type":"test","op":"begin","ts":"2022-07-07T13:00:00Z"}`,
+		`{"transaction":0,"type":"test","id":1,"ts":"2022-07-07T13:00:00Z","from":null,"to":{"foo":"foo"}}`,
+		`{"transaction":0,"type":"test","op":"commit","ts":"2022-07-07T13:00:00Z"}`,
+		``,
+		`{"transaction":1,"type":"other","id":"a","ts":"2022-07-07T14:00:00Z","from":null,"to":{"fiz":"buz"}}`,
+		`{"transaction":2,"type":"test","id":1,"ts":"2022-07-07T15:00:00Z","from":{"foo":"foo"},"to":{"foo":"bar"}}`,
+		`{"transaction":2,"type":"test","id":2,"ts":"2022-07-07T15:00:00Z","from":null,"to":{"foo":"baz"}}`,
+	}
+
+	tests := []test{
+		{
+			name: "with no filters",
+			expect: []string{
+				"0 test begin <nil> null null",
+				"0 test  1 null {\"foo\":\"foo\"}",
+				"0 test commit <nil> null null",
+				"1 other  a null {\"fiz\":\"buz\"}",
+				"2 test  1 {\"foo\":\"foo\"} {\"foo\":\"bar\"}",
+				"2 test  2 null {\"foo\":\"baz\"}",
+			},
+		},
+		{
+			name: "with obj type",
+			opts: []OptReader{OptObjType{"other"}},
+			expect: []string{
+				"1 other  a null {\"fiz\":\"buz\"}",
+			},
+		},
+		{
+			name: "with transaction id",
+			opts: []OptReader{OptTransactionId[int]{0}},
+			expect: []string{
+				"0 test begin <nil> null null",
+				"0 test  1 null {\"foo\":\"foo\"}",
+				"0 test commit <nil> null null",
+			},
+		},
+		{
+			name: "with entity id",
+			opts: []OptReader{OptEntityId{1}},
+			expect: []string{
+				"0 test  1 null {\"foo\":\"foo\"}",
+				"2 test  1 {\"foo\":\"foo\"} {\"foo\":\"bar\"}",
+			},
+		},
+		{
+			name: "with string entity id",
+			opts: []OptReader{OptEntityId{"a"}},
+			expect: []string{
+				"1 other  a null {\"fiz\":\"buz\"}",
+			},
+		},
+		{
+			name: "with time range",
+			opts: []OptReader{
+				OptSince{time.Date(2022, 7, 7, 14, 0, 0, 0, time.UTC)},
+				OptUntil{time.Date(2022, 7, 7, 15, 0, 0, 0, time.UTC)},
+			},
+			expect: []string{
+				"1 other  a null {\"fiz\":\"buz\"}",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				err error
+				log *Log[int]
+			)
+
+			helper := &testHelper{t: t}
+
+			helper.setup()
+			defer helper.teardown()
+
+			for _, line := range lines {
+				if _, err = fmt.Fprintf(helper.file, "%s\n", line); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			reader := NewReader[int](
+				helper.file,
+				&testMarshalUnmarshaller{},
+				tc.opts...,
+			)
+
+			got := []string{}
+			for {
+				if log, err = reader.Next(); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+
+				from, _ := log.From.MarshalJSON()
+				to, _ := log.To.MarshalJSON()
+				got = append(got, fmt.Sprintf(
+					"%d %s %s %v %s %s",
+					log.TransactionId,
+					log.Type,
+					log.Op,
+					log.Id,
+					from,
+					to,
+				))
+			}
+
+			if !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("expected logs \n%q\n but got \n%q\n", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestReaderIncompleteLine(t *testing.T) {
+	var (
+		err error
+		log *Log[int]
+	)
+
+	helper := &testHelper{t: t}
+
+	helper.setup()
+	defer helper.teardown()
+
+	fmt.Fprintf(
+		helper.file,
+		"%s\n%s",
+		`{"transaction":0,"type":"test","id":1,"ts":"2022-07-07T13:00:00Z","from":null,"to":{"foo":"foo"}}`,
+		`{"transaction":1,"type":"test","id":2,"ts":"2022-07-07T13:00:00Z"`,
+	)
+
+	reader := NewReader[int](helper.file, &testMarshalUnmarshaller{})
+
+	if log, err = reader.Next(); err != nil {
+		t.Fatal(err)
+	} else if log.TransactionId != 0 {
+		t.Errorf("expected transaction 0 but got %d", log.TransactionId)
+	}
+
+	if _, err = reader.Next(); err != io.EOF {
+		t.Fatalf("expected io.EOF but got %v", err)
+	}
+
+	fmt.Fprintf(
+		helper.file,
+		"%s\n",
+		`,"from":null,"to":{"foo":"bar"}}`,
+	)
+
+	if log, err = reader.Next(); err != nil {
+		t.Fatal(err)
+	} else if log.TransactionId != 1 {
+		t.Errorf("expected transaction 1 but got %d", log.TransactionId)
+	}
+
+	reader.Reset()
+
+	if log, err = reader.Next(); err != nil {
+		t.Fatal(err)
+	} else if log.TransactionId != 0 {
+		t.Errorf("expected transaction 0 after reset but got %d", log.TransactionId)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	type spec struct {
+		Foo string `json:"foo"`
+	}
+
+	unmarshaller := &testMarshalUnmarshaller{}
+
+	got := &spec{}
+	ok, err := Decode[any](DataWrapper{[]byte(`{"foo":"bar"}`)}, unmarshaller, got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || got.Foo != "bar" {
+		t.Errorf("expected decoded foo to be bar but got %t %s", ok, got.Foo)
+	}
+
+	got = &spec{}
+	if ok, err = Decode[any](DataWrapper{}, unmarshaller, got); err != nil {
+		t.Fatal(err)
+	}
+	if ok || got.Foo != "" {
+		t.Errorf("expected null image not to be decoded but got %t %s", ok, got.Foo)
+	}
+
+	_, err = Decode[any](DataWrapper{[]byte(`{`)}, unmarshaller, got)
+	if err == nil {
+		t.Errorf("expected an error decoding invalid data")
+	}
+}
@@ -0,0 +1,368 @@
+package objbinlog
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/yo3jones/stg/pkg/fstln"
+	"github.com/yo3jones/stg/pkg/stg"
+)
+
+// Replica keeps a data file of one object type in step with the bin log of a
+// primary, applying the to image of every log of a committed transaction as
+// the primary writes them. Aborted transactions are never applied and a
+// transaction still open is held back until it ends. The data file should
+// only be read by others; it starts out empty or as written by Restore.
+type Replica[T comparable] interface {
+	LastTransactionId() (transactionId T, ok bool)
+	Run(ctx context.Context) (err error)
+}
+
+type replica[T comparable] struct {
+	checkpoint          CheckpointStore
+	follower            *follower[T]
+	idField             string
+	lastTransactionId   *T
+	marshalUnmarshaller stg.MarshalUnmarshaller[any]
+	objType             string
+	pending             map[T][]*Log[T]
+	positions           map[string]fstln.Position
+	stg                 fstln.Storage
+}
+
+type replicaCheckpoint[T comparable] struct {
+	followerCheckpoint
+	TransactionId *T `json:"transaction"`
+}
+
+// NewReplica applies the bin log in src to the records of objType in dst
+// from where checkpoint says the last run stopped. The records in dst are
+// found by the OptIdField of their image.
+func NewReplica[T comparable](
+	src stg.Handle,
+	dst stg.Handle,
+	checkpoint CheckpointStore,
+	marshalUnmarshaller stg.MarshalUnmarshaller[any],
+	objType string,
+	opts ...OptReplica,
+) (Replica[T], error) {
+	return newReplica(
+		dst,
+		checkpoint,
+		marshalUnmarshaller,
+		objType,
+		func(
+			saved followerCheckpoint,
+			followerOpts ...OptFollower,
+		) (follower *follower[T], err error) {
+			follower, err = newFollower[T](marshalUnmarshaller, followerOpts...)
+			if err != nil {
+				return nil, err
+			}
+			follower.follow(src, "", saved.Offset)
+			return follower, nil
+		},
+		opts...,
+	)
+}
+
+// NewSegmentedReplica is NewReplica for the segmented bin log of factory,
+// which it follows from segment to segment like NewSegmentedFollower.
+func NewSegmentedReplica[T comparable](
+	factory SegmentFactory,
+	dst stg.Handle,
+	checkpoint CheckpointStore,
+	marshalUnmarshaller stg.MarshalUnmarshaller[any],
+	objType string,
+	opts ...OptReplica,
+) (Replica[T], error) {
+	return newReplica(
+		dst,
+		checkpoint,
+		marshalUnmarshaller,
+		objType,
+		func(
+			saved followerCheckpoint,
+			followerOpts ...OptFollower,
+		) (*follower[T], error) {
+			return newSegmentedFollower[T](
+				factory,
+				saved,
+				marshalUnmarshaller,
+				followerOpts...,
+			)
+		},
+		opts...,
+	)
+}
+
+// newReplica creates a replica reading the logs of the follower that follow
+// creates from the saved checkpoint.
+func newReplica[T comparable](
+	dst stg.Handle,
+	checkpoint CheckpointStore,
+	marshalUnmarshaller stg.MarshalUnmarshaller[any],
+	objType string,
+	follow func(
+		saved followerCheckpoint,
+		followerOpts ...OptFollower,
+	) (*follower[T], error),
+	opts ...OptReplica,
+) (*replica[T], error) {
+	var (
+		err          error
+		followerOpts = []OptFollower{OptObjType{objType}}
+		saved        replicaCheckpoint[T]
+	)
+
+	replica := &replica[T]{
+		checkpoint:          checkpoint,
+		idField:             "id",
+		marshalUnmarshaller: marshalUnmarshaller,
+		objType:             objType,
+		pending:             map[T][]*Log[T]{},
+		positions:           map[string]fstln.Position{},
+	}
+
+	for _, opt := range opts {
+		opt.isReplicaOpt()
+		switch opt := opt.(type) {
+		case OptIdField:
+			replica.idField = opt.Value
+		case OptPollInterval:
+			followerOpts = append(followerOpts, opt)
+		}
+	}
+
+	if err = loadCheckpoint(checkpoint, marshalUnmarshaller, &saved); err != nil {
+		return nil, err
+	}
+	replica.lastTransactionId = saved.TransactionId
+
+	replica.follower, err = follow(saved.followerCheckpoint, followerOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if replica.stg, err = fstln.New(dst); err != nil {
+		return nil, err
+	}
+
+	if err = replica.locate(); err != nil {
+		return nil, err
+	}
+
+	return replica, nil
+}
+
+func (replica *replica[T]) LastTransactionId() (transactionId T, ok bool) {
+	if replica.lastTransactionId == nil {
+		return transactionId, false
+	}
+
+	return *replica.lastTransactionId, true
+}
+
+// Run applies the bin log until ctx is done, which is the error it returns
+// then, or until applying it fails.
+func (replica *replica[T]) Run(ctx context.Context) (err error) {
+	var log *Log[T]
+
+	for {
+		if log, err = replica.follower.Next(ctx); err != nil {
+			return err
+		}
+
+		if err = replica.apply(log); err != nil {
+			return err
+		}
+	}
+}
+
+func (replica *replica[T]) apply(log *Log[T]) (err error) {
+	switch log.Op {
+	case OpBegin:
+		replica.pending[log.TransactionId] = make([]*Log[T], 0)
+		return nil
+	case OpAbort:
+		delete(replica.pending, log.TransactionId)
+		return replica.save()
+	case OpCommit:
+		for _, pending := range replica.pending[log.TransactionId] {
+			if err = replica.set(pending); err != nil {
+				return err
+			}
+		}
+
+		delete(replica.pending, log.TransactionId)
+		return replica.applied(log.TransactionId)
+	}
+
+	if logs, isOpen := replica.pending[log.TransactionId]; isOpen {
+		replica.pending[log.TransactionId] = append(logs, log)
+		return nil
+	}
+
+	if err = replica.set(log); err != nil {
+		return err
+	}
+
+	return replica.applied(log.TransactionId)
+}
+
+func (replica *replica[T]) applied(transactionId T) (err error) {
+	replica.lastTransactionId = &transactionId
+	return replica.save()
+}
+
+// save checkpoints the replica once no transaction is held back, so that a
+// restart never skips the logs of one. A log applied again after a restart
+// leaves the data file as it was.
+func (replica *replica[T]) save() (err error) {
+	var data []byte
+
+	if len(replica.pending) > 0 {
+		return nil
+	}
+
+	data, err = replica.marshalUnmarshaller.Marshal(&replicaCheckpoint[T]{
+		followerCheckpoint: replica.follower.position(),
+		TransactionId:      replica.lastTransactionId,
+	})
+	if err != nil {
+		return err
+	}
+
+	return replica.checkpoint.Save(data)
+}
+
+func (replica *replica[T]) set(log *Log[T]) (err error) {
+	var (
+		data []byte
+		pos  fstln.Position
+	)
+
+	if data, err = replica.marshalUnmarshaller.Marshal(log.Id); err != nil {
+		return err
+	}
+
+	key := string(data)
+	pos, exists := replica.positions[key]
+
+	switch {
+	case log.To.data == nil && !exists:
+		return nil
+	case log.To.data == nil:
+		if err = replica.stg.Delete(pos); err != nil {
+			return err
+		}
+		delete(replica.positions, key)
+		return nil
+	case exists:
+		pos, err = replica.stg.Update(pos, log.To.data)
+	default:
+		pos, err = replica.stg.Insert(log.To.data)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	replica.positions[key] = pos
+
+	return nil
+}
+
+// locate finds where the records already in the data file are by the id of
+// their image.
+func (replica *replica[T]) locate() (err error) {
+	var (
+		data   []byte
+		fields map[string]any
+		key    []byte
+		pos    fstln.Position
+	)
+
+	if err = replica.stg.ResetScan(); err != nil {
+		return err
+	}
+
+	for {
+		if pos, data, err = replica.read(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		fields = map[string]any{}
+		if err = replica.marshalUnmarshaller.Unmarshal(data, &fields); err != nil {
+			return err
+		}
+
+		id, found := fields[replica.idField]
+		if !found {
+			return fmt.Errorf(
+				"%w, record at %d has no %s",
+				ErrIllegalState,
+				pos.Offset,
+				replica.idField,
+			)
+		}
+
+		if key, err = replica.marshalUnmarshaller.Marshal(id); err != nil {
+			return err
+		}
+
+		replica.positions[string(key)] = pos
+	}
+}
+
+func (replica *replica[T]) read() (
+	pos fstln.Position,
+	data []byte,
+	err error,
+) {
+	var (
+		buffer   = make([]byte, 1000)
+		dataLen  int
+		isPrefix bool
+	)
+
+	data = buffer
+
+	for {
+		if pos, _, isPrefix, err = replica.stg.Read(buffer); err != nil &&
+			err != io.EOF {
+			return pos, nil, err
+		} else if pos == fstln.EOF {
+			return pos, nil, io.EOF
+		}
+
+		if !isPrefix {
+			return pos, data[:pos.Len-1], nil
+		}
+
+		dataLen = len(data)
+		data = append(data, make([]byte, 1000)...)
+		buffer = data[dataLen:]
+	}
+}
+
+type OptReplica interface {
+	isReplicaOpt() bool
+}
+
+// OptIdField is the field of the record images holding their id. It
+// defaults to id.
+type OptIdField struct {
+	Value string
+}
+
+func (opt OptIdField) isReplicaOpt() bool {
+	return true
+}
+
+func (opt OptPollInterval) isReplicaOpt() bool {
+	return true
+}
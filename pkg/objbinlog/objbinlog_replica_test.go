@@ -0,0 +1,339 @@
+package objbinlog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplica(t *testing.T) {
+	type test struct {
+		name              string
+		initial           []string
+		batches           [][]string
+		opts              []OptReplica
+		expectErr         string
+		expect            []string
+		expectTransaction int
+		expectApplied     bool
+	}
+
+	lines := []string{
+		`{"transaction":0,"type":"test","id":1,"ts":"2022-07-07T13:00:00Z","from":null,"to":{"id":1,"foo":"foo"}}`,
+		`{"transaction":0,"type":"test","id":2,"ts":"2022-07-07T13:00:00Z","from":null,"to":{"id":2,"foo":"fiz"}}`,
+		`{"transaction":1,"type":"test","op":"begin","ts":"2022-07-07T14:00:00Z"}`,
+		`{"transaction":1,"type":"test","id":1,"ts":"2022-07-07T14:00:00Z","from":{"id":1,"foo":"foo"},"to":{"id":1,"foo":"bar"}}`,
+		`{"transaction":1,"type":"test","id":2,"ts":"2022-07-07T14:00:00Z","from":{"id":2,"foo":"fiz"},"to":null}`,
+		`{"transaction":1,"type":"test","op":"commit","ts":"2022-07-07T14:00:01Z"}`,
+		`{"transaction":2,"type":"other","op":"begin","ts":"2022-07-07T15:00:00Z"}`,
+		`{"transaction":2,"type":"other","id":1,"ts":"2022-07-07T15:00:00Z","from":null,"to":{"id":1,"fiz":"buz"}}`,
+		`{"transaction":2,"type":"other","op":"commit","ts":"2022-07-07T15:00:00Z"}`,
+		`{"transaction":3,"type":"test","op":"begin","ts":"2022-07-07T16:00:00Z"}`,
+		`{"transaction":3,"type":"test","id":1,"ts":"2022-07-07T16:00:00Z","from":{"id":1,"foo":"bar"},"to":{"id":1,"foo":"baz"}}`,
+		`{"transaction":3,"type":"test","op":"abort","ts":"2022-07-07T16:00:00Z"}`,
+		`{"transaction":4,"type":"test","op":"begin","ts":"2022-07-07T17:00:00Z"}`,
+		`{"transaction":4,"type":"test","id":3,"ts":"2022-07-07T17:00:00Z","from":null,"to":{"id":3,"foo":"new"}}`,
+		`{"transaction":4,"type":"test","id":1,"ts":"2022-07-07T17:00:00Z","from":{"id":1,"foo":"bar"},"to":{"id":1,"foo":"longer"}}`,
+		`{"transaction":4,"type":"test","op":"commit","ts":"2022-07-07T17:00:00Z"}`,
+		`{"transaction":5,"type":"test","op":"begin","ts":"2022-07-07T18:00:00Z"}`,
+		`{"transaction":5,"type":"test","id":3,"ts":"2022-07-07T18:00:00Z","from":{"id":3,"foo":"new"},"to":null}`,
+	}
+
+	tests := []test{
+		{
+			name:    "with whole log",
+			batches: [][]string{lines},
+			expect: []string{
+				`{"id":3,"foo":"new"}`,
+				`{"id":1,"foo":"longer"}`,
+			},
+			expectTransaction: 4,
+			expectApplied:     true,
+		},
+		{
+			name:    "with restarts",
+			batches: [][]string{lines[:4], lines[4:13], lines[13:]},
+			expect: []string{
+				`{"id":3,"foo":"new"}`,
+				`{"id":1,"foo":"longer"}`,
+			},
+			expectTransaction: 4,
+			expectApplied:     true,
+		},
+		{
+			name:    "with incomplete transaction",
+			batches: [][]string{lines[:4]},
+			expect: []string{
+				`{"id":1,"foo":"foo"}`,
+				`{"id":2,"foo":"fiz"}`,
+			},
+			expectTransaction: 0,
+			expectApplied:     true,
+		},
+		{
+			name: "with restored data file",
+			initial: []string{
+				`{"id":1,"foo":"bar"}`,
+			},
+			batches: [][]string{lines[9:]},
+			expect: []string{
+				`{"id":3,"foo":"new"}`,
+				`{"id":1,"foo":"longer"}`,
+			},
+			expectTransaction: 4,
+			expectApplied:     true,
+		},
+		{
+			name: "with id field",
+			initial: []string{
+				`{"key":1,"foo":"bar"}`,
+			},
+			opts: []OptReplica{OptIdField{"key"}},
+			batches: [][]string{{
+				`{"transaction":0,"type":"test","id":1,"ts":"2022-07-07T13:00:00Z","from":{"key":1,"foo":"bar"},"to":{"key":1,"foo":"baz"}}`,
+			}},
+			expect: []string{
+				`{"key":1,"foo":"baz"}`,
+			},
+			expectTransaction: 0,
+			expectApplied:     true,
+		},
+		{
+			name:      "with record missing its id",
+			initial:   []string{`{"key":1,"foo":"bar"}`},
+			batches:   [][]string{lines},
+			expectErr: "illegal state error, record at 0 has no id",
+		},
+		{
+			name:    "with nothing applied",
+			batches: [][]string{lines[6:9]},
+			expect:  []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				err     error
+				replica Replica[int]
+			)
+
+			helper := &replicaHelper{t: t}
+			defer helper.teardown()
+
+			helper.setup(tc.initial)
+
+			for _, batch := range tc.batches {
+				helper.append(batch)
+
+				// Every batch is applied by a new replica, as it would be
+				// after a restart.
+				replica, err = NewReplica[int](
+					helper.src,
+					helper.dst,
+					helper.checkpoint,
+					&testMarshalUnmarshaller{},
+					"test",
+					append(
+						[]OptReplica{OptPollInterval{time.Millisecond}},
+						tc.opts...,
+					)...,
+				)
+
+				if tc.expectErr != "" {
+					if err == nil || err.Error() != tc.expectErr {
+						t.Errorf("expected error %s but got %v", tc.expectErr, err)
+					}
+					return
+				}
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				ctx, cancel := context.WithTimeout(
+					context.Background(),
+					20*time.Millisecond,
+				)
+				err = replica.Run(ctx)
+				cancel()
+
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Fatalf("expected the deadline to be exceeded but got %v", err)
+				}
+			}
+
+			if got := helper.records(); !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("expected records \n%q\n but got \n%q\n", tc.expect, got)
+			}
+
+			transactionId, ok := replica.LastTransactionId()
+			if ok != tc.expectApplied || transactionId != tc.expectTransaction {
+				t.Errorf(
+					"expected last transaction %d %t but got %d %t",
+					tc.expectTransaction,
+					tc.expectApplied,
+					transactionId,
+					ok,
+				)
+			}
+		})
+	}
+}
+
+func TestSegmentedReplica(t *testing.T) {
+	var (
+		err     error
+		replica Replica[int]
+	)
+
+	os.RemoveAll("test_segments")
+	os.MkdirAll("test_segments", 0755)
+	defer os.RemoveAll("test_segments")
+
+	helper := &replicaHelper{t: t}
+	defer helper.teardown()
+
+	helper.setup(nil)
+
+	factory := NewFileSegmentFactory("test_segments")
+	stg, err := NewSegmented[int](
+		factory,
+		&testIdFactory{},
+		&testMarshalUnmarshaller{},
+		OptNower{&testNower{}},
+		OptMaxSegmentSize{1},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(id int, from, to []byte) {
+		trans := stg.StartTransaction("test")
+		if err := trans.LogUpdate(id, from, to); err != nil {
+			t.Fatal(err)
+		}
+		if err := trans.End(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run := func() {
+		// Every run is made by a new replica, as it would be after a restart.
+		replica, err = NewSegmentedReplica[int](
+			factory,
+			helper.dst,
+			helper.checkpoint,
+			&testMarshalUnmarshaller{},
+			"test",
+			OptPollInterval{time.Millisecond},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(
+			context.Background(),
+			20*time.Millisecond,
+		)
+		defer cancel()
+
+		if err = replica.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected the deadline to be exceeded but got %v", err)
+		}
+	}
+
+	write(1, nil, []byte(`{"id":1,"foo":"foo"}`))
+	write(2, nil, []byte(`{"id":2,"foo":"fiz"}`))
+	run()
+
+	write(1, []byte(`{"id":1,"foo":"foo"}`), []byte(`{"id":1,"foo":"bar"}`))
+	write(2, []byte(`{"id":2,"foo":"fiz"}`), nil)
+	run()
+
+	if segments := stg.Segments(); len(segments) != 4 {
+		t.Errorf("expected 4 segments but got %d", len(segments))
+	}
+
+	expect := []string{`{"id":1,"foo":"bar"}`}
+	if got := helper.records(); !reflect.DeepEqual(got, expect) {
+		t.Errorf("expected records \n%q\n but got \n%q\n", expect, got)
+	}
+
+	if transactionId, ok := replica.LastTransactionId(); !ok ||
+		transactionId != 3 {
+		t.Errorf("expected last transaction 3 but got %d %t", transactionId, ok)
+	}
+}
+
+type replicaHelper struct {
+	t          *testing.T
+	src        *os.File
+	dst        *os.File
+	checkpoint CheckpointStore
+}
+
+func (helper *replicaHelper) setup(initial []string) {
+	var err error
+
+	helper.teardown()
+
+	if helper.src, err = os.Create("test_replica_src.jsonl"); err != nil {
+		helper.t.Fatal(err)
+	}
+
+	if helper.dst, err = os.Create("test_replica_dst.jsonl"); err != nil {
+		helper.t.Fatal(err)
+	}
+
+	helper.checkpoint = NewFileCheckpointStore("test_replica.json")
+
+	for _, line := range initial {
+		if _, err = fmt.Fprintf(helper.dst, "%s\n", line); err != nil {
+			helper.t.Fatal(err)
+		}
+	}
+}
+
+func (helper *replicaHelper) append(lines []string) {
+	for _, line := range lines {
+		if _, err := fmt.Fprintf(helper.src, "%s\n", line); err != nil {
+			helper.t.Fatal(err)
+		}
+	}
+}
+
+// records returns the lines of the data file that hold a record.
+func (helper *replicaHelper) records() []string {
+	data, err := ioutil.ReadFile("test_replica_dst.jsonl")
+	if err != nil {
+		helper.t.Fatal(err)
+	}
+
+	records := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			records = append(records, line)
+		}
+	}
+
+	return records
+}
+
+func (helper *replicaHelper) teardown() {
+	for _, file := range []*os.File{helper.src, helper.dst} {
+		if file != nil {
+			file.Close()
+		}
+	}
+
+	os.Remove("test_replica_src.jsonl")
+	os.Remove("test_replica_dst.jsonl")
+	os.Remove("test_replica.json")
+}
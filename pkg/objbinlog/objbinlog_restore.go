@@ -0,0 +1,282 @@
+package objbinlog
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/yo3jones/stg/pkg/fstln"
+	"github.com/yo3jones/stg/pkg/stg"
+)
+
+// Restore rebuilds the records of objType as they stood at a past point of
+// the bin log read from src and writes them to dst, which is truncated first.
+// The point is the last transaction committed at or before OptAt, or the
+// transaction named by OptAtTransactionId; without either the whole log is
+// replayed. Aborted and incomplete transactions are never applied.
+//
+// An obj storage opened over dst with the same bin log recovers by default,
+// replaying the transactions after the restored point and undoing the
+// restore. Open it with obj.OptRecover{Value: false} to keep dst as restored.
+func Restore[T comparable](
+	src stg.Handle,
+	dst stg.Handle,
+	marshalUnmarshaller stg.MarshalUnmarshaller[any],
+	objType string,
+	opts ...OptRestore,
+) (restored int, err error) {
+	return restore(
+		[]Reader[T]{NewReader[T](src, marshalUnmarshaller)},
+		dst,
+		marshalUnmarshaller,
+		objType,
+		opts...,
+	)
+}
+
+// RestoreSegmented is Restore for the segmented bin log of factory, reading
+// the segments its manifest lists oldest first. Records are rebuilt from the
+// whole history of the log, so once Prune has retired a segment the records
+// last written in it are gone and RestoreSegmented fails with ErrIllegalState
+// rather than restore without them.
+func RestoreSegmented[T comparable](
+	factory SegmentFactory,
+	dst stg.Handle,
+	marshalUnmarshaller stg.MarshalUnmarshaller[any],
+	objType string,
+	opts ...OptRestore,
+) (restored int, err error) {
+	var (
+		handle  stg.Handle
+		handles []stg.Handle
+		read    manifest[T]
+		readers []Reader[T]
+	)
+
+	if read, err = readManifest[T](factory, marshalUnmarshaller); err != nil {
+		return 0, err
+	}
+
+	if !read.PrunedThrough.IsZero() {
+		return 0, fmt.Errorf(
+			"%w, segments through %s were pruned",
+			ErrIllegalState,
+			read.PrunedThrough.Format(time.RFC3339),
+		)
+	}
+
+	defer func() { closeHandles(handles...) }()
+
+	for _, segment := range read.Segments {
+		if handle, err = factory.Open(segment.Name); err != nil {
+			return 0, err
+		}
+		handles = append(handles, handle)
+		readers = append(readers, NewReader[T](handle, marshalUnmarshaller))
+	}
+
+	return restore(readers, dst, marshalUnmarshaller, objType, opts...)
+}
+
+func restore[T comparable](
+	readers []Reader[T],
+	dst stg.Handle,
+	marshalUnmarshaller stg.MarshalUnmarshaller[any],
+	objType string,
+	opts ...OptRestore,
+) (restored int, err error) {
+	var (
+		done     bool
+		fstlnStg fstln.Storage
+		log      *Log[T]
+		restorer = newRestorer[T](objType, marshalUnmarshaller, opts...)
+	)
+
+	for _, reader := range readers {
+		for !done {
+			if log, err = reader.Next(); err == io.EOF {
+				break
+			} else if err != nil {
+				return 0, err
+			}
+
+			if done, err = restorer.apply(log); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if restorer.transactionId != nil && !restorer.reached {
+		return 0, fmt.Errorf(
+			"%w, transaction %v",
+			transactionNotFoundError,
+			*restorer.transactionId,
+		)
+	}
+
+	if err = dst.Truncate(0); err != nil {
+		return 0, err
+	}
+
+	if fstlnStg, err = fstln.New(dst); err != nil {
+		return 0, err
+	}
+
+	for _, key := range restorer.keys {
+		image := restorer.images[key]
+		if image == nil {
+			continue
+		}
+
+		if _, err = fstlnStg.Insert(image); err != nil {
+			return restored, err
+		}
+		restored++
+	}
+
+	return restored, nil
+}
+
+type restorer[T comparable] struct {
+	at                  time.Time
+	images              map[string][]byte
+	keys                []string
+	marshalUnmarshaller stg.MarshalUnmarshaller[any]
+	objType             string
+	pending             map[T][]*Log[T]
+	reached             bool
+	transactionId       *T
+}
+
+func newRestorer[T comparable](
+	objType string,
+	marshalUnmarshaller stg.MarshalUnmarshaller[any],
+	opts ...OptRestore,
+) *restorer[T] {
+	restorer := &restorer[T]{
+		images:              map[string][]byte{},
+		keys:                make([]string, 0, 100),
+		marshalUnmarshaller: marshalUnmarshaller,
+		objType:             objType,
+		pending:             map[T][]*Log[T]{},
+	}
+
+	for _, opt := range opts {
+		opt.isRestoreOpt()
+		switch opt := opt.(type) {
+		case OptAt:
+			restorer.at = opt.Value
+		case OptAtTransactionId[T]:
+			restorer.transactionId = &opt.Value
+		}
+	}
+
+	return restorer
+}
+
+func (restorer *restorer[T]) apply(log *Log[T]) (done bool, err error) {
+	if restorer.reached && log.TransactionId != *restorer.transactionId {
+		return true, nil
+	}
+
+	switch log.Op {
+	case OpBegin:
+		restorer.pending[log.TransactionId] = make([]*Log[T], 0)
+		return false, nil
+	case OpAbort:
+		delete(restorer.pending, log.TransactionId)
+		restorer.reach(log)
+		return false, nil
+	case OpCommit:
+		if restorer.after(log) {
+			return true, nil
+		}
+
+		for _, pending := range restorer.pending[log.TransactionId] {
+			if err = restorer.set(pending); err != nil {
+				return false, err
+			}
+		}
+
+		delete(restorer.pending, log.TransactionId)
+		restorer.reach(log)
+		return false, nil
+	}
+
+	if logs, isOpen := restorer.pending[log.TransactionId]; isOpen {
+		restorer.pending[log.TransactionId] = append(logs, log)
+		return false, nil
+	}
+
+	if restorer.after(log) {
+		return true, nil
+	}
+
+	if err = restorer.set(log); err != nil {
+		return false, err
+	}
+
+	restorer.reach(log)
+
+	return false, nil
+}
+
+func (restorer *restorer[T]) after(log *Log[T]) bool {
+	return !restorer.at.IsZero() && log.Timestamp.After(restorer.at)
+}
+
+func (restorer *restorer[T]) reach(log *Log[T]) {
+	if restorer.transactionId != nil &&
+		log.TransactionId == *restorer.transactionId {
+		restorer.reached = true
+	}
+}
+
+func (restorer *restorer[T]) set(log *Log[T]) (err error) {
+	var data []byte
+
+	if log.Type != restorer.objType {
+		return nil
+	}
+
+	if data, err = restorer.marshalUnmarshaller.Marshal(log.Id); err != nil {
+		return err
+	}
+
+	key := string(data)
+	if _, exists := restorer.images[key]; !exists {
+		restorer.keys = append(restorer.keys, key)
+	}
+	restorer.images[key] = log.To.data
+
+	return nil
+}
+
+type OptRestore interface {
+	isRestoreOpt() bool
+}
+
+// OptAt restores the records as they stood right after the last transaction
+// committed at or before Value.
+type OptAt struct {
+	Value time.Time
+}
+
+func (opt OptAt) isRestoreOpt() bool {
+	return true
+}
+
+// OptAtTransactionId restores the records as they stood right after the
+// transaction Value ended. An aborted transaction restores the state before
+// it.
+type OptAtTransactionId[T comparable] struct {
+	Value T
+}
+
+func (opt OptAtTransactionId[T]) isRestoreOpt() bool {
+	return true
+}
+
+var transactionNotFoundError = fmt.Errorf(
+	"illegal argument error, transaction not found",
+)
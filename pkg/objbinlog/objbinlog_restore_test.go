@@ -0,0 +1,338 @@
+package objbinlog
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRestore(t *testing.T) {
+	type test struct {
+		name          string
+		objType       string
+		opts          []OptRestore
+		expectErr     string
+		expectRestore int
+		expect        []string
+	}
+
+	lines := []string{
+		`{"transaction":0,"type":"test","id":1,"ts":"2022-07-07T13:00:00Z","from":null,"to":{"id":1,"foo":"foo"}}`,
+		`{"transaction":0,"type":"test","id":2,"ts":"2022-07-07T13:00:00Z","from":null,"to":{"id":2,"foo":"fiz"}}`,
+		`{"transaction":1,"type":"test","op":"begin","ts":"2022-07-07T14:00:00Z"}`,
+		`{"transaction":1,"type":"test","id":1,"ts":"2022-07-07T14:00:00Z","from":{"id":1,"foo":"foo"},"to":{"id":1,"foo":"bar"}}`,
+		`{"transaction":1,"type":"test","id":2,"ts":"2022-07-07T14:00:00Z","from":{"id":2,"foo":"fiz"},"to":null}`,
+		`{"transaction":1,"type":"test","op":"commit","ts":"2022-07-07T14:00:01Z"}`,
+		`{"transaction":2,"type":"other","op":"begin","ts":"2022-07-07T15:00:00Z"}`,
+		`{"transaction":2,"type":"other","id":1,"ts":"2022-07-07T15:00:00Z","from":null,"to":{"id":1,"fiz":"buz"}}`,
+		`{"transaction":2,"type":"other","op":"commit","ts":"2022-07-07T15:00:00Z"}`,
+		`{"transaction":3,"type":"test","op":"begin","ts":"2022-07-07T16:00:00Z"}`,
+		`{"transaction":3,"type":"test","id":1,"ts":"2022-07-07T16:00:00Z","from":{"id":1,"foo":"bar"},"to":{"id":1,"foo":"baz"}}`,
+		`{"transaction":3,"type":"test","op":"abort","ts":"2022-07-07T16:00:00Z"}`,
+		`{"transaction":4,"type":"test","op":"begin","ts":"2022-07-07T17:00:00Z"}`,
+		`{"transaction":4,"type":"test","id":3,"ts":"2022-07-07T17:00:00Z","from":null,"to":{"id":3,"foo":"new"}}`,
+		`{"transaction":4,"type":"test","op":"commit","ts":"2022-07-07T17:00:00Z"}`,
+		`{"transaction":5,"type":"test","op":"begin","ts":"2022-07-07T18:00:00Z"}`,
+		`{"transaction":5,"type":"test","id":3,"ts":"2022-07-07T18:00:00Z","from":{"id":3,"foo":"new"},"to":null}`,
+	}
+
+	tests := []test{
+		{
+			name:          "with whole log",
+			objType:       "test",
+			expectRestore: 2,
+			expect: []string{
+				`{"id":1,"foo":"bar"}`,
+				`{"id":3,"foo":"new"}`,
+			},
+		},
+		{
+			name:          "with time before commit",
+			objType:       "test",
+			opts:          []OptRestore{OptAt{time.Date(2022, 7, 7, 14, 0, 0, 0, time.UTC)}},
+			expectRestore: 2,
+			expect: []string{
+				`{"id":1,"foo":"foo"}`,
+				`{"id":2,"foo":"fiz"}`,
+			},
+		},
+		{
+			name:          "with time after commit",
+			objType:       "test",
+			opts:          []OptRestore{OptAt{time.Date(2022, 7, 7, 14, 0, 1, 0, time.UTC)}},
+			expectRestore: 1,
+			expect: []string{
+				`{"id":1,"foo":"bar"}`,
+			},
+		},
+		{
+			name:          "with legacy transaction id",
+			objType:       "test",
+			opts:          []OptRestore{OptAtTransactionId[int]{0}},
+			expectRestore: 2,
+			expect: []string{
+				`{"id":1,"foo":"foo"}`,
+				`{"id":2,"foo":"fiz"}`,
+			},
+		},
+		{
+			name:          "with other type transaction id",
+			objType:       "test",
+			opts:          []OptRestore{OptAtTransactionId[int]{2}},
+			expectRestore: 1,
+			expect: []string{
+				`{"id":1,"foo":"bar"}`,
+			},
+		},
+		{
+			name:          "with other type",
+			objType:       "other",
+			expectRestore: 1,
+			expect: []string{
+				`{"id":1,"fiz":"buz"}`,
+			},
+		},
+		{
+			name:      "with unknown transaction id",
+			objType:   "test",
+			opts:      []OptRestore{OptAtTransactionId[int]{9}},
+			expectErr: "illegal argument error, transaction not found, transaction 9",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				dst      *os.File
+				err      error
+				gotBytes []byte
+				restored int
+			)
+
+			helper := &testHelper{t: t}
+
+			helper.setup()
+			defer helper.teardown()
+
+			for _, line := range lines {
+				if _, err = fmt.Fprintf(helper.file, "%s\n", line); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			os.Remove("test_restore.jsonl")
+			if dst, err = os.Create("test_restore.jsonl"); err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove("test_restore.jsonl")
+			defer dst.Close()
+
+			fmt.Fprintf(dst, "%s\n", `{"id":9,"foo":"stale"}`)
+
+			restored, err = Restore[int](
+				helper.file,
+				dst,
+				&testMarshalUnmarshaller{},
+				tc.objType,
+				tc.opts...,
+			)
+
+			if tc.expectErr != "" {
+				if err == nil || err.Error() != tc.expectErr {
+					t.Errorf("expected error %s but got %v", tc.expectErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if restored != tc.expectRestore {
+				t.Errorf(
+					"expected %d records restored but got %d",
+					tc.expectRestore,
+					restored,
+				)
+			}
+
+			if gotBytes, err = ioutil.ReadFile("test_restore.jsonl"); err != nil {
+				t.Fatal(err)
+			}
+
+			expect := fmt.Sprintf("%s\n", strings.Join(tc.expect, "\n"))
+			if string(gotBytes) != expect {
+				t.Errorf(
+					"expected restored file \n%s\n but got \n%s\n",
+					expect,
+					string(gotBytes),
+				)
+			}
+		})
+	}
+}
+
+func TestRestoreSegmented(t *testing.T) {
+	type test struct {
+		name          string
+		opts          []OptRestore
+		prune         bool
+		expectErr     string
+		expectRestore int
+		expect        []string
+	}
+
+	tests := []test{
+		{
+			name:          "with whole log",
+			expectRestore: 2,
+			expect: []string{
+				`{"id":1,"foo":"bar"}`,
+				`{"id":2,"foo":"fiz"}`,
+			},
+		},
+		{
+			name:          "with time in a later segment",
+			opts:          []OptRestore{OptAt{GetTestNow().Add(time.Hour)}},
+			expectRestore: 1,
+			expect: []string{
+				`{"id":1,"foo":"bar"}`,
+			},
+		},
+		{
+			name:          "with transaction id in the first segment",
+			opts:          []OptRestore{OptAtTransactionId[int]{0}},
+			expectRestore: 1,
+			expect: []string{
+				`{"id":1,"foo":"foo"}`,
+			},
+		},
+		{
+			name:  "with pruned segments",
+			prune: true,
+			expectErr: fmt.Sprintf(
+				"illegal state error, segments through %s were pruned",
+				GetTestNow().Format(time.RFC3339),
+			),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				dst      *os.File
+				err      error
+				gotBytes []byte
+				restored int
+			)
+
+			os.RemoveAll("test_segments")
+			os.MkdirAll("test_segments", 0755)
+			defer os.RemoveAll("test_segments")
+
+			factory := NewFileSegmentFactory("test_segments")
+			clock := &testClock{GetTestNow()}
+
+			stg, err := NewSegmented[int](
+				factory,
+				&testIdFactory{},
+				&testMarshalUnmarshaller{},
+				OptNower{clock},
+				OptMaxSegmentSize{1},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Every transaction lands in a segment of its own.
+			for _, write := range []func(trans Transaction) error{
+				func(trans Transaction) error {
+					return trans.LogInsert(1, []byte(`{"id":1,"foo":"foo"}`))
+				},
+				func(trans Transaction) error {
+					return trans.LogUpdate(
+						1,
+						[]byte(`{"id":1,"foo":"foo"}`),
+						[]byte(`{"id":1,"foo":"bar"}`),
+					)
+				},
+				func(trans Transaction) error {
+					return trans.LogInsert(2, []byte(`{"id":2,"foo":"fiz"}`))
+				},
+			} {
+				trans := stg.StartTransaction("test")
+				if err = write(trans); err != nil {
+					t.Fatal(err)
+				}
+				if err = trans.End(); err != nil {
+					t.Fatal(err)
+				}
+				clock.now = clock.now.Add(time.Hour)
+			}
+
+			if segments := stg.Segments(); len(segments) != 3 {
+				t.Fatalf("expected 3 segments but got %d", len(segments))
+			}
+
+			// Pruning retires the first segment, which holds the only insert
+			// of record 1.
+			if tc.prune {
+				if _, err = stg.Prune(GetTestNow()); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			os.Remove("test_restore.jsonl")
+			if dst, err = os.Create("test_restore.jsonl"); err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove("test_restore.jsonl")
+			defer dst.Close()
+
+			restored, err = RestoreSegmented[int](
+				factory,
+				dst,
+				&testMarshalUnmarshaller{},
+				"test",
+				tc.opts...,
+			)
+
+			if tc.expectErr != "" {
+				if !errors.Is(err, ErrIllegalState) ||
+					err.Error() != tc.expectErr {
+					t.Errorf("expected error %s but got %v", tc.expectErr, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if restored != tc.expectRestore {
+				t.Errorf(
+					"expected %d records restored but got %d",
+					tc.expectRestore,
+					restored,
+				)
+			}
+
+			if gotBytes, err = ioutil.ReadFile("test_restore.jsonl"); err != nil {
+				t.Fatal(err)
+			}
+
+			expect := fmt.Sprintf("%s\n", strings.Join(tc.expect, "\n"))
+			if string(gotBytes) != expect {
+				t.Errorf(
+					"expected restored file \n%s\n but got \n%s\n",
+					expect,
+					string(gotBytes),
+				)
+			}
+		})
+	}
+}
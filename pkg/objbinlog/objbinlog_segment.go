@@ -0,0 +1,525 @@
+package objbinlog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/yo3jones/stg/pkg/stg"
+)
+
+// SegmentFactory opens the handles backing a segmented bin log. Open creates
+// the named segment when it does not exist yet and Retire drops or archives
+// a segment that retention no longer needs. Replace sets the whole contents
+// of the named segment in a single step, so that a crash leaves either the
+// old or the new contents; the manifest is written with it. Handles that
+// implement io.Closer are closed once the bin log is done with them.
+type SegmentFactory interface {
+	Open(name string) (handle stg.Handle, err error)
+	Replace(name string, data []byte) (err error)
+	Retire(name string) (err error)
+}
+
+type SegmentedBinLogStorage[T comparable] interface {
+	BinLogStorage
+	Prune(
+		coveredAt time.Time,
+		opts ...OptPrune,
+	) (retired []Segment[T], err error)
+	Segments() []Segment[T]
+}
+
+// Segment is the manifest entry of one segment. The first and last fields
+// describe the oldest and newest line written to it and stay zero until the
+// segment is written to.
+type Segment[T comparable] struct {
+	Name               string    `json:"name"`
+	CreatedAt          time.Time `json:"createdAt"`
+	Size               int64     `json:"size"`
+	FirstTransactionId T         `json:"firstTransaction"`
+	FirstTimestamp     time.Time `json:"firstTs"`
+	LastTransactionId  T         `json:"lastTransaction"`
+	LastTimestamp      time.Time `json:"lastTs"`
+}
+
+// manifest is what the manifest segment holds.
+type manifest[T comparable] struct {
+	// PrunedThrough is the last timestamp of the newest segment Prune has
+	// retired, zero while none was.
+	PrunedThrough time.Time    `json:"prunedThrough"`
+	Segments      []Segment[T] `json:"segments"`
+}
+
+type segments[T comparable] struct {
+	factory       SegmentFactory
+	list          []Segment[T]
+	prunedThrough time.Time
+}
+
+const manifestName = "manifest.json"
+
+// NewSegmented returns a bin log spread over segments opened through factory
+// and tracked in a manifest segment. A new segment is started ahead of the
+// first write of a transaction once the current one has reached
+// OptMaxSegmentSize bytes or is older than OptMaxSegmentAge, so a transaction
+// never spans two segments. The manifest is only rewritten when a segment is
+// started or pruned.
+func NewSegmented[T comparable](
+	factory SegmentFactory,
+	idFactory stg.IdFactory[T],
+	marshalUnmarshaller stg.MarshalUnmarshaller[any],
+	opts ...OptBinLogStorage,
+) (segmented SegmentedBinLogStorage[T], err error) {
+	var (
+		binLogStg = newBinLogStorage(
+			nil,
+			idFactory,
+			marshalUnmarshaller,
+			opts...,
+		)
+		read manifest[T]
+	)
+
+	if read, err = readManifest[T](factory, marshalUnmarshaller); err != nil {
+		return nil, err
+	}
+
+	binLogStg.segments = &segments[T]{
+		factory:       factory,
+		list:          read.Segments,
+		prunedThrough: read.PrunedThrough,
+	}
+
+	if len(binLogStg.segments.list) == 0 {
+		if err = binLogStg.startSegment(); err != nil {
+			return nil, err
+		}
+		return binLogStg, nil
+	}
+
+	if err = binLogStg.openCurrentSegment(); err != nil {
+		return nil, err
+	}
+
+	return binLogStg, nil
+}
+
+// readManifest reads the manifest of factory, which lists the segments oldest
+// first.
+func readManifest[T comparable](
+	factory SegmentFactory,
+	marshalUnmarshaller stg.MarshalUnmarshaller[any],
+) (read manifest[T], err error) {
+	var (
+		data   []byte
+		handle stg.Handle
+	)
+
+	if handle, err = factory.Open(manifestName); err != nil {
+		return read, err
+	}
+	defer closeHandles(handle)
+
+	if _, err = handle.Seek(0, io.SeekStart); err != nil {
+		return read, err
+	}
+
+	if data, err = io.ReadAll(handle); err != nil {
+		return read, err
+	}
+
+	if len(data) > 0 {
+		if err = marshalUnmarshaller.Unmarshal(data, &read); err != nil {
+			return read, err
+		}
+	}
+
+	if read.Segments == nil {
+		read.Segments = make([]Segment[T], 0)
+	}
+
+	return read, nil
+}
+
+// Prune retires the oldest segments whose every line is at or before
+// coveredAt, the time up to which a snapshot of the data holds what they
+// logged, keeping at least OptRetainSegments of them. Followers and replicas
+// read segments on their own schedule and fail once the segment of their
+// checkpoint is retired, so pass the Segment of each of them with
+// OptKeepSegment; that segment and every later one are kept.
+func (stg *binLogStorage[T]) Prune(
+	coveredAt time.Time,
+	opts ...OptPrune,
+) (retired []Segment[T], err error) {
+	stg.lock.Lock()
+	defer stg.lock.Unlock()
+	stg.writeLock.Lock()
+	defer stg.writeLock.Unlock()
+
+	var (
+		keep   = map[string]bool{}
+		list   = stg.segments.list
+		retain = stg.retainSegments
+	)
+
+	for _, opt := range opts {
+		opt.isPruneOpt()
+		switch opt := opt.(type) {
+		case OptKeepSegment:
+			keep[opt.Value] = true
+		}
+	}
+
+	if retain < 1 {
+		retain = 1
+	}
+
+	retired = make([]Segment[T], 0)
+
+	for len(list) > retain &&
+		!keep[""] &&
+		!keep[list[0].Name] &&
+		!list[0].LastTimestamp.After(coveredAt) {
+		if err = stg.segments.factory.Retire(list[0].Name); err != nil {
+			break
+		}
+
+		retired = append(retired, list[0])
+		stg.segments.prunedThrough = list[0].LastTimestamp
+		list = list[1:]
+	}
+
+	stg.segments.list = list
+
+	if len(retired) == 0 {
+		return retired, err
+	}
+
+	if writeErr := stg.writeManifest(); err == nil {
+		err = writeErr
+	}
+
+	return retired, err
+}
+
+func (stg *binLogStorage[T]) Segments() []Segment[T] {
+	stg.writeLock.Lock()
+	defer stg.writeLock.Unlock()
+
+	return append([]Segment[T]{}, stg.segments.list...)
+}
+
+// readers returns a reader per segment, oldest first, along with the handles
+// that were opened for them and must be closed once reading is done.
+func (stg *binLogStorage[T]) readers(
+	opts ...OptReader,
+) (readers []Reader[T], opened []stg.Handle, err error) {
+	handle := stg.handle
+
+	if stg.segments == nil {
+		return []Reader[T]{
+			NewReader[T](stg.handle, stg.marshalUnmarshaller, opts...),
+		}, nil, nil
+	}
+
+	list := stg.segments.list
+	for i, segment := range list {
+		handle = stg.handle
+		if i < len(list)-1 {
+			if handle, err = stg.segments.factory.Open(segment.Name); err != nil {
+				closeHandles(opened...)
+				return nil, nil, err
+			}
+			opened = append(opened, handle)
+		}
+
+		readers = append(
+			readers,
+			NewReader[T](handle, stg.marshalUnmarshaller, opts...),
+		)
+	}
+
+	return readers, opened, nil
+}
+
+// rotate starts a new segment when the current one is due. It is called
+// ahead of the first write of a transaction.
+func (stg *binLogStorage[T]) rotate() (err error) {
+	if stg.segments == nil {
+		return nil
+	}
+
+	current := stg.segments.list[len(stg.segments.list)-1]
+	if current.Size == 0 {
+		return nil
+	}
+
+	bySize := stg.maxSegmentSize > 0 && current.Size >= stg.maxSegmentSize
+	byAge := stg.maxSegmentAge > 0 &&
+		stg.nower.Now().Sub(current.CreatedAt) >= stg.maxSegmentAge
+
+	if !bySize && !byAge {
+		return nil
+	}
+
+	return stg.startSegment()
+}
+
+// startSegment opens the next segment and lists it in the manifest before it
+// takes over from the current one, which is left in place when either fails.
+func (stg *binLogStorage[T]) startSegment() (err error) {
+	var (
+		next     int
+		segments = stg.segments
+	)
+
+	if n := len(segments.list); n > 0 {
+		fmt.Sscanf(segments.list[n-1].Name, "segment-%d.jsonl", &next)
+	}
+
+	segment := Segment[T]{
+		Name:      fmt.Sprintf("segment-%08d.jsonl", next+1),
+		CreatedAt: stg.nower.Now(),
+	}
+
+	handle, err := segments.factory.Open(segment.Name)
+	if err != nil {
+		return err
+	}
+
+	segments.list = append(segments.list, segment)
+
+	if err = stg.writeManifest(); err != nil {
+		segments.list = segments.list[:len(segments.list)-1]
+		closeHandles(handle)
+		return err
+	}
+
+	closeHandles(stg.handle)
+	stg.handle = handle
+
+	return nil
+}
+
+// openCurrentSegment reopens the newest segment and rebuilds its manifest
+// entry from its contents, since the manifest is only written when segments
+// are started or pruned.
+func (stg *binLogStorage[T]) openCurrentSegment() (err error) {
+	var (
+		log     *Log[T]
+		segment = &stg.segments.list[len(stg.segments.list)-1]
+	)
+
+	if stg.handle, err = stg.segments.factory.Open(segment.Name); err != nil {
+		return err
+	}
+
+	reader := NewReader[T](stg.handle, stg.marshalUnmarshaller)
+	for {
+		if log, err = reader.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		segment.wrote(log.TransactionId, log.Timestamp, 0)
+	}
+
+	if segment.Size, err = stg.handle.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// wrote records a line of n bytes written to the current segment.
+func (stg *binLogStorage[T]) wrote(
+	transactionId T,
+	timestamp time.Time,
+	n int,
+) {
+	if stg.segments == nil {
+		return
+	}
+
+	segment := &stg.segments.list[len(stg.segments.list)-1]
+	segment.wrote(transactionId, timestamp, n)
+}
+
+func (stg *binLogStorage[T]) writeManifest() (err error) {
+	var data []byte
+
+	data, err = stg.marshalUnmarshaller.Marshal(manifest[T]{
+		PrunedThrough: stg.segments.prunedThrough,
+		Segments:      stg.segments.list,
+	})
+	if err != nil {
+		return err
+	}
+
+	return stg.segments.factory.Replace(manifestName, data)
+}
+
+func (segment *Segment[T]) wrote(
+	transactionId T,
+	timestamp time.Time,
+	n int,
+) {
+	if segment.FirstTimestamp.IsZero() {
+		segment.FirstTransactionId = transactionId
+		segment.FirstTimestamp = timestamp
+	}
+
+	segment.LastTransactionId = transactionId
+	segment.LastTimestamp = timestamp
+	segment.Size += int64(n)
+}
+
+func closeHandles(handles ...stg.Handle) {
+	for _, handle := range handles {
+		if closer, ok := handle.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+}
+
+// NewFileSegmentFactory returns a SegmentFactory keeping segments as files in
+// dir. Retired segments are removed unless OptArchiveDir is given, in which
+// case they are moved there.
+func NewFileSegmentFactory(
+	dir string,
+	opts ...OptFileSegmentFactory,
+) SegmentFactory {
+	factory := &fileSegmentFactory{dir: dir}
+
+	for _, opt := range opts {
+		opt.isFileSegmentFactoryOpt()
+		switch opt := opt.(type) {
+		case OptArchiveDir:
+			factory.archiveDir = opt.Value
+		}
+	}
+
+	return factory
+}
+
+type fileSegmentFactory struct {
+	archiveDir string
+	dir        string
+}
+
+func (factory *fileSegmentFactory) Open(name string) (stg.Handle, error) {
+	return os.OpenFile(
+		filepath.Join(factory.dir, name),
+		os.O_RDWR|os.O_CREATE,
+		0644,
+	)
+}
+
+func (factory *fileSegmentFactory) Replace(name string, data []byte) error {
+	return writeFileAtomic(filepath.Join(factory.dir, name), data)
+}
+
+func (factory *fileSegmentFactory) Retire(name string) (err error) {
+	path := filepath.Join(factory.dir, name)
+
+	if factory.archiveDir == "" {
+		return os.Remove(path)
+	}
+
+	if err = os.MkdirAll(factory.archiveDir, 0755); err != nil {
+		return err
+	}
+
+	return os.Rename(path, filepath.Join(factory.archiveDir, name))
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// over path, so that path holds either its old or its new contents.
+func writeFileAtomic(path string, data []byte) (err error) {
+	var file *os.File
+
+	file, err = os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+	}()
+
+	if _, err = file.Write(data); err != nil {
+		return err
+	}
+
+	if err = file.Chmod(0644); err != nil {
+		return err
+	}
+
+	if err = file.Sync(); err != nil {
+		return err
+	}
+
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+type OptFileSegmentFactory interface {
+	isFileSegmentFactoryOpt() bool
+}
+
+type OptArchiveDir struct {
+	Value string
+}
+
+func (opt OptArchiveDir) isFileSegmentFactoryOpt() bool {
+	return true
+}
+
+type OptMaxSegmentAge struct {
+	Value time.Duration
+}
+
+func (opt OptMaxSegmentAge) isBinLogStorageOpt() bool {
+	return true
+}
+
+type OptMaxSegmentSize struct {
+	Value int64
+}
+
+func (opt OptMaxSegmentSize) isBinLogStorageOpt() bool {
+	return true
+}
+
+type OptPrune interface {
+	isPruneOpt() bool
+}
+
+// OptKeepSegment keeps the named segment, and so every later one, from being
+// pruned. An empty name, that of a follower which has not read a segment yet,
+// keeps them all.
+type OptKeepSegment struct {
+	Value string
+}
+
+func (opt OptKeepSegment) isPruneOpt() bool {
+	return true
+}
+
+// OptRetainSegments is the number of most recent segments Prune keeps no
+// matter what the snapshot covers. The current segment is always kept.
+type OptRetainSegments struct {
+	Value int
+}
+
+func (opt OptRetainSegments) isBinLogStorageOpt() bool {
+	return true
+}
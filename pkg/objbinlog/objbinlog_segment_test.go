@@ -0,0 +1,372 @@
+package objbinlog
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yo3jones/stg/pkg/stg"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (clock *testClock) Now() time.Time {
+	return clock.now
+}
+
+func setupSegmented(
+	t *testing.T,
+	clock *testClock,
+	opts ...OptBinLogStorage,
+) SegmentedBinLogStorage[int] {
+	stg, err := NewSegmented[int](
+		NewFileSegmentFactory(
+			"test_segments",
+			OptArchiveDir{filepath.Join("test_segments", "archive")},
+		),
+		&testIdFactory{},
+		&testMarshalUnmarshaller{},
+		append([]OptBinLogStorage{OptNower{clock}}, opts...)...,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return stg
+}
+
+func writeTransaction(t *testing.T, stg BinLogStorage, id int) {
+	trans := stg.StartTransaction("test")
+	if err := trans.LogInsert(id, []byte(`{"foo":"foo"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := trans.End(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func segmentSummary(segments []Segment[int]) [][3]any {
+	summary := make([][3]any, 0, len(segments))
+	for _, segment := range segments {
+		summary = append(summary, [3]any{
+			segment.Name,
+			segment.FirstTransactionId,
+			segment.LastTransactionId,
+		})
+	}
+	return summary
+}
+
+func TestSegmentedRotation(t *testing.T) {
+	type test struct {
+		name   string
+		opts   []OptBinLogStorage
+		step   time.Duration
+		expect [][3]any
+	}
+
+	tests := []test{
+		{
+			name: "with max size",
+			opts: []OptBinLogStorage{OptMaxSegmentSize{1}},
+			expect: [][3]any{
+				{"segment-00000001.jsonl", 0, 0},
+				{"segment-00000002.jsonl", 1, 1},
+				{"segment-00000003.jsonl", 2, 2},
+			},
+		},
+		{
+			name: "with max age",
+			opts: []OptBinLogStorage{OptMaxSegmentAge{time.Hour}},
+			step: 40 * time.Minute,
+			expect: [][3]any{
+				{"segment-00000001.jsonl", 0, 1},
+				{"segment-00000002.jsonl", 2, 2},
+			},
+		},
+		{
+			name: "without rotation",
+			expect: [][3]any{
+				{"segment-00000001.jsonl", 0, 2},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			os.RemoveAll("test_segments")
+			os.MkdirAll("test_segments", 0755)
+			defer os.RemoveAll("test_segments")
+
+			clock := &testClock{GetTestNow()}
+			stg := setupSegmented(t, clock, tc.opts...)
+
+			for id := 0; id < 3; id++ {
+				writeTransaction(t, stg, id)
+				clock.now = clock.now.Add(tc.step)
+			}
+
+			got := segmentSummary(stg.Segments())
+			if !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("expected segments %v but got %v", tc.expect, got)
+			}
+
+			ids := []any{}
+			err := stg.Scan("test", func(entry Entry) error {
+				ids = append(ids, entry.Id)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expectIds := []any{float64(0), float64(1), float64(2)}
+			if !reflect.DeepEqual(ids, expectIds) {
+				t.Errorf("expected scanned ids %v but got %v", expectIds, ids)
+			}
+
+			reopened := setupSegmented(t, clock, tc.opts...)
+			got = segmentSummary(reopened.Segments())
+			if !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf(
+					"expected reopened segments %v but got %v",
+					tc.expect,
+					got,
+				)
+			}
+		})
+	}
+}
+
+func TestSegmentedPrune(t *testing.T) {
+	type test struct {
+		name          string
+		opts          []OptBinLogStorage
+		pruneOpts     []OptPrune
+		coveredAt     time.Duration
+		expectRetired []string
+		expect        [][3]any
+	}
+
+	tests := []test{
+		{
+			name:          "with covered segments",
+			coveredAt:     time.Hour,
+			expectRetired: []string{"segment-00000001.jsonl", "segment-00000002.jsonl"},
+			expect: [][3]any{
+				{"segment-00000003.jsonl", 2, 2},
+			},
+		},
+		{
+			name:          "with partially covered segments",
+			coveredAt:     30 * time.Minute,
+			expectRetired: []string{"segment-00000001.jsonl"},
+			expect: [][3]any{
+				{"segment-00000002.jsonl", 1, 1},
+				{"segment-00000003.jsonl", 2, 2},
+			},
+		},
+		{
+			name:          "with kept segment",
+			pruneOpts:     []OptPrune{OptKeepSegment{"segment-00000002.jsonl"}},
+			coveredAt:     time.Hour,
+			expectRetired: []string{"segment-00000001.jsonl"},
+			expect: [][3]any{
+				{"segment-00000002.jsonl", 1, 1},
+				{"segment-00000003.jsonl", 2, 2},
+			},
+		},
+		{
+			name:          "with follower yet to read",
+			pruneOpts:     []OptPrune{OptKeepSegment{""}},
+			coveredAt:     time.Hour,
+			expectRetired: []string{},
+			expect: [][3]any{
+				{"segment-00000001.jsonl", 0, 0},
+				{"segment-00000002.jsonl", 1, 1},
+				{"segment-00000003.jsonl", 2, 2},
+			},
+		},
+		{
+			name:          "with retained segments",
+			opts:          []OptBinLogStorage{OptRetainSegments{3}},
+			coveredAt:     time.Hour,
+			expectRetired: []string{},
+			expect: [][3]any{
+				{"segment-00000001.jsonl", 0, 0},
+				{"segment-00000002.jsonl", 1, 1},
+				{"segment-00000003.jsonl", 2, 2},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			os.RemoveAll("test_segments")
+			os.MkdirAll("test_segments", 0755)
+			defer os.RemoveAll("test_segments")
+
+			clock := &testClock{GetTestNow()}
+			opts := append(
+				[]OptBinLogStorage{OptMaxSegmentSize{1}},
+				tc.opts...,
+			)
+			stg := setupSegmented(t, clock, opts...)
+
+			for id := 0; id < 3; id++ {
+				writeTransaction(t, stg, id)
+				clock.now = clock.now.Add(time.Hour)
+			}
+
+			retired, err := stg.Prune(
+				GetTestNow().Add(tc.coveredAt),
+				tc.pruneOpts...,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotRetired := []string{}
+			for _, segment := range retired {
+				gotRetired = append(gotRetired, segment.Name)
+
+				archived := filepath.Join("test_segments", "archive", segment.Name)
+				if _, err = os.Stat(archived); err != nil {
+					t.Errorf("expected %s to be archived but got %v", archived, err)
+				}
+			}
+
+			if !reflect.DeepEqual(gotRetired, tc.expectRetired) {
+				t.Errorf(
+					"expected retired segments %v but got %v",
+					tc.expectRetired,
+					gotRetired,
+				)
+			}
+
+			got := segmentSummary(stg.Segments())
+			if !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf("expected segments %v but got %v", tc.expect, got)
+			}
+
+			reopened := setupSegmented(t, clock, opts...)
+			got = segmentSummary(reopened.Segments())
+			if !reflect.DeepEqual(got, tc.expect) {
+				t.Errorf(
+					"expected reopened segments %v but got %v",
+					tc.expect,
+					got,
+				)
+			}
+		})
+	}
+}
+
+// flakySegmentFactory counts the manifest writes of the factory it wraps and
+// fails to open new segments while failOpen is set.
+type flakySegmentFactory struct {
+	SegmentFactory
+	failOpen bool
+	replaced int
+}
+
+func (factory *flakySegmentFactory) Open(name string) (stg.Handle, error) {
+	if factory.failOpen && name != manifestName {
+		return nil, errors.New("open failed")
+	}
+	return factory.SegmentFactory.Open(name)
+}
+
+func (factory *flakySegmentFactory) Replace(name string, data []byte) error {
+	factory.replaced++
+	return factory.SegmentFactory.Replace(name, data)
+}
+
+func TestSegmentedManifest(t *testing.T) {
+	os.RemoveAll("test_segments")
+	os.MkdirAll("test_segments", 0755)
+	defer os.RemoveAll("test_segments")
+
+	factory := &flakySegmentFactory{
+		SegmentFactory: NewFileSegmentFactory("test_segments"),
+	}
+	clock := &testClock{GetTestNow()}
+
+	stg, err := NewSegmented[int](
+		factory,
+		&testIdFactory{},
+		&testMarshalUnmarshaller{},
+		OptNower{clock},
+		OptMaxSegmentSize{1},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first segment is listed when the bin log is created and the second
+	// when the first write after the first transaction rotates.
+	writeTransaction(t, stg, 0)
+	writeTransaction(t, stg, 1)
+	if factory.replaced != 2 {
+		t.Errorf("expected 2 manifest writes but got %d", factory.replaced)
+	}
+
+	factory.failOpen = true
+	trans := stg.StartTransaction("test")
+	if err = trans.LogInsert(2, []byte(`{"foo":"foo"}`)); err == nil {
+		t.Errorf("expected the rotation to fail")
+	}
+	trans.Abort()
+	factory.failOpen = false
+
+	// The current segment is still open after the failed rotation.
+	ids := []any{}
+	err = stg.Scan("test", func(entry Entry) error {
+		ids = append(ids, entry.Id)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expectIds := []any{float64(0), float64(1)}; !reflect.DeepEqual(
+		ids,
+		expectIds,
+	) {
+		t.Errorf("expected scanned ids %v but got %v", expectIds, ids)
+	}
+
+	writeTransaction(t, stg, 3)
+
+	expect := [][3]any{
+		{"segment-00000001.jsonl", 0, 0},
+		{"segment-00000002.jsonl", 1, 1},
+		{"segment-00000003.jsonl", 3, 3},
+	}
+	if got := segmentSummary(stg.Segments()); !reflect.DeepEqual(got, expect) {
+		t.Errorf("expected segments %v but got %v", expect, got)
+	}
+
+	reopened := setupSegmented(t, clock)
+	if got := segmentSummary(reopened.Segments()); !reflect.DeepEqual(
+		got,
+		expect,
+	) {
+		t.Errorf("expected reopened segments %v but got %v", expect, got)
+	}
+
+	entries, err := os.ReadDir("test_segments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), manifestName+".") {
+			t.Errorf("expected no temporary manifest but got %s", entry.Name())
+		}
+	}
+}
@@ -1,14 +1,23 @@
 package objbinlog
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"time"
 )
 
-func (trans *transaction[T]) End() {
-	trans.ended = true
-	trans.stg.lock.Unlock()
+func (trans *transaction[T]) Abort() (err error) {
+	return trans.finish(OpAbort)
+}
+
+func (trans *transaction[T]) End() (err error) {
+	return trans.finish(OpCommit)
+}
+
+// Id returns the id the entries of the transaction are logged with.
+func (trans *transaction[T]) Id() any {
+	return trans.transactionId
 }
 
 func (trans *transaction[T]) LogDelete(id any, from []byte) (err error) {
@@ -26,17 +35,25 @@ func (trans *transaction[T]) LogUpdate(
 	return trans.writeLog(id, from, to)
 }
 
-func (trans *transaction[T]) writeLog(id any, from, to []byte) (err error) {
-	var (
-		data                []byte
-		handle              = trans.stg.handle
-		log                 *Log[T]
-		marshalUnmarshaller = trans.stg.marshalUnmarshaller
-		n                   int
-		offset              int64
-		timestamp           = trans.timestamp
-	)
+func (trans *transaction[T]) finish(op Op) (err error) {
+	trans.stg.writeLock.Lock()
+	defer trans.stg.writeLock.Unlock()
+
+	if trans.ended {
+		return fmt.Errorf("%w", endedError)
+	}
+
+	trans.ended = true
+	defer trans.stg.lock.Unlock()
+
+	if !trans.begun {
+		return nil
+	}
+
+	return trans.writeMarker(op)
+}
 
+func (trans *transaction[T]) writeLog(id any, from, to []byte) (err error) {
 	trans.stg.writeLock.Lock()
 	defer trans.stg.writeLock.Unlock()
 
@@ -44,16 +61,53 @@ func (trans *transaction[T]) writeLog(id any, from, to []byte) (err error) {
 		return fmt.Errorf("%w", endedError)
 	}
 
-	log = &Log[T]{
+	if !trans.begun {
+		if err = trans.stg.rotate(); err != nil {
+			return err
+		}
+
+		if err = trans.writeMarker(OpBegin); err != nil {
+			return err
+		}
+		trans.begun = true
+	}
+
+	return trans.writeLine(trans.timestamp, &Log[T]{
 		TransactionId: trans.transactionId,
 		Type:          trans.objType,
 		Id:            id,
-		Timestamp:     timestamp,
+		Timestamp:     trans.timestamp,
 		From:          DataWrapper{from},
 		To:            DataWrapper{to},
+	})
+}
+
+func (trans *transaction[T]) writeMarker(op Op) (err error) {
+	timestamp := trans.timestamp
+	if op != OpBegin {
+		timestamp = trans.stg.nower.Now()
 	}
 
-	if data, err = marshalUnmarshaller.Marshal(log); err != nil {
+	return trans.writeLine(timestamp, &Marker[T]{
+		TransactionId: trans.transactionId,
+		Type:          trans.objType,
+		Op:            op,
+		Timestamp:     timestamp,
+	})
+}
+
+func (trans *transaction[T]) writeLine(
+	timestamp time.Time,
+	v any,
+) (err error) {
+	var (
+		data   []byte
+		handle = trans.stg.handle
+		n      int
+		offset int64
+	)
+
+	if data, err = trans.stg.marshalUnmarshaller.Marshal(v); err != nil {
 		return err
 	}
 
@@ -69,27 +123,64 @@ func (trans *transaction[T]) writeLog(id any, from, to []byte) (err error) {
 		return err
 	}
 
+	trans.stg.wrote(trans.transactionId, timestamp, n+1)
+
 	return nil
 }
 
 type Log[T comparable] struct {
 	TransactionId T           `json:"transaction"`
 	Type          string      `json:"type"`
+	Op            Op          `json:"op,omitempty"`
 	Id            any         `json:"id"`
 	Timestamp     time.Time   `json:"ts"`
 	From          DataWrapper `json:"from"`
 	To            DataWrapper `json:"to"`
 }
 
+// Marker records a transaction boundary. A begin marker is written ahead of
+// the first log of a transaction and a commit or abort marker once it ends,
+// so transactions that never logged anything leave no trace.
+type Marker[T comparable] struct {
+	TransactionId T         `json:"transaction"`
+	Type          string    `json:"type"`
+	Op            Op        `json:"op"`
+	Timestamp     time.Time `json:"ts"`
+}
+
+type Op string
+
+const (
+	OpBegin  Op = "begin"
+	OpCommit Op = "commit"
+	OpAbort  Op = "abort"
+)
+
 type DataWrapper struct {
 	data []byte
 }
 
+func (wrapper *DataWrapper) Bytes() []byte {
+	return wrapper.data
+}
+
 func (wrapper *DataWrapper) MarshalJSON() ([]byte, error) {
 	if wrapper.data == nil {
 		return []byte("null"), nil
 	}
+
 	return wrapper.data, nil
 }
 
-var endedError = fmt.Errorf("illegal state error, transaction has ended")
+func (wrapper *DataWrapper) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		wrapper.data = nil
+		return nil
+	}
+
+	wrapper.data = append([]byte{}, data...)
+
+	return nil
+}
+
+var endedError = fmt.Errorf("%w, transaction has ended", ErrIllegalState)
@@ -108,6 +108,14 @@ func TestLogDelete(t *testing.T) {
 				},
 			},
 			expect: []string{
+				strings.Join([]string{
+					`{`,
+					`"transaction":0,`,
+					`"type":"test",`,
+					`"op":"begin",`,
+					`"ts":"2022-07-07T13:51:57-04:00"`,
+					`}`,
+				}, ""),
 				strings.Join([]string{
 					`{`,
 					`"transaction":0,`,
@@ -128,6 +136,22 @@ func TestLogDelete(t *testing.T) {
 					`"to":null`,
 					`}`,
 				}, ""),
+				strings.Join([]string{
+					`{`,
+					`"transaction":0,`,
+					`"type":"test",`,
+					`"op":"commit",`,
+					`"ts":"2022-07-07T13:51:57-04:00"`,
+					`}`,
+				}, ""),
+				strings.Join([]string{
+					`{`,
+					`"transaction":1,`,
+					`"type":"test",`,
+					`"op":"begin",`,
+					`"ts":"2022-07-07T13:51:57-04:00"`,
+					`}`,
+				}, ""),
 				strings.Join([]string{
 					`{`,
 					`"transaction":1,`,
@@ -148,6 +172,14 @@ func TestLogDelete(t *testing.T) {
 					`"to":null`,
 					`}`,
 				}, ""),
+				strings.Join([]string{
+					`{`,
+					`"transaction":1,`,
+					`"type":"test",`,
+					`"op":"commit",`,
+					`"ts":"2022-07-07T13:51:57-04:00"`,
+					`}`,
+				}, ""),
 			},
 		},
 		{
@@ -320,6 +352,14 @@ func TestLogInsert(t *testing.T) {
 				},
 			},
 			expect: []string{
+				strings.Join([]string{
+					`{`,
+					`"transaction":0,`,
+					`"type":"test",`,
+					`"op":"begin",`,
+					`"ts":"2022-07-07T13:51:57-04:00"`,
+					`}`,
+				}, ""),
 				strings.Join([]string{
 					`{`,
 					`"transaction":0,`,
@@ -340,6 +380,22 @@ func TestLogInsert(t *testing.T) {
 					`"to":{"foo":"bar"}`,
 					`}`,
 				}, ""),
+				strings.Join([]string{
+					`{`,
+					`"transaction":0,`,
+					`"type":"test",`,
+					`"op":"commit",`,
+					`"ts":"2022-07-07T13:51:57-04:00"`,
+					`}`,
+				}, ""),
+				strings.Join([]string{
+					`{`,
+					`"transaction":1,`,
+					`"type":"test",`,
+					`"op":"begin",`,
+					`"ts":"2022-07-07T13:51:57-04:00"`,
+					`}`,
+				}, ""),
 				strings.Join([]string{
 					`{`,
 					`"transaction":1,`,
@@ -360,6 +416,14 @@ func TestLogInsert(t *testing.T) {
 					`"to":{"foo":"buz"}`,
 					`}`,
 				}, ""),
+				strings.Join([]string{
+					`{`,
+					`"transaction":1,`,
+					`"type":"test",`,
+					`"op":"commit",`,
+					`"ts":"2022-07-07T13:51:57-04:00"`,
+					`}`,
+				}, ""),
 			},
 		},
 	}
@@ -423,6 +487,14 @@ func TestLogUpdate(t *testing.T) {
 				},
 			},
 			expect: []string{
+				strings.Join([]string{
+					`{`,
+					`"transaction":0,`,
+					`"type":"test",`,
+					`"op":"begin",`,
+					`"ts":"2022-07-07T13:51:57-04:00"`,
+					`}`,
+				}, ""),
 				strings.Join([]string{
 					`{`,
 					`"transaction":0,`,
@@ -443,6 +515,22 @@ func TestLogUpdate(t *testing.T) {
 					`"to":{"foo":"BAR"}`,
 					`}`,
 				}, ""),
+				strings.Join([]string{
+					`{`,
+					`"transaction":0,`,
+					`"type":"test",`,
+					`"op":"commit",`,
+					`"ts":"2022-07-07T13:51:57-04:00"`,
+					`}`,
+				}, ""),
+				strings.Join([]string{
+					`{`,
+					`"transaction":1,`,
+					`"type":"test",`,
+					`"op":"begin",`,
+					`"ts":"2022-07-07T13:51:57-04:00"`,
+					`}`,
+				}, ""),
 				strings.Join([]string{
 					`{`,
 					`"transaction":1,`,
@@ -463,6 +551,14 @@ func TestLogUpdate(t *testing.T) {
 					`"to":{"foo":"BUZ"}`,
 					`}`,
 				}, ""),
+				strings.Join([]string{
+					`{`,
+					`"transaction":1,`,
+					`"type":"test",`,
+					`"op":"commit",`,
+					`"ts":"2022-07-07T13:51:57-04:00"`,
+					`}`,
+				}, ""),
 			},
 		},
 	}
@@ -509,6 +605,100 @@ func TestLogUpdate(t *testing.T) {
 	}
 }
 
+func TestEnd(t *testing.T) {
+	type test struct {
+		name      string
+		tos       []string
+		abort     bool
+		expectErr string
+		expect    []string
+	}
+
+	tests := []test{
+		{
+			name:   "with empty transaction",
+			tos:    []string{},
+			expect: []string{},
+		},
+		{
+			name: "with abort",
+			tos: []string{
+				`{"foo":"foo"}`,
+			},
+			abort: true,
+			expect: []string{
+				`{"transaction":0,"type":"test","op":"begin","ts":"2022-07-07T13:51:57-04:00"}`,
+				`{"transaction":0,"type":"test","id":0,"ts":"2022-07-07T13:51:57-04:00","from":null,"to":{"foo":"foo"}}`,
+				`{"transaction":0,"type":"test","op":"abort","ts":"2022-07-07T13:51:57-04:00"}`,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var err error
+
+			helper := &testHelper{
+				t:      t,
+				expect: tc.expect,
+			}
+
+			stg := helper.setup()
+			defer helper.teardown()
+
+			transaction := stg.StartTransaction("test")
+			for id, to := range tc.tos {
+				if err = transaction.LogInsert(id, []byte(to)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if tc.abort {
+				err = transaction.Abort()
+			} else {
+				err = transaction.End()
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expectErr := "illegal state error, transaction has ended"
+			for _, err = range []error{
+				transaction.End(),
+				transaction.Abort(),
+				transaction.LogInsert(0, []byte(`{}`)),
+			} {
+				if err == nil || err.Error() != expectErr {
+					t.Errorf("expected error %s but got %v", expectErr, err)
+				}
+			}
+
+			helper.doExpect()
+		})
+	}
+}
+
+func TestId(t *testing.T) {
+	helper := &testHelper{t: t, expect: []string{}}
+
+	stg := helper.setup()
+	defer helper.teardown()
+
+	for expect := 0; expect < 2; expect++ {
+		transaction := stg.StartTransaction("test")
+
+		if got := transaction.Id(); got != expect {
+			t.Errorf("expected transaction id %d but got %v", expect, got)
+		}
+
+		if err := transaction.Abort(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	helper.doExpect()
+}
+
 type testIdFactory struct {
 	value int
 }
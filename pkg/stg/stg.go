@@ -51,6 +51,12 @@ type Unmarshaller[S any] interface {
 	Unmarshal(data []byte, v S) error
 }
 
+// FieldUnmarshaller is an Unmarshaller that can also decode only the named
+// fields of a record, leaving the others at their zero value.
+type FieldUnmarshaller[S any] interface {
+	UnmarshalFields(data []byte, v S, fields []string) error
+}
+
 type Nower interface {
 	Now() time.Time
 }